@@ -0,0 +1,187 @@
+/*
+Kustogen writes Go structs, with `kusto` tags that table.Row.ToStruct() understands, for the tables and stored
+functions of a Kusto database.
+
+Usage:
+
+	kustogen -connection-string "https://<cluster>.kusto.windows.net" -auth az-cli -database db \
+		-tables "Logs,Metrics" -functions "*" -package models -out models/kusto.go
+
+Without -connection-string, the connection is read from the KUSTO_* environment variables, see kusto.ConfigFromEnv.
+It is typically run with go:generate, so that the structs are regenerated when the schema changes:
+
+	//go:generate go run github.com/Azure/azure-kusto-go/cmd/kustogen -database db -tables "*" -package models -out kusto.go
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/schema"
+	"github.com/Azure/azure-kusto-go/kusto/mgmt"
+)
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "kustogen:", err)
+		os.Exit(1)
+	}
+}
+
+// options are the command line flags that select what is generated.
+type options struct {
+	database  string
+	tables    []string
+	functions []string
+	pkg       string
+	nullable  schema.Nullable
+	helpers   bool
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("kustogen", flag.ContinueOnError)
+	connStr := fs.String("connection-string", "", "Kusto connection string or cluster URI. Defaults to the KUSTO_* environment variables.")
+	auth := fs.String("auth", "", "Auth mode, such as default, az-cli or managed-identity, if the connection string has no credentials.")
+	database := fs.String("database", "", "Database to read the schemas from.")
+	tables := fs.String("tables", "", `Comma-separated tables to generate structs for, or "*" for all.`)
+	functions := fs.String("functions", "", `Comma-separated stored functions to generate structs and helpers for, or "*" for all.`)
+	pkg := fs.String("package", "models", "Package name of the generated file.")
+	out := fs.String("out", "", "Output file. Defaults to stdout.")
+	nullable := fs.String("nullable", "value", `How columns are represented: "value" (value.* types), "pointer" or "none" (plain types).`)
+	helpers := fs.Bool("helpers", true, "Generate query helpers.")
+	timeout := fs.Duration("timeout", time.Minute, "Timeout for reading the schemas.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := options{
+		database:  *database,
+		tables:    splitList(*tables),
+		functions: splitList(*functions),
+		pkg:       *pkg,
+		helpers:   *helpers,
+	}
+	switch *nullable {
+	case "value":
+		opts.nullable = schema.NullableValue
+	case "pointer":
+		opts.nullable = schema.NullablePointer
+	case "none":
+		opts.nullable = schema.NotNullable
+	default:
+		return fmt.Errorf("-nullable must be value, pointer or none, got %q", *nullable)
+	}
+	if len(opts.tables) == 0 && len(opts.functions) == 0 {
+		return fmt.Errorf("nothing to generate, set -tables or -functions")
+	}
+
+	var config *kusto.Config
+	if *connStr != "" {
+		config = &kusto.Config{ConnectionString: *connStr, AuthMode: kusto.AuthMode(*auth)}
+	} else {
+		var err error
+		if config, err = kusto.ConfigFromEnv("KUSTO_"); err != nil {
+			return err
+		}
+	}
+	if opts.database == "" {
+		opts.database = config.DefaultDatabase
+	}
+	if opts.database == "" {
+		return fmt.Errorf("-database must be set")
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	src, err := generate(ctx, client, opts)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0644)
+}
+
+// generate reads the schemas selected by opts, and returns the generated source.
+func generate(ctx context.Context, client mgmt.Client, opts options) ([]byte, error) {
+	gen := &schema.GoGenerator{Package: opts.pkg, Nullable: opts.nullable, Helpers: opts.helpers}
+
+	tables := opts.tables
+	if len(tables) == 1 && tables[0] == "*" {
+		all, err := mgmt.ListTables(ctx, client, opts.database)
+		if err != nil {
+			return nil, err
+		}
+		tables = tables[:0]
+		for _, t := range all {
+			tables = append(tables, t.Name)
+		}
+	}
+	for _, name := range tables {
+		s, err := mgmt.GetTableSchema(ctx, client, opts.database, name)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		gen.AddTable(s.Name, s.Columns)
+	}
+
+	if len(opts.functions) > 0 {
+		all, err := mgmt.ListFunctionSchemas(ctx, client, opts.database)
+		if err != nil {
+			return nil, err
+		}
+		byName := map[string]mgmt.FunctionSchema{}
+		for _, f := range all {
+			byName[f.Name] = f
+		}
+
+		functions := opts.functions
+		if len(functions) == 1 && functions[0] == "*" {
+			functions = functions[:0]
+			for _, f := range all {
+				functions = append(functions, f.Name)
+			}
+		}
+		for _, name := range functions {
+			f, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("function %s was not found in database %s", name, opts.database)
+			}
+			params := make([]schema.Parameter, 0, len(f.Parameters))
+			for _, p := range f.Parameters {
+				params = append(params, schema.Parameter{Name: p.Name, Type: p.Type})
+			}
+			gen.AddFunction(f.Name, params, f.OutputColumns)
+		}
+	}
+
+	return gen.Generate()
+}
+
+// splitList splits a comma-separated flag value, ignoring empty elements.
+func splitList(s string) []string {
+	var out []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/schema"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient replies to the management commands kustogen runs with canned results.
+type fakeClient struct {
+	stmts []string
+}
+
+func (f *fakeClient) Mgmt(_ context.Context, _ string, query kusto.Statement, _ ...kusto.QueryOption) (*kusto.RowIterator, error) {
+	stmt := query.String()
+	f.stmts = append(f.stmts, stmt)
+
+	var cols table.Columns
+	var rows []value.Values
+	str := func(s string) value.String { return value.String{Value: s, Valid: true} }
+
+	switch {
+	case stmt == ".show tables":
+		cols = table.Columns{{Name: "TableName", Type: types.String}}
+		rows = []value.Values{{str("Logs")}}
+	case strings.HasPrefix(stmt, ".show table Logs schema"):
+		cols = table.Columns{{Name: "TableName", Type: types.String}, {Name: "Schema", Type: types.String}}
+		rows = []value.Values{{str("Logs"), str(`{"Name": "Logs", "OrderedColumns": [{"Name": "Timestamp", "CslType": "datetime"}, {"Name": "Level", "CslType": "int"}]}`)}}
+	case stmt == ".show database schema as json":
+		cols = table.Columns{{Name: "DatabaseSchema", Type: types.String}}
+		rows = []value.Values{{str(`{"Databases": {"db": {"Functions": {"Errors": {
+			"Name": "Errors",
+			"InputParameters": [{"Name": "since", "CslType": "timespan"}],
+			"Body": "{ Logs | where Level > 2 and Timestamp > ago(since) }",
+			"OutputColumns": [{"Name": "Timestamp", "CslType": "datetime"}]
+		}}}}}`)}}
+	default:
+		cols = table.Columns{{Name: "Result", Type: types.String}}
+	}
+
+	mock, err := kusto.NewMockRows(cols)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if err := mock.Row(r); err != nil {
+			return nil, err
+		}
+	}
+	iter := &kusto.RowIterator{}
+	if err := iter.Mock(mock); err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	src, err := generate(context.Background(), client, options{
+		database:  "db",
+		tables:    []string{"*"},
+		functions: []string{"Errors"},
+		pkg:       "models",
+		nullable:  schema.NullablePointer,
+		helpers:   true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".show tables", ".show table Logs schema as json", ".show database schema as json"}, client.stmts)
+
+	s := string(src)
+	assert.Contains(t, s, "package models")
+	assert.Contains(t, s, "type Logs struct {\n\tTimestamp *time.Time `kusto:\"Timestamp\"`\n\tLevel     *int32     `kusto:\"Level\"`\n}")
+	assert.Contains(t, s, "func QueryLogs(")
+	assert.Contains(t, s, "type ErrorsResult struct {")
+	assert.Contains(t, s, "func Errors(ctx context.Context, client QueryClient, db string, since time.Duration, options ...kusto.QueryOption) ([]ErrorsResult, error) {")
+
+	_, err = generate(context.Background(), &fakeClient{}, options{database: "db", functions: []string{"Missing"}, pkg: "models"})
+	assert.Error(t, err)
+}
+
+func TestRunFlags(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	assert.Error(t, run(context.Background(), []string{"-connection-string", "https://a.kusto.windows.net", "-database", "db"}, &out))
+	assert.Error(t, run(context.Background(), []string{"-tables", "T", "-nullable", "maybe"}, &out))
+	assert.Error(t, run(context.Background(), []string{"-unknown"}, &out))
+}
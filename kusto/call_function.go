@@ -0,0 +1,196 @@
+package kusto
+
+// call_function.go holds Client.CallFunction(), which invokes a stored function with Go arguments.
+
+import (
+	"context"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/Azure/azure-kusto-go/kusto/kql/lexer"
+)
+
+// NamedArg is an argument to CallFunction() that is passed to the parameter with the same name, instead of by position.
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named returns a NamedArg that passes value to the parameter called name.
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// CallFunction invokes the stored function name of database db with args, and returns its result.
+//
+// The signature of the function is read with ".show function", and each argument is checked against the type of its
+// parameter and rendered as a typed KQL literal, so that no query text is built from the arguments themselves.
+// Arguments are passed by position, followed by any NamedArg arguments. Parameters with a default value may be omitted.
+//
+// Arguments are converted with kql.Builder.AddValueAs(): bool to bool, integers to int or long (int arguments must
+// fit in 32 bits), floats and integers to real, string to string, time.Time to datetime, time.Duration to timespan,
+// uuid.UUID to guid, decimal.Decimal to decimal, and any value that encodes to JSON to dynamic, where a string is a
+// JSON string and a value.Dynamic or json.RawMessage is a JSON document. The value.* types are accepted for their own
+// type. nil, nil pointers and value.* types that are not Valid pass a null of the parameter type. Tabular parameters
+// take a *kql.Builder holding a tabular expression.
+//
+// Arguments that are QueryOption are not passed to the function, but applied to the query.
+func (c *Client) CallFunction(ctx context.Context, db string, name string, args ...interface{}) (*RowIterator, error) {
+	params, err := c.functionParameters(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []QueryOption
+	var fnArgs []interface{}
+	for _, arg := range args {
+		if o, ok := arg.(QueryOption); ok {
+			options = append(options, o)
+			continue
+		}
+		fnArgs = append(fnArgs, arg)
+	}
+
+	stmt, err := functionCall(name, params, fnArgs)
+	if err != nil {
+		return nil, err
+	}
+	return c.Query(ctx, db, stmt, options...)
+}
+
+// functionParameter is a parameter of a stored function, as listed by ".show function".
+type functionParameter struct {
+	name string
+	// colType is the type of a scalar parameter. It is empty for tabular parameters.
+	colType    types.Column
+	hasDefault bool
+}
+
+// functionParameters reads the parameters of function name with ".show function".
+func (c *Client) functionParameters(ctx context.Context, db, name string) ([]functionParameter, error) {
+	iter, err := c.Mgmt(ctx, db, kql.New(".show function ").AddFunction(name))
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	var rec struct {
+		Name       string `kusto:"Name"`
+		Parameters string `kusto:"Parameters"`
+	}
+	found := false
+	err = iter.DoOnRowOrError(func(row *table.Row, inlineErr *errors.Error) error {
+		if inlineErr != nil {
+			return inlineErr
+		}
+		found = true
+		return row.ToStruct(&rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "function %q was not found in database %q", name, db).SetNoRetry()
+	}
+
+	return parseFunctionParameters(rec.Parameters)
+}
+
+// parseFunctionParameters parses the parameter list of a function, such as "(T:(*), n:long, s:string=\"a\")".
+func parseFunctionParameters(list string) ([]functionParameter, error) {
+	decls, err := lexer.ParameterList(list)
+	if err != nil {
+		return nil, errors.ES(errors.OpQuery, errors.KInternal, "cannot parse function parameters %q: %s", list, err).SetNoRetry()
+	}
+
+	var params []functionParameter
+	for _, d := range decls {
+		p := functionParameter{name: d.Name, colType: types.Column(d.Type), hasDefault: d.HasDefault}
+		if d.Type != "" && !p.colType.Valid() {
+			return nil, errors.ES(errors.OpQuery, errors.KInternal, "parameter %q has unknown type %q", p.name, d.Type).SetNoRetry()
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// functionCall returns the statement that invokes function name with params, passing args.
+func functionCall(name string, params []functionParameter, args []interface{}) (*kql.Builder, error) {
+	argErr := func(format string, a ...interface{}) error {
+		return errors.ES(errors.OpQuery, errors.KClientArgs, "function %q: "+format, append([]interface{}{name}, a...)...).SetNoRetry()
+	}
+
+	byName := make(map[string]int, len(params))
+	for i, p := range params {
+		byName[p.name] = i
+	}
+
+	values := make([]interface{}, len(params))
+	set := make([]bool, len(params))
+	named := false
+	for i, arg := range args {
+		if n, ok := arg.(NamedArg); ok {
+			named = true
+			idx, ok := byName[n.Name]
+			if !ok {
+				return nil, argErr("has no parameter %q", n.Name)
+			}
+			if set[idx] {
+				return nil, argErr("parameter %q is passed more than once", n.Name)
+			}
+			values[idx], set[idx] = n.Value, true
+			continue
+		}
+
+		if named {
+			return nil, argErr("positional argument %d follows a named argument", i)
+		}
+		if i >= len(params) {
+			return nil, argErr("takes %d arguments, got %d", len(params), len(args))
+		}
+		values[i], set[i] = arg, true
+	}
+
+	stmt := kql.New("").AddFunction(name).AddLiteral("(")
+	first := true
+	skipped := false
+	for i, p := range params {
+		if !set[i] {
+			if !p.hasDefault {
+				return nil, argErr("parameter %q has no default value and must be passed", p.name)
+			}
+			skipped = true
+			continue
+		}
+
+		if !first {
+			stmt.AddLiteral(", ")
+		}
+		first = false
+		// Once a parameter is skipped, the following ones are no longer in position and must be passed by name.
+		if skipped {
+			stmt.AddColumn(p.name).AddLiteral("=")
+		}
+		if err := addFunctionArg(stmt, p, values[i]); err != nil {
+			return nil, argErr("parameter %q: %s", p.name, err)
+		}
+	}
+	return stmt.AddLiteral(")"), nil
+}
+
+// addFunctionArg adds arg to stmt as a literal of the type of parameter p.
+func addFunctionArg(stmt *kql.Builder, p functionParameter, arg interface{}) error {
+	if p.colType == "" {
+		q, ok := arg.(*kql.Builder)
+		if !ok || q == nil {
+			return errors.ES(errors.OpQuery, errors.KClientArgs, "is tabular and requires a *kql.Builder, got %T", arg)
+		}
+		stmt.AddLiteral("(").AddUnsafe(q.String()).AddLiteral(")")
+		return nil
+	}
+
+	_, err := stmt.AddValueAs(arg, p.colType)
+	return err
+}
@@ -0,0 +1,251 @@
+package kusto
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/internal/frames"
+	v1 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v1"
+	v2 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v2"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFunctionParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		list string
+		want []functionParameter
+		err  bool
+	}{
+		{desc: "No parameters", list: "()"},
+		{
+			desc: "Scalars with defaults",
+			list: `(n:long, s:string="a, b", d:dynamic=dynamic({"x": [1, 2]}))`,
+			want: []functionParameter{
+				{name: "n", colType: types.Long},
+				{name: "s", colType: types.String, hasDefault: true},
+				{name: "d", colType: types.Dynamic, hasDefault: true},
+			},
+		},
+		{
+			desc: "Tabular",
+			list: `(T:(*), S:(a:long, b:string), ['my-param']:int=5)`,
+			want: []functionParameter{
+				{name: "T"},
+				{name: "S"},
+				{name: "my-param", colType: types.Int, hasDefault: true},
+			},
+		},
+		{desc: "Not a list", list: "n:long", err: true},
+		{desc: "Missing type", list: "(n)", err: true},
+		{desc: "Unknown type", list: "(n:number)", err: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseFunctionParameters(test.list)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestFunctionCall(t *testing.T) {
+	t.Parallel()
+
+	when := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	id := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	n := int64(7)
+	var nilLong *int64
+
+	params := func(p ...functionParameter) []functionParameter { return p }
+	long := functionParameter{name: "n", colType: types.Long}
+
+	tests := []struct {
+		desc   string
+		name   string
+		params []functionParameter
+		args   []interface{}
+		want   string
+		err    bool
+	}{
+		{desc: "No arguments", name: "F", want: "F()"},
+		{desc: "Quoted name", name: "my-func", want: `["my-func"]()`},
+		{desc: "Long", name: "F", params: params(long), args: []interface{}{42}, want: "F(long(42))"},
+		{desc: "Long from value", name: "F", params: params(long), args: []interface{}{value.Long{Value: 1, Valid: true}}, want: "F(long(1))"},
+		{desc: "Long from pointer", name: "F", params: params(long), args: []interface{}{&n}, want: "F(long(7))"},
+		{desc: "Null", name: "F", params: params(long), args: []interface{}{nil}, want: "F(long(null))"},
+		{desc: "Null pointer", name: "F", params: params(long), args: []interface{}{nilLong}, want: "F(long(null))"},
+		{desc: "Null value", name: "F", params: params(long), args: []interface{}{value.Long{}}, want: "F(long(null))"},
+		{
+			desc:   "Null string",
+			name:   "F",
+			params: params(functionParameter{name: "s", colType: types.String}),
+			args:   []interface{}{value.String{}},
+			want:   `F("")`,
+		},
+		{
+			desc:   "Dynamic string",
+			name:   "F",
+			params: params(functionParameter{name: "d", colType: types.Dynamic}),
+			args:   []interface{}{`1) | union SecretTable //`},
+			want:   `F(dynamic("1) | union SecretTable //"))`,
+		},
+		{
+			desc:   "Dynamic document",
+			name:   "F",
+			params: params(functionParameter{name: "d", colType: types.Dynamic}, functionParameter{name: "e", colType: types.Dynamic}),
+			args:   []interface{}{value.Dynamic{Value: []byte(`{"a": [1]}`), Valid: true}, json.RawMessage(`[1, 2]`)},
+			want:   `F(dynamic({"a": [1]}), dynamic([1,2]))`,
+		},
+		{
+			desc: "Scalar types",
+			name: "F",
+			params: params(
+				functionParameter{name: "b", colType: types.Bool},
+				functionParameter{name: "i", colType: types.Int},
+				functionParameter{name: "r", colType: types.Real},
+				functionParameter{name: "s", colType: types.String},
+				functionParameter{name: "t", colType: types.DateTime},
+				functionParameter{name: "ts", colType: types.Timespan},
+				functionParameter{name: "g", colType: types.GUID},
+				functionParameter{name: "dec", colType: types.Decimal},
+				functionParameter{name: "dyn", colType: types.Dynamic},
+			),
+			args: []interface{}{true, int16(3), 2, "it's", when, time.Minute, id, decimal.RequireFromString("1.5"), map[string]int{"a": 1}},
+			want: `F(bool(true), int(3), real(2), "it\'s", datetime(2023-01-02T03:04:05Z), timespan(00:01:00.0000000), guid(11111111-2222-3333-4444-555555555555), decimal(1.5), dynamic({"a":1}))`,
+		},
+		{
+			desc:   "Named",
+			name:   "F",
+			params: params(long, functionParameter{name: "s", colType: types.String, hasDefault: true}, functionParameter{name: "x", colType: types.Real, hasDefault: true}),
+			args:   []interface{}{Named("x", 1.5), Named("n", 2)},
+			want:   "F(long(2), x=real(1.5))",
+		},
+		{
+			desc:   "Tabular",
+			name:   "F",
+			params: params(functionParameter{name: "T"}, long),
+			args:   []interface{}{kql.New("T | where x > ").AddLong(1), 5},
+			want:   "F((T | where x > long(1)), long(5))",
+		},
+		{desc: "Too many", name: "F", params: params(long), args: []interface{}{1, 2}, err: true},
+		{desc: "Missing", name: "F", params: params(long), err: true},
+		{desc: "Unknown name", name: "F", params: params(long), args: []interface{}{Named("m", 1)}, err: true},
+		{desc: "Twice", name: "F", params: params(long), args: []interface{}{1, Named("n", 1)}, err: true},
+		{
+			desc:   "Positional after named",
+			name:   "F",
+			params: params(long, functionParameter{name: "m", colType: types.Long}),
+			args:   []interface{}{Named("n", 1), 2},
+			err:    true,
+		},
+		{desc: "Wrong type", name: "F", params: params(long), args: []interface{}{"1"}, err: true},
+		{
+			desc:   "Invalid dynamic document",
+			name:   "F",
+			params: params(functionParameter{name: "d", colType: types.Dynamic}),
+			args:   []interface{}{value.Dynamic{Value: []byte(`1) | union SecretTable //`), Valid: true}},
+			err:    true,
+		},
+		{desc: "Int overflow", name: "F", params: params(functionParameter{name: "i", colType: types.Int}), args: []interface{}{int64(1) << 40}, err: true},
+		{desc: "Tabular needs a builder", name: "F", params: params(functionParameter{name: "T"}), args: []interface{}{"T"}, err: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			got, err := functionCall(test.name, test.params, test.args)
+			if test.err {
+				require.Error(t, err)
+				assert.Equal(t, errors.KClientArgs, err.(*errors.Error).Kind)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got.String())
+		})
+	}
+}
+
+// functionConn is a queryer that serves ".show function" and records the query it runs.
+type functionConn struct {
+	mockConn
+	parameters string
+	queries    chan string
+}
+
+func (f functionConn) mgmt(_ context.Context, _ string, query Statement, _ *queryOptions) (execResp, error) {
+	ch := make(chan frames.Frame, 1)
+	ch <- v1.DataTable{
+		DataTypes: v1.DataTypes{
+			{ColumnName: "Name", ColumnType: "string"},
+			{ColumnName: "Parameters", ColumnType: "string"},
+		},
+		KustoRows: []value.Values{{value.String{Value: "F", Valid: true}, value.String{Value: f.parameters, Valid: true}}},
+	}
+	close(ch)
+	return execResp{frameCh: ch}, nil
+}
+
+func (f functionConn) query(_ context.Context, _ string, query Statement, _ *queryOptions) (execResp, error) {
+	f.queries <- query.String()
+
+	ch := make(chan frames.Frame, 3)
+	ch <- v2.DataSetHeader{}
+	ch <- v2.DataTable{
+		TableKind: frames.PrimaryResult,
+		TableName: frames.PrimaryResult,
+		Columns:   table.Columns{{Name: "x", Type: types.Long}},
+		KustoRows: []value.Values{{value.Long{Value: 1, Valid: true}}},
+	}
+	ch <- v2.DataSetCompletion{}
+	close(ch)
+	return execResp{frameCh: ch}, nil
+}
+
+func TestCallFunction(t *testing.T) {
+	t.Parallel()
+
+	conn := functionConn{parameters: `(n:long, s:string="x")`, queries: make(chan string, 1)}
+	client := NewMockClient()
+	client.conn = conn
+
+	iter, err := client.CallFunction(context.Background(), "db", "F", 3, Named("s", "y"), ServerTimeout(time.Minute))
+	require.NoError(t, err)
+	defer iter.Stop()
+
+	assert.Equal(t, `F(long(3), "y")`, <-conn.queries)
+
+	var got []int64
+	err = iter.DoOnRowOrError(func(row *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		got = append(got, row.Values[0].(value.Long).Value)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, got)
+
+	_, err = client.CallFunction(context.Background(), "db", "F")
+	assert.Error(t, err)
+}
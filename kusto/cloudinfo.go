@@ -4,13 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	kustoErrors "github.com/Azure/azure-kusto-go/kusto/data/errors"
-	"github.com/Azure/azure-kusto-go/kusto/utils"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // abstraction to query metadata and use this information for providing all
@@ -49,67 +50,167 @@ var defaultCloudInfo = CloudInfo{
 	FirstPartyAuthorityURL: defaultFirstPartyAuthorityUrl,
 }
 
+// CloudInfoCachePolicy controls how long cloud metadata fetched by GetMetadata is cached.
+type CloudInfoCachePolicy struct {
+	// TTL is how long a fetched entry is used before it is fetched again. Zero means entries never expire.
+	TTL time.Duration
+	// ServeStaleOnError returns an expired entry if refreshing it fails, instead of returning the error.
+	ServeStaleOnError bool
+}
+
+// CloudInfoCacheEntry describes a cached CloudInfo.
+type CloudInfoCacheEntry struct {
+	CloudInfo CloudInfo
+	// FetchedAt is when the entry was fetched or set.
+	FetchedAt time.Time
+	// Static is true if the entry was set with SetCloudInfo(). Static entries never expire.
+	Static bool
+}
+
+// cloudInfoCacheItem holds the cache state for a single endpoint. Fetches for the same endpoint are serialized.
+type cloudInfoCacheItem struct {
+	mu    sync.Mutex
+	entry CloudInfoCacheEntry
+	valid bool
+}
+
 // cache to query it once per instance
 var cloudInfoCache sync.Map
 
-func GetMetadata(kustoUri string, httpClient *http.Client) (CloudInfo, error) {
-	// retrieve &return if exists
-	once, ok := cloudInfoCache.Load(kustoUri)
-	if !ok {
-		once = utils.NewOnce[CloudInfo]()
-		cloudInfoCache.Store(kustoUri, once)
+var cloudInfoPolicy atomic.Value
+
+// SetCloudInfoCachePolicy sets the policy used for all cached cloud metadata. By default, entries never expire.
+func SetCloudInfoCachePolicy(policy CloudInfoCachePolicy) {
+	cloudInfoPolicy.Store(policy)
+}
+
+func getCloudInfoCachePolicy() CloudInfoCachePolicy {
+	if p, ok := cloudInfoPolicy.Load().(CloudInfoCachePolicy); ok {
+		return p
 	}
+	return CloudInfoCachePolicy{}
+}
 
-	return once.(utils.Once[CloudInfo]).Do(func() (CloudInfo, error) {
-		u, err := url.Parse(kustoUri)
-		if err != nil {
-			return CloudInfo{}, err
-		}
-		if !strings.HasPrefix(u.Path, "/") {
-			u.Path = "/" + u.Path
-		}
-		u = u.JoinPath(metadataPath)
-		// TODO should we make this timeout configurable.
-		req, err := http.NewRequest("GET", u.String(), nil)
+func loadCloudInfoCacheItem(kustoUri string) *cloudInfoCacheItem {
+	item, _ := cloudInfoCache.LoadOrStore(kustoUri, &cloudInfoCacheItem{})
+	return item.(*cloudInfoCacheItem)
+}
 
-		if err != nil {
-			return CloudInfo{}, kustoErrors.E(kustoErrors.OpCloudInfo, kustoErrors.KHTTPError, err)
-		}
-		resp, err := httpClient.Do(req)
+// SetCloudInfo pre-seeds the cache for kustoUri, so that GetMetadata does not call the metadata endpoint.
+// This is useful for sovereign clouds and air-gapped deployments where the metadata call is blocked.
+func SetCloudInfo(kustoUri string, info CloudInfo) {
+	item := loadCloudInfoCacheItem(kustoUri)
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	item.entry = CloudInfoCacheEntry{CloudInfo: info, FetchedAt: time.Now(), Static: true}
+	item.valid = true
+}
 
-		if err != nil {
-			return CloudInfo{}, err
-		}
+// CachedCloudInfo returns the cache entry for kustoUri, if there is one.
+func CachedCloudInfo(kustoUri string) (CloudInfoCacheEntry, bool) {
+	item, ok := cloudInfoCache.Load(kustoUri)
+	if !ok {
+		return CloudInfoCacheEntry{}, false
+	}
+	i := item.(*cloudInfoCacheItem)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.entry, i.valid
+}
 
-		// Handle internal server error as a special case and return as an error (to be consistent with other SDK's)
-		if resp.StatusCode >= 300 && resp.StatusCode != 404 {
-			return CloudInfo{}, kustoErrors.E(kustoErrors.OpCloudInfo, kustoErrors.KHTTPError, fmt.Errorf("error %s when querying endpoint %s",
-				resp.Status, u.String()),
-			)
-		}
+// EvictCloudInfo removes the cache entry for kustoUri, so the next GetMetadata call fetches it again.
+func EvictCloudInfo(kustoUri string) {
+	cloudInfoCache.Delete(kustoUri)
+}
+
+// ClearCloudInfoCache removes all cached cloud metadata.
+func ClearCloudInfoCache() {
+	cloudInfoCache.Range(func(key, _ interface{}) bool {
+		cloudInfoCache.Delete(key)
+		return true
+	})
+}
 
-		defer resp.Body.Close()
+// get returns the cached CloudInfo if it is still fresh, otherwise calls fetch to refresh it.
+func (c *cloudInfoCacheItem) get(fetch func() (CloudInfo, error)) (CloudInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		b, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return CloudInfo{}, kustoErrors.E(kustoErrors.OpCloudInfo, kustoErrors.KHTTPError, err)
-		}
+	policy := getCloudInfoCachePolicy()
+	if c.valid && (c.entry.Static || policy.TTL <= 0 || time.Since(c.entry.FetchedAt) < policy.TTL) {
+		return c.entry.CloudInfo, nil
+	}
 
-		// Covers scenarios of 200/OK with no body or a 404 where there is no body
-		if len(b) == 0 {
-			return defaultCloudInfo, nil
+	info, err := fetch()
+	if err != nil {
+		if c.valid && policy.ServeStaleOnError {
+			return c.entry.CloudInfo, nil
 		}
+		return CloudInfo{}, err
+	}
 
-		md := metaResp{}
+	c.entry = CloudInfoCacheEntry{CloudInfo: info, FetchedAt: time.Now()}
+	c.valid = true
+	return info, nil
+}
 
-		if err := json.Unmarshal(b, &md); err != nil {
-			return CloudInfo{}, err
-		}
-		// this should be set in the map by now
-		return md.AzureAD, nil
+// GetMetadata returns the CloudInfo for kustoUri, fetching it from the cluster's metadata endpoint if it is not
+// cached or has expired according to the CloudInfoCachePolicy.
+func GetMetadata(kustoUri string, httpClient *http.Client) (CloudInfo, error) {
+	return loadCloudInfoCacheItem(kustoUri).get(func() (CloudInfo, error) {
+		return fetchMetadata(kustoUri, httpClient)
 	})
 }
 
+func fetchMetadata(kustoUri string, httpClient *http.Client) (CloudInfo, error) {
+	u, err := url.Parse(kustoUri)
+	if err != nil {
+		return CloudInfo{}, err
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	u = u.JoinPath(metadataPath)
+	// TODO should we make this timeout configurable.
+	req, err := http.NewRequest("GET", u.String(), nil)
+
+	if err != nil {
+		return CloudInfo{}, kustoErrors.E(kustoErrors.OpCloudInfo, kustoErrors.KHTTPError, err)
+	}
+	resp, err := httpClient.Do(req)
+
+	if err != nil {
+		return CloudInfo{}, err
+	}
+
+	// Handle internal server error as a special case and return as an error (to be consistent with other SDK's)
+	if resp.StatusCode >= 300 && resp.StatusCode != 404 {
+		return CloudInfo{}, kustoErrors.E(kustoErrors.OpCloudInfo, kustoErrors.KHTTPError, fmt.Errorf("error %s when querying endpoint %s",
+			resp.Status, u.String()),
+		)
+	}
+
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return CloudInfo{}, kustoErrors.E(kustoErrors.OpCloudInfo, kustoErrors.KHTTPError, err)
+	}
+
+	// Covers scenarios of 200/OK with no body or a 404 where there is no body
+	if len(b) == 0 {
+		return defaultCloudInfo, nil
+	}
+
+	md := metaResp{}
+
+	if err := json.Unmarshal(b, &md); err != nil {
+		return CloudInfo{}, err
+	}
+	// this should be set in the map by now
+	return md.AzureAD, nil
+}
+
 func getEnvOrDefault(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
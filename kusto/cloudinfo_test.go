@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -145,3 +146,89 @@ func TestGetMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestCloudInfoCache(t *testing.T) {
+	calls := 0
+	code := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			_, _ = w.Write([]byte(`{"AzureAD": {"LoginEndpoint": "https://login.example.com"}}`))
+		}
+	}))
+	defer s.Close()
+
+	defer SetCloudInfoCachePolicy(CloudInfoCachePolicy{})
+	uri := s.URL + "/cache"
+	defer EvictCloudInfo(uri)
+
+	res, err := GetMetadata(uri, &http.Client{})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://login.example.com", res.LoginEndpoint)
+	_, err = GetMetadata(uri, &http.Client{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	entry, ok := CachedCloudInfo(uri)
+	assert.True(t, ok)
+	assert.False(t, entry.Static)
+	assert.Equal(t, "https://login.example.com", entry.CloudInfo.LoginEndpoint)
+
+	// Expired entries are fetched again.
+	SetCloudInfoCachePolicy(CloudInfoCachePolicy{TTL: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	_, err = GetMetadata(uri, &http.Client{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// A failed refresh returns an error, unless stale entries can be served.
+	code = http.StatusInternalServerError
+	time.Sleep(time.Millisecond)
+	_, err = GetMetadata(uri, &http.Client{})
+	assert.Error(t, err)
+
+	SetCloudInfoCachePolicy(CloudInfoCachePolicy{TTL: time.Nanosecond, ServeStaleOnError: true})
+	res, err = GetMetadata(uri, &http.Client{})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://login.example.com", res.LoginEndpoint)
+
+	// Evicted entries are gone until fetched again.
+	EvictCloudInfo(uri)
+	_, ok = CachedCloudInfo(uri)
+	assert.False(t, ok)
+}
+
+func TestSetCloudInfo(t *testing.T) {
+	uri := "https://airgapped.example.com"
+	defer EvictCloudInfo(uri)
+
+	want := CloudInfo{LoginEndpoint: "https://login.airgapped.example.com", KustoServiceResourceID: "https://airgapped.example.com"}
+	SetCloudInfo(uri, want)
+
+	// The http client would fail on any request, so this only works if the metadata endpoint is not called.
+	res, err := GetMetadata(uri, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, want, res)
+
+	entry, ok := CachedCloudInfo(uri)
+	assert.True(t, ok)
+	assert.True(t, entry.Static)
+}
+
+func TestWithCloudInfo(t *testing.T) {
+	t.Parallel()
+
+	info := CloudInfo{LoginEndpoint: "https://login.microsoftonline.com", KustoServiceResourceID: "https://kusto.kusto.windows.net"}
+	client, err := New(NewConnectionStringBuilder("https://withcloudinfo.kusto.windows.net").WithApplicationToken("app", "token"), WithCloudInfo(info))
+	assert.NoError(t, err)
+
+	conn := client.conn.(*Conn)
+	res, err := conn.getCloudInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, info, res)
+	assert.NoError(t, conn.validateEndpoint())
+
+	_, ok := CachedCloudInfo("https://withcloudinfo.kusto.windows.net")
+	assert.False(t, ok)
+}
@@ -0,0 +1,328 @@
+package kusto
+
+// config.go provides loading of client configuration from environment variables or a JSON file, so that services
+// can share a single convention instead of each inventing their own.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/trustedendpoints"
+)
+
+// AuthMode is the authentication method a Config will use to build a ConnectionStringBuilder.
+type AuthMode string
+
+const (
+	// AuthDefault uses the DefaultAzureCredential chain (environment, managed identity, az cli).
+	AuthDefault AuthMode = "default"
+	// AuthAzCli uses the current az cli login.
+	AuthAzCli AuthMode = "azcli"
+	// AuthManagedIdentity uses a managed identity. If ClientID is set, it is used as the user assigned identity.
+	AuthManagedIdentity AuthMode = "managedidentity"
+	// AuthAppKey uses an AAD application id and secret.
+	AuthAppKey AuthMode = "appkey"
+	// AuthAppCertificate uses an AAD application id and a certificate read from CertificatePath.
+	AuthAppCertificate AuthMode = "appcertificate"
+	// AuthWorkloadIdentity uses Kubernetes workload identity.
+	AuthWorkloadIdentity AuthMode = "workloadidentity"
+	// AuthInteractive launches the system browser to log in a user.
+	AuthInteractive AuthMode = "interactive"
+	// AuthNone sends no credentials, for the local Kusto emulator. See ConnectionStringBuilder.WithNoAuth().
+	AuthNone AuthMode = "none"
+)
+
+// authModeAliases maps normalized names to an AuthMode. This includes the names used by the quickstart sample config.
+var authModeAliases = map[string]AuthMode{
+	"":                 "",
+	"default":          AuthDefault,
+	"azcli":            AuthAzCli,
+	"managedidentity":  AuthManagedIdentity,
+	"msi":              AuthManagedIdentity,
+	"appkey":           AuthAppKey,
+	"appcertificate":   AuthAppCertificate,
+	"workloadidentity": AuthWorkloadIdentity,
+	"interactive":      AuthInteractive,
+	"userprompt":       AuthInteractive,
+	"none":             AuthNone,
+	"noauth":           AuthNone,
+	"emulator":         AuthNone,
+}
+
+// parseAuthMode parses an AuthMode, ignoring case, dashes and underscores.
+func parseAuthMode(s string) (AuthMode, error) {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(s))
+	mode, ok := authModeAliases[normalized]
+	if !ok {
+		return "", fmt.Errorf("unsupported auth mode %q", s)
+	}
+	return mode, nil
+}
+
+// Environment variable names read by ConfigFromEnv, after the prefix.
+const (
+	EnvConnectionString     = "CONNECTION_STRING"
+	EnvEndpoint             = "ENDPOINT"
+	EnvAuthMode             = "AUTH_MODE"
+	EnvTenantID             = "TENANT_ID"
+	EnvClientID             = "CLIENT_ID"
+	EnvClientSecret         = "CLIENT_SECRET"
+	EnvCertificatePath      = "CERTIFICATE_PATH"
+	EnvCertificatePassword  = "CERTIFICATE_PASSWORD"
+	EnvSendCertificateChain = "SEND_CERTIFICATE_CHAIN"
+	EnvTokenFilePath        = "TOKEN_FILE_PATH"
+	EnvDatabase             = "DATABASE"
+	EnvAppName              = "APP_NAME"
+	EnvTrustedEndpointsFile = "TRUSTED_ENDPOINTS_FILE"
+	EnvIngestionURI         = "INGESTION_URI"
+)
+
+// Config holds the settings needed to create a Client. It can be loaded with ConfigFromEnv() or ConfigFromFile(),
+// or filled in directly. Either ConnectionString or Endpoint must be set, but not both.
+type Config struct {
+	// ConnectionString is a full Kusto connection string. If it carries credentials, AuthMode should be empty.
+	ConnectionString string `json:"connectionString,omitempty"`
+	// Endpoint is the cluster URI, such as https://<cluster>.<region>.kusto.windows.net.
+	Endpoint string `json:"endpoint,omitempty"`
+	// AuthMode selects how to authenticate. See the Auth* constants.
+	AuthMode AuthMode `json:"authMode,omitempty"`
+	// TenantID is the AAD tenant (authority) id.
+	TenantID string `json:"tenantId,omitempty"`
+	// ClientID is the AAD application id, or the user assigned managed identity id.
+	ClientID string `json:"clientId,omitempty"`
+	// ClientSecret is the application key used with AuthAppKey.
+	ClientSecret string `json:"clientSecret,omitempty"`
+	// CertificatePath is the path to a PEM or PKCS#12 certificate used with AuthAppCertificate.
+	CertificatePath string `json:"certificatePath,omitempty"`
+	// CertificatePassword is the password of the certificate, if any.
+	CertificatePassword string `json:"certificatePassword,omitempty"`
+	// SendCertificateChain sends the x5c header when using AuthAppCertificate.
+	SendCertificateChain bool `json:"sendCertificateChain,omitempty"`
+	// TokenFilePath is the federated token file used with AuthWorkloadIdentity.
+	TokenFilePath string `json:"tokenFilePath,omitempty"`
+	// DefaultDatabase is the database the application should use by default.
+	DefaultDatabase string `json:"defaultDatabase,omitempty"`
+	// AppName is sent as the application name for tracing (x-ms-app).
+	AppName string `json:"appName,omitempty"`
+	// TrustedEndpointsFile is a JSON file of trusted endpoint rules, in the shape of the well-known endpoints list,
+	// used by this client instead of the process-wide policy.
+	TrustedEndpointsFile string `json:"trustedEndpointsFile,omitempty"`
+	// IngestionURI is the data management (ingestion) endpoint, if it isn't the endpoint with "ingest-" added to the host.
+	IngestionURI string `json:"ingestionUri,omitempty"`
+}
+
+// ConfigFromEnv reads a Config from environment variables named prefix + one of the Env* constants.
+// For example, ConfigFromEnv("KUSTO_") reads KUSTO_ENDPOINT, KUSTO_AUTH_MODE, ... The result is validated.
+func ConfigFromEnv(prefix string) (*Config, error) {
+	get := func(name string) string {
+		return strings.TrimSpace(os.Getenv(prefix + name))
+	}
+
+	c := &Config{
+		ConnectionString:     get(EnvConnectionString),
+		Endpoint:             get(EnvEndpoint),
+		AuthMode:             AuthMode(get(EnvAuthMode)),
+		TenantID:             get(EnvTenantID),
+		ClientID:             get(EnvClientID),
+		ClientSecret:         get(EnvClientSecret),
+		CertificatePath:      get(EnvCertificatePath),
+		CertificatePassword:  get(EnvCertificatePassword),
+		TokenFilePath:        get(EnvTokenFilePath),
+		DefaultDatabase:      get(EnvDatabase),
+		AppName:              get(EnvAppName),
+		TrustedEndpointsFile: get(EnvTrustedEndpointsFile),
+		IngestionURI:         get(EnvIngestionURI),
+	}
+
+	if s := get(EnvSendCertificateChain); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.ES(errors.OpServConn, errors.KClientArgs, "environment variable %s%s is not a bool: %s", prefix, EnvSendCertificateChain, err).SetNoRetry()
+		}
+		c.SendCertificateChain = b
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	// The Config is not the caller's, so its auth mode can be normalized.
+	c.AuthMode, _ = c.authMode()
+	return c, nil
+}
+
+// ConfigFromFile reads a Config from a JSON file. The field names are the json tags of Config. The result is validated.
+func ConfigFromFile(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.E(errors.OpServConn, errors.KLocalFileSystem, fmt.Errorf("could not read config file %q: %w", path, err)).SetNoRetry()
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, errors.ES(errors.OpServConn, errors.KClientArgs, "could not parse config file %q: %s", path, err).SetNoRetry()
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	c.AuthMode, _ = c.authMode()
+	return c, nil
+}
+
+// Validate checks that the Config is complete and consistent. It does not change the Config.
+func (c *Config) Validate() error {
+	argErr := func(s string, args ...interface{}) error {
+		return errors.ES(errors.OpServConn, errors.KClientArgs, "invalid client config: "+s, args...).SetNoRetry()
+	}
+
+	switch {
+	case isEmpty(c.ConnectionString) && isEmpty(c.Endpoint):
+		return argErr("one of connection string or endpoint must be set")
+	case !isEmpty(c.ConnectionString) && !isEmpty(c.Endpoint):
+		return argErr("connection string and endpoint cannot both be set")
+	}
+
+	if !isEmpty(c.Endpoint) {
+		u, err := url.Parse(c.Endpoint)
+		if err != nil {
+			return argErr("could not parse endpoint(%s): %s", c.Endpoint, err)
+		}
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return argErr("endpoint(%s) must start with https:// or, for the emulator, http://", c.Endpoint)
+		}
+	}
+
+	mode, err := c.authMode()
+	if err != nil {
+		return argErr("%s", err)
+	}
+
+	switch mode {
+	case "":
+		if isEmpty(c.ConnectionString) {
+			return argErr("auth mode must be set when using an endpoint")
+		}
+	case AuthAppKey:
+		if isEmpty(c.ClientID) || isEmpty(c.ClientSecret) || isEmpty(c.TenantID) {
+			return argErr("auth mode %s requires client id, client secret and tenant id", mode)
+		}
+	case AuthAppCertificate:
+		if isEmpty(c.ClientID) || isEmpty(c.CertificatePath) || isEmpty(c.TenantID) {
+			return argErr("auth mode %s requires client id, certificate path and tenant id", mode)
+		}
+	case AuthWorkloadIdentity:
+		if isEmpty(c.ClientID) || isEmpty(c.TokenFilePath) || isEmpty(c.TenantID) {
+			return argErr("auth mode %s requires client id, token file path and tenant id", mode)
+		}
+	}
+	return nil
+}
+
+// Build returns a ConnectionStringBuilder and the client Options described by the Config.
+// The result can be passed directly to New(). Use NewDatabase() for a handle on the DefaultDatabase.
+func (c *Config) Build() (*ConnectionStringBuilder, []Option, error) {
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var kcsb *ConnectionStringBuilder
+	if !isEmpty(c.ConnectionString) {
+		var err error
+		kcsb, err = parseConnectionString(c.ConnectionString)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		kcsb = NewConnectionStringBuilder(c.Endpoint)
+	}
+
+	mode, _ := c.authMode()
+	switch mode {
+	case AuthDefault:
+		kcsb.WithDefaultAzureCredential()
+		kcsb.AuthorityId = c.TenantID
+	case AuthAzCli:
+		kcsb.WithAzCli()
+		kcsb.AuthorityId = c.TenantID
+	case AuthManagedIdentity:
+		if isEmpty(c.ClientID) {
+			kcsb.WithSystemManagedIdentity()
+		} else {
+			kcsb.WithUserManagedIdentity(c.ClientID)
+		}
+	case AuthAppKey:
+		kcsb.WithAadAppKey(c.ClientID, c.ClientSecret, c.TenantID)
+	case AuthAppCertificate:
+		cert, err := os.ReadFile(c.CertificatePath)
+		if err != nil {
+			return nil, nil, errors.E(errors.OpServConn, errors.KLocalFileSystem, fmt.Errorf("could not read certificate %q: %w", c.CertificatePath, err)).SetNoRetry()
+		}
+		kcsb.WithAppCertificate(c.ClientID, string(cert), c.CertificatePassword, c.SendCertificateChain, c.TenantID)
+	case AuthWorkloadIdentity:
+		kcsb.WithKubernetesWorkloadIdentity(c.ClientID, c.TokenFilePath, c.TenantID)
+	case AuthInteractive:
+		kcsb.WithInteractiveLogin(c.TenantID)
+	case AuthNone:
+		kcsb.WithNoAuth()
+	}
+
+	if !isEmpty(c.AppName) {
+		kcsb.ApplicationForTracing = c.AppName
+	}
+
+	if !isEmpty(c.IngestionURI) {
+		kcsb.IngestionURI = c.IngestionURI
+	}
+
+	var options []Option
+	if !isEmpty(c.TrustedEndpointsFile) {
+		trusted, err := trustedendpoints.FromFile(c.TrustedEndpointsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		options = append(options, WithTrustedEndpoints(trusted))
+	}
+
+	return kcsb, options, nil
+}
+
+// NewClient is a shortcut for calling Build() and passing the result to New().
+func (c *Config) NewClient(options ...Option) (*Client, error) {
+	kcsb, opts, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	return New(kcsb, append(opts, options...)...)
+}
+
+// NewDatabase is NewClient(), returning a handle on the DefaultDatabase of the Config, which must be set.
+func (c *Config) NewDatabase(options ...Option) (*Database, error) {
+	if isEmpty(c.DefaultDatabase) {
+		return nil, errors.ES(errors.OpServConn, errors.KClientArgs, "invalid client config: the default database is not set").SetNoRetry()
+	}
+	client, err := c.NewClient(options...)
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(c.DefaultDatabase), nil
+}
+
+// authMode returns the AuthMode of the Config, normalized from any of its aliases.
+func (c *Config) authMode() (AuthMode, error) {
+	return parseAuthMode(string(c.AuthMode))
+}
+
+// parseConnectionString wraps NewConnectionStringBuilder, which panics on bad input, to return an error instead.
+func parseConnectionString(connStr string) (kcsb *ConnectionStringBuilder, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.ES(errors.OpServConn, errors.KClientArgs, "invalid connection string: %v", r).SetNoRetry()
+		}
+	}()
+	return NewConnectionStringBuilder(connStr), nil
+}
@@ -0,0 +1,117 @@
+package kusto
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("TESTKUSTO_ENDPOINT", "https://help.kusto.windows.net")
+	t.Setenv("TESTKUSTO_AUTH_MODE", "App-Key")
+	t.Setenv("TESTKUSTO_TENANT_ID", "tenant")
+	t.Setenv("TESTKUSTO_CLIENT_ID", "client")
+	t.Setenv("TESTKUSTO_CLIENT_SECRET", "secret")
+	t.Setenv("TESTKUSTO_DATABASE", "Samples")
+	t.Setenv("TESTKUSTO_APP_NAME", "myApp")
+
+	c, err := ConfigFromEnv("TESTKUSTO_")
+	require.NoError(t, err)
+	assert.Equal(t, AuthAppKey, c.AuthMode)
+	assert.Equal(t, "Samples", c.DefaultDatabase)
+
+	kcsb, _, err := c.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "https://help.kusto.windows.net", kcsb.DataSource)
+	assert.Equal(t, "client", kcsb.ApplicationClientId)
+	assert.Equal(t, "secret", kcsb.ApplicationKey)
+	assert.Equal(t, "tenant", kcsb.AuthorityId)
+	assert.Equal(t, "myApp", kcsb.ApplicationForTracing)
+}
+
+func TestConfigFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"connectionString": "https://help.kusto.windows.net;Application Client Id=client;Application Key=secret;Authority Id=tenant", "defaultDatabase": "Samples"}`), 0600))
+
+	c, err := ConfigFromFile(path)
+	require.NoError(t, err)
+
+	kcsb, _, err := c.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "https://help.kusto.windows.net", kcsb.DataSource)
+	assert.Equal(t, "client", kcsb.ApplicationClientId)
+	assert.Equal(t, "Samples", c.DefaultDatabase)
+
+	_, err = ConfigFromFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "Empty", config: Config{}, wantErr: true},
+		{name: "Both", config: Config{ConnectionString: "https://a.kusto.windows.net", Endpoint: "https://a.kusto.windows.net", AuthMode: AuthAzCli}, wantErr: true},
+		{name: "EndpointNoAuth", config: Config{Endpoint: "https://a.kusto.windows.net"}, wantErr: true},
+		{name: "BadScheme", config: Config{Endpoint: "ftp://a.kusto.windows.net", AuthMode: AuthAzCli}, wantErr: true},
+		{name: "BadMode", config: Config{Endpoint: "https://a.kusto.windows.net", AuthMode: "magic"}, wantErr: true},
+		{name: "AppKeyMissingSecret", config: Config{Endpoint: "https://a.kusto.windows.net", AuthMode: AuthAppKey, ClientID: "a", TenantID: "t"}, wantErr: true},
+		{name: "QuickstartMode", config: Config{Endpoint: "https://a.kusto.windows.net", AuthMode: "UserPrompt"}},
+		{name: "ManagedIdentity", config: Config{Endpoint: "https://a.kusto.windows.net", AuthMode: AuthManagedIdentity}},
+		{name: "Emulator", config: Config{Endpoint: "http://localhost:8080", AuthMode: "emulator"}},
+		{name: "ConnectionStringOnly", config: Config{ConnectionString: "https://a.kusto.windows.net"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := test.config.Validate()
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigValidateDoesNotChange(t *testing.T) {
+	t.Parallel()
+
+	c := Config{Endpoint: "https://a.kusto.windows.net", AuthMode: "User-Prompt"}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, AuthMode("User-Prompt"), c.AuthMode)
+
+	kcsb, _, err := c.Build()
+	require.NoError(t, err)
+	assert.True(t, kcsb.InteractiveLogin)
+	assert.Equal(t, AuthMode("User-Prompt"), c.AuthMode)
+}
+
+func TestConfigNewDatabase(t *testing.T) {
+	t.Parallel()
+
+	c := Config{Endpoint: "http://localhost:8080", AuthMode: AuthNone, DefaultDatabase: "Samples"}
+	db, err := c.NewDatabase()
+	require.NoError(t, err)
+	assert.Equal(t, "Samples", db.Name())
+	assert.Equal(t, "http://localhost:8080", db.Client().Endpoint())
+
+	c.DefaultDatabase = ""
+	_, err = c.NewDatabase()
+	assert.Error(t, err)
+}
+
+func TestConfigBadConnectionString(t *testing.T) {
+	c := Config{ConnectionString: "https://a.kusto.windows.net;NotAKey=1"}
+	_, _, err := c.Build()
+	assert.Error(t, err)
+}
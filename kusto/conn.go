@@ -21,6 +21,7 @@ import (
 	v1 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v1"
 	v2 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v2"
 	"github.com/Azure/azure-kusto-go/kusto/internal/response"
+	"github.com/Azure/azure-kusto-go/kusto/kql/lexer"
 	truestedEndpoints "github.com/Azure/azure-kusto-go/kusto/trustedendpoints"
 	"github.com/google/uuid"
 )
@@ -39,10 +40,38 @@ type Conn struct {
 	client                             *http.Client
 	endpointValidated                  atomic.Bool
 	clientDetails                      *ClientDetails
+	trustedEndpoints                   *truestedEndpoints.TrustedEndpoints
+	skipEndpointValidation             bool
+	cloudInfo                          *CloudInfo
+}
+
+// ConnOption is an optional argument to NewConn().
+type ConnOption func(c *Conn)
+
+// ConnTrustedEndpoints sets the trusted endpoints policy the Conn validates its endpoint against.
+// If not set, the process-wide trustedendpoints.Instance is used.
+func ConnTrustedEndpoints(trusted *truestedEndpoints.TrustedEndpoints) ConnOption {
+	return func(c *Conn) {
+		c.trustedEndpoints = trusted
+	}
+}
+
+// ConnSkipEndpointValidation disables trusted endpoint validation for the Conn.
+func ConnSkipEndpointValidation() ConnOption {
+	return func(c *Conn) {
+		c.skipEndpointValidation = true
+	}
+}
+
+// ConnCloudInfo sets the CloudInfo for the Conn's endpoint, instead of fetching it from the metadata endpoint.
+func ConnCloudInfo(info CloudInfo) ConnOption {
+	return func(c *Conn) {
+		c.cloudInfo = &info
+	}
 }
 
 // NewConn returns a new Conn object with an injected http.Client
-func NewConn(endpoint string, auth Authorization, client *http.Client, clientDetails *ClientDetails) (*Conn, error) {
+func NewConn(endpoint string, auth Authorization, client *http.Client, clientDetails *ClientDetails, options ...ConnOption) (*Conn, error) {
 	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, errors.ES(errors.OpServConn, errors.KClientArgs, "could not parse the endpoint(%s): %s", endpoint, err).SetNoRetry()
@@ -70,6 +99,10 @@ func NewConn(endpoint string, auth Authorization, client *http.Client, clientDet
 		endpoint:        endpoint,
 	}
 
+	for _, o := range options {
+		o(c)
+	}
+
 	return c, nil
 }
 
@@ -86,8 +119,12 @@ type connOptions struct {
 // query makes a query for the purpose of extracting data from Kusto. Context can be used to set
 // a timeout or cancel the query. Queries cannot take longer than 5 minutes.
 func (c *Conn) query(ctx context.Context, db string, query Statement, options *queryOptions) (execResp, error) {
-	if strings.HasPrefix(strings.TrimSpace(query.String()), ".") {
-		return execResp{}, errors.ES(errors.OpQuery, errors.KClientArgs, "a Stmt to Query() cannot begin with a period(.), only Mgmt() calls can do that").SetNoRetry()
+	isCommand, err := lexer.IsCommand(query.String())
+	if err != nil {
+		return execResp{}, errors.ES(errors.OpQuery, errors.KClientArgs, "the query is not valid KQL: %s", err).SetNoRetry()
+	}
+	if isCommand {
+		return execResp{}, errors.ES(errors.OpQuery, errors.KClientArgs, "a Stmt to Query() cannot be a management command beginning with a period(.), only Mgmt() calls can do that").SetNoRetry()
 	}
 
 	return c.execute(ctx, execQuery, db, query, *options.requestProperties)
@@ -252,21 +289,38 @@ func (c *Conn) doRequestImpl(
 }
 
 func (c *Conn) validateEndpoint() error {
+	if c.skipEndpointValidation {
+		return nil
+	}
+
 	if !c.endpointValidated.Load() {
-		var err error
-		if cloud, err := GetMetadata(c.endpoint, c.client); err == nil {
-			err = truestedEndpoints.Instance.ValidateTrustedEndpoint(c.endpoint, cloud.LoginEndpoint)
-			if err == nil {
-				c.endpointValidated.Store(true)
-			}
+		trusted := c.trustedEndpoints
+		if trusted == nil {
+			trusted = truestedEndpoints.Instance
 		}
 
-		return err
+		cloud, err := c.getCloudInfo()
+		if err != nil {
+			// The metadata endpoint may be unreachable while the engine is not, so the endpoint is validated on a later request.
+			return nil
+		}
+		if err := trusted.ValidateTrustedEndpoint(c.endpoint, cloud.LoginEndpoint); err != nil {
+			return err
+		}
+		c.endpointValidated.Store(true)
 	}
 
 	return nil
 }
 
+// getCloudInfo returns the CloudInfo set with ConnCloudInfo(), or fetches it with GetMetadata().
+func (c *Conn) getCloudInfo() (CloudInfo, error) {
+	if c.cloudInfo != nil {
+		return *c.cloudInfo, nil
+	}
+	return GetMetadata(c.endpoint, c.client)
+}
+
 const ClientRequestIdHeader = "x-ms-client-request-id"
 const ApplicationHeader = "x-ms-app"
 const UserHeader = "x-ms-user"
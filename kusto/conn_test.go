@@ -4,8 +4,11 @@ import (
 	"context"
 	"github.com/Azure/azure-kusto-go/kusto/data/errors"
 	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/Azure/azure-kusto-go/kusto/trustedendpoints"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
 	"strings"
 	"testing"
 )
@@ -62,7 +65,7 @@ func TestHeaders(t *testing.T) {
 			queryOptions = append(queryOptions, Application(tt.propApplication))
 			queryOptions = append(queryOptions, User(tt.propUser))
 
-			opts, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("test"), queryCall, queryOptions...)
+			opts, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("test"), queryCall, nil, queryOptions...)
 			require.NoError(t, err)
 
 			client, err := New(kcsb)
@@ -199,3 +202,90 @@ func TestSetConnectorDetails(t *testing.T) {
 		})
 	}
 }
+
+type metadataNotFoundTransport struct{}
+
+func (metadataNotFoundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestConnTrustedEndpoints(t *testing.T) {
+	t.Parallel()
+
+	trusted, err := trustedendpoints.FromJSON([]byte(`{"AllowedEndpointsByLogin": {"` + defaultCloudInfo.LoginEndpoint + `": {"AllowedKustoSuffixes": [".private.example.com"]}}}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		endpoint string
+		options  []ConnOption
+		wantErr  bool
+	}{
+		{name: "GlobalRejects", endpoint: "https://cluster.private.example.com", wantErr: true},
+		{name: "ClientPolicyAccepts", endpoint: "https://cluster2.private.example.com", options: []ConnOption{ConnTrustedEndpoints(trusted)}},
+		{name: "ClientPolicyRejects", endpoint: "https://cluster.kusto.windows.net", options: []ConnOption{ConnTrustedEndpoints(trusted)}, wantErr: true},
+		{name: "SkipValidation", endpoint: "https://cluster3.private.example.com", options: []ConnOption{ConnSkipEndpointValidation()}},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			conn, err := NewConn(tt.endpoint, Authorization{TokenProvider: &TokenProvider{}}, &http.Client{Transport: metadataNotFoundTransport{}}, NewClientDetails("", ""), tt.options...)
+			require.NoError(t, err)
+
+			err = conn.validateEndpoint()
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "not trusted")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+type metadataErrorTransport struct{}
+
+func (metadataErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error", Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestConnMetadataFailure(t *testing.T) {
+	t.Parallel()
+
+	conn, err := NewConn("https://metadatafailure.kusto.windows.net", Authorization{TokenProvider: &TokenProvider{}}, &http.Client{Transport: metadataErrorTransport{}}, NewClientDetails("", ""))
+	require.NoError(t, err)
+
+	// A metadata failure does not fail the request, and the endpoint is validated again on the next one.
+	assert.NoError(t, conn.validateEndpoint())
+	assert.False(t, conn.endpointValidated.Load())
+}
+
+func TestConnQueryPreflight(t *testing.T) {
+	t.Parallel()
+
+	conn, err := NewConn("https://cluster.kusto.windows.net", Authorization{TokenProvider: &TokenProvider{}}, &http.Client{Transport: metadataNotFoundTransport{}}, NewClientDetails("", ""), ConnSkipEndpointValidation())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{name: "Command", query: ".show tables", err: "management command"},
+		{name: "CommandAfterComment", query: "// comment\n.drop table T", err: "management command"},
+		{name: "UnclosedString", query: `T | where a == "x`, err: "not valid KQL"},
+		{name: "UnbalancedBrackets", query: "T | where f(a", err: "not valid KQL"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := conn.query(context.Background(), "db", kql.New("").AddUnsafe(tt.query), &queryOptions{requestProperties: &requestProperties{}})
+			require.Error(t, err)
+			assert.Equal(t, errors.KClientArgs, err.(*errors.Error).Kind)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
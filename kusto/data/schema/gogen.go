@@ -0,0 +1,386 @@
+package schema
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+)
+
+// Nullable selects how GoType() represents columns, as any Kusto value can be null.
+type Nullable int
+
+const (
+	// NullableValue uses the value.* types, such as value.Long, whose Valid field is false for null values.
+	NullableValue Nullable = iota
+	// NullablePointer uses pointers, such as *int64, which are nil for null values.
+	NullablePointer
+	// NotNullable uses plain Go types, such as int64. Null values read as the zero value.
+	NotNullable
+)
+
+const (
+	importJSON    = "encoding/json"
+	importTime    = "time"
+	importUUID    = "github.com/google/uuid"
+	importDecimal = "github.com/shopspring/decimal"
+	importValue   = "github.com/Azure/azure-kusto-go/kusto/data/value"
+)
+
+// goTypes are the plain Go types of the Kusto types, and the package they need. Decimals are strings, as
+// table.Row.ToStruct() only decodes them to strings and value.Decimal.
+var goTypes = map[types.Column][2]string{
+	types.Bool:     {"bool", ""},
+	types.DateTime: {"time.Time", importTime},
+	types.Dynamic:  {"json.RawMessage", importJSON},
+	types.GUID:     {"uuid.UUID", importUUID},
+	types.Int:      {"int32", ""},
+	types.Long:     {"int64", ""},
+	types.Real:     {"float64", ""},
+	types.String:   {"string", ""},
+	types.Timespan: {"time.Duration", importTime},
+	types.Decimal:  {"string", ""},
+}
+
+// valueTypeNames are the names of the value.* types of the Kusto types.
+var valueTypeNames = map[types.Column]string{
+	types.Bool:     "value.Bool",
+	types.DateTime: "value.DateTime",
+	types.Dynamic:  "value.Dynamic",
+	types.GUID:     "value.GUID",
+	types.Int:      "value.Int",
+	types.Long:     "value.Long",
+	types.Real:     "value.Real",
+	types.String:   "value.String",
+	types.Timespan: "value.Timespan",
+	types.Decimal:  "value.Decimal",
+}
+
+// GoType returns the Go type of a struct field for a column of type t, and the import path it needs, if any.
+// json.RawMessage is used for dynamic columns with NullablePointer, as it is already nil for null values.
+func GoType(t types.Column, n Nullable) (goType string, importPath string, err error) {
+	plain, ok := goTypes[t]
+	if !ok {
+		return "", "", fmt.Errorf("%q is not a Kusto type", t)
+	}
+
+	switch n {
+	case NullableValue:
+		return valueTypeNames[t], importValue, nil
+	case NullablePointer:
+		if t == types.Dynamic {
+			return plain[0], plain[1], nil
+		}
+		return "*" + plain[0], plain[1], nil
+	default:
+		return plain[0], plain[1], nil
+	}
+}
+
+// paramTypes are the Go types of function parameters, and the kql.Builder method that adds them to a query.
+var paramTypes = map[types.Column][3]string{
+	types.Bool:     {"bool", "", "AddBool"},
+	types.DateTime: {"time.Time", importTime, "AddDateTime"},
+	types.Dynamic:  {"interface{}", "", "AddDynamic"},
+	types.GUID:     {"uuid.UUID", importUUID, "AddGUID"},
+	types.Int:      {"int32", "", "AddInt"},
+	types.Long:     {"int64", "", "AddLong"},
+	types.Real:     {"float64", "", "AddReal"},
+	types.String:   {"string", "", "AddString"},
+	types.Timespan: {"time.Duration", importTime, "AddTimespan"},
+	types.Decimal:  {"decimal.Decimal", importDecimal, "AddDecimal"},
+}
+
+// Parameter is a parameter of a stored function given to GoGenerator.AddFunction().
+type Parameter struct {
+	Name string
+	// Type is the type of a scalar parameter, or empty for a tabular parameter.
+	Type types.Column
+}
+
+// GoGenerator writes Go source with a struct per table or function result, with `kusto` tags that
+// table.Row.ToStruct() understands. Add tables and functions, then call Generate().
+type GoGenerator struct {
+	// Package is the package name of the generated file.
+	Package string
+	// Nullable is how nullable columns are represented.
+	Nullable Nullable
+	// Helpers adds a Query<Table> function per table, which decodes the rows of a query, and a function per stored
+	// function, which calls it with typed arguments.
+	Helpers bool
+	// Generator is the name of the program in the "Code generated" header. It defaults to "kustogen".
+	Generator string
+
+	entities []genEntity
+}
+
+type genEntity struct {
+	name     string
+	columns  table.Columns
+	function bool
+	params   []Parameter
+}
+
+// AddTable adds a struct for the rows of table name.
+func (g *GoGenerator) AddTable(name string, columns table.Columns) {
+	g.entities = append(g.entities, genEntity{name: name, columns: columns})
+}
+
+// AddFunction adds a struct for the result of stored function name, and with Helpers, a function that calls it. If
+// output is empty, as when the service could not infer it, the helper returns the *kusto.RowIterator.
+func (g *GoGenerator) AddFunction(name string, params []Parameter, output table.Columns) {
+	g.entities = append(g.entities, genEntity{name: name, columns: output, function: true, params: params})
+}
+
+// Generate returns the gofmt-ed source.
+func (g *GoGenerator) Generate() ([]byte, error) {
+	if g.Package == "" {
+		return nil, fmt.Errorf("GoGenerator.Package must be set")
+	}
+	generator := g.Generator
+	if generator == "" {
+		generator = "kustogen"
+	}
+
+	imports := map[string]bool{}
+	names := map[string]bool{"QueryClient": true}
+	var body strings.Builder
+	needQueryRows := false
+
+	for _, e := range g.entities {
+		kind := "table"
+		if e.function {
+			kind = "function"
+		}
+
+		var typeName string
+		if e.function {
+			typeName = uniqueName(names, GoName(e.name)+"Result")
+		} else {
+			typeName = uniqueName(names, GoName(e.name))
+		}
+
+		if len(e.columns) > 0 {
+			if err := g.writeStruct(&body, imports, typeName, kind, e); err != nil {
+				return nil, err
+			}
+		}
+
+		if !g.Helpers {
+			continue
+		}
+		imports["context"] = true
+		imports["github.com/Azure/azure-kusto-go/kusto"] = true
+
+		if !e.function {
+			fmt.Fprintf(&body, "// Query%s runs query in database db, and decodes its rows into %s. The query must return the columns of\n", typeName, typeName)
+			fmt.Fprintf(&body, "// table %s, such as %s.\n", e.name, strconv.Quote(e.name+" | take 10"))
+			fmt.Fprintf(&body, "func Query%s(ctx context.Context, client QueryClient, db string, query kusto.Statement, options ...kusto.QueryOption) ([]%s, error) {\n", typeName, typeName)
+			fmt.Fprintf(&body, "\treturn queryRows[%s](ctx, client, db, query, options...)\n}\n\n", typeName)
+			names["Query"+typeName] = true
+			needQueryRows = true
+			continue
+		}
+
+		ok, err := g.writeFunctionHelper(&body, imports, names, typeName, e)
+		if err != nil {
+			return nil, err
+		}
+		if ok && len(e.columns) > 0 {
+			needQueryRows = true
+		}
+	}
+
+	if g.Helpers {
+		body.WriteString(queryClientSource)
+		if needQueryRows {
+			imports["github.com/Azure/azure-kusto-go/kusto/data/errors"] = true
+			imports["github.com/Azure/azure-kusto-go/kusto/data/table"] = true
+			body.WriteString(queryRowsSource)
+		}
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by %s. DO NOT EDIT.\n\npackage %s\n\n", generator, g.Package)
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for p := range imports {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		src.WriteString("import (\n")
+		for _, p := range paths {
+			fmt.Fprintf(&src, "\t%s\n", strconv.Quote(p))
+		}
+		src.WriteString(")\n\n")
+	}
+	src.WriteString(body.String())
+
+	out, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %w", err)
+	}
+	return out, nil
+}
+
+func (g *GoGenerator) writeStruct(w *strings.Builder, imports map[string]bool, typeName, kind string, e genEntity) error {
+	if kind == "function" {
+		fmt.Fprintf(w, "// %s is a row of the result of function %s.\n", typeName, e.name)
+	} else {
+		fmt.Fprintf(w, "// %s is a row of table %s.\n", typeName, e.name)
+	}
+	fmt.Fprintf(w, "type %s struct {\n", typeName)
+
+	fields := map[string]bool{}
+	for _, c := range e.columns {
+		goType, importPath, err := GoType(c.Type, g.Nullable)
+		if err != nil {
+			return fmt.Errorf("column %s of %s %s: %w", c.Name, kind, e.name, err)
+		}
+		if importPath != "" {
+			imports[importPath] = true
+		}
+		fmt.Fprintf(w, "\t%s %s `kusto:%s`\n", uniqueName(fields, GoName(c.Name)), goType, strconv.Quote(c.Name))
+	}
+	w.WriteString("}\n\n")
+	return nil
+}
+
+// writeFunctionHelper writes a function that calls stored function e. It returns false if the function has tabular
+// parameters, which can't be passed from Go.
+func (g *GoGenerator) writeFunctionHelper(w *strings.Builder, imports, names map[string]bool, typeName string, e genEntity) (bool, error) {
+	funcName := uniqueName(names, GoName(e.name))
+	for _, p := range e.params {
+		if p.Type == "" {
+			fmt.Fprintf(w, "// Function %s takes tabular parameters, so no helper was generated for it.\n\n", e.name)
+			return false, nil
+		}
+	}
+	imports["github.com/Azure/azure-kusto-go/kusto/kql"] = true
+
+	var sig, call strings.Builder
+	// Arguments can't shadow the other arguments, or the packages used in the body.
+	args := map[string]bool{"ctx": true, "client": true, "db": true, "options": true, "query": true, "kql": true, "kusto": true}
+	for _, p := range e.params {
+		pt, ok := paramTypes[p.Type]
+		if !ok {
+			return false, fmt.Errorf("parameter %s of function %s: %q is not a Kusto type", p.Name, e.name, p.Type)
+		}
+		if pt[1] != "" {
+			imports[pt[1]] = true
+		}
+		arg := uniqueName(args, goArgName(p.Name))
+		fmt.Fprintf(&sig, ", %s %s", arg, pt[0])
+		if call.Len() > 0 {
+			call.WriteString(".AddLiteral(\", \")")
+		}
+		fmt.Fprintf(&call, ".%s(%s)", pt[2], arg)
+	}
+
+	fmt.Fprintf(w, "// %s calls function %s in database db.\n", funcName, e.name)
+	if len(e.columns) > 0 {
+		fmt.Fprintf(w, "func %s(ctx context.Context, client QueryClient, db string%s, options ...kusto.QueryOption) ([]%s, error) {\n", funcName, sig.String(), typeName)
+	} else {
+		fmt.Fprintf(w, "func %s(ctx context.Context, client QueryClient, db string%s, options ...kusto.QueryOption) (*kusto.RowIterator, error) {\n", funcName, sig.String())
+	}
+	fmt.Fprintf(w, "\tquery := kql.New(\"\").AddFunction(%s).AddLiteral(\"(\")%s.AddLiteral(\")\")\n", strconv.Quote(e.name), call.String())
+	if len(e.columns) > 0 {
+		fmt.Fprintf(w, "\treturn queryRows[%s](ctx, client, db, query, options...)\n}\n\n", typeName)
+	} else {
+		w.WriteString("\treturn client.Query(ctx, db, query, options...)\n}\n\n")
+	}
+	return true, nil
+}
+
+const queryClientSource = `// QueryClient is the subset of *kusto.Client used by the generated functions.
+type QueryClient interface {
+	Query(ctx context.Context, db string, query kusto.Statement, options ...kusto.QueryOption) (*kusto.RowIterator, error)
+}
+
+`
+
+const queryRowsSource = `// queryRows runs query and decodes every row into a T.
+func queryRows[T any](ctx context.Context, client QueryClient, db string, query kusto.Statement, options ...kusto.QueryOption) ([]T, error) {
+	iter, err := client.Query(ctx, db, query, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	var out []T
+	err = iter.DoOnRowOrError(func(r *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		var rec T
+		if err := r.ToStruct(&rec); err != nil {
+			return err
+		}
+		out = append(out, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+`
+
+// GoName converts a Kusto name, such as "event_count" or "my-table", to an exported Go identifier, such as
+// "EventCount" or "MyTable".
+func GoName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// goArgName converts a Kusto name to an unexported Go identifier.
+func goArgName(name string) string {
+	s := []rune(GoName(name))
+	s[0] = unicode.ToLower(s[0])
+	arg := string(s)
+	if isGoKeyword(arg) {
+		arg += "_"
+	}
+	return arg
+}
+
+func isGoKeyword(s string) bool {
+	switch s {
+	case "break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func", "go",
+		"goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type", "var":
+		return true
+	}
+	return false
+}
+
+// uniqueName returns name, or name with a numeric suffix if it is already in used, and adds it to used.
+func uniqueName(used map[string]bool, name string) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
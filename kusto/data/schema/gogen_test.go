@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		col      types.Column
+		nullable Nullable
+		want     string
+		imp      string
+	}{
+		{types.Long, NullableValue, "value.Long", importValue},
+		{types.Long, NullablePointer, "*int64", ""},
+		{types.Long, NotNullable, "int64", ""},
+		{types.DateTime, NullablePointer, "*time.Time", importTime},
+		{types.Timespan, NotNullable, "time.Duration", importTime},
+		{types.GUID, NotNullable, "uuid.UUID", importUUID},
+		{types.Dynamic, NullablePointer, "json.RawMessage", importJSON},
+		{types.Decimal, NullablePointer, "*string", ""},
+		{types.Int, NotNullable, "int32", ""},
+	}
+
+	for _, test := range tests {
+		got, imp, err := GoType(test.col, test.nullable)
+		require.NoError(t, err)
+		assert.Equal(t, test.want, got, "%s %d", test.col, test.nullable)
+		assert.Equal(t, test.imp, imp, "%s %d", test.col, test.nullable)
+	}
+
+	_, _, err := GoType("integer", NotNullable)
+	assert.Error(t, err)
+}
+
+func TestGoName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "EventCount", GoName("event_count"))
+	assert.Equal(t, "MyTable", GoName("my-table"))
+	assert.Equal(t, "Timestamp", GoName("Timestamp"))
+	assert.Equal(t, "X1stValue", GoName("1st value"))
+	assert.Equal(t, "X", GoName("$"))
+	assert.Equal(t, "type_", goArgName("type"))
+}
+
+func TestGoGenerator(t *testing.T) {
+	t.Parallel()
+
+	gen := &GoGenerator{Package: "models", Helpers: true}
+	gen.AddTable("my-logs", table.Columns{
+		{Name: "Timestamp", Type: types.DateTime},
+		{Name: "level", Type: types.Int},
+		{Name: "Level", Type: types.Long},
+	})
+	gen.AddFunction("GetErrors", []Parameter{{Name: "since", Type: types.Timespan}, {Name: "type", Type: types.String}},
+		table.Columns{{Name: "Message", Type: types.String}})
+	gen.AddFunction("Raw", nil, nil)
+	gen.AddFunction("Filter", []Parameter{{Name: "T"}}, table.Columns{{Name: "a", Type: types.Long}})
+
+	src, err := gen.Generate()
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "kusto.go", src, 0)
+	require.NoError(t, err)
+
+	s := string(src)
+	assert.Contains(t, s, "// Code generated by kustogen. DO NOT EDIT.\n\npackage models\n")
+	assert.Contains(t, s, "type MyLogs struct {\n\tTimestamp value.DateTime `kusto:\"Timestamp\"`\n\tLevel     value.Int      `kusto:\"level\"`\n\tLevel2    value.Long     `kusto:\"Level\"`\n}")
+	assert.Contains(t, s, "func QueryMyLogs(ctx context.Context, client QueryClient, db string, query kusto.Statement, options ...kusto.QueryOption) ([]MyLogs, error) {")
+	assert.Contains(t, s, "type GetErrorsResult struct {\n\tMessage value.String `kusto:\"Message\"`\n}")
+	assert.Contains(t, s, "func GetErrors(ctx context.Context, client QueryClient, db string, since time.Duration, type_ string, options ...kusto.QueryOption) ([]GetErrorsResult, error) {")
+	assert.Contains(t, s, `query := kql.New("").AddFunction("GetErrors").AddLiteral("(").AddTimespan(since).AddLiteral(", ").AddString(type_).AddLiteral(")")`)
+	assert.Contains(t, s, "func Raw(ctx context.Context, client QueryClient, db string, options ...kusto.QueryOption) (*kusto.RowIterator, error) {")
+	assert.Contains(t, s, "type FilterResult struct {")
+	assert.Contains(t, s, "// Function Filter takes tabular parameters, so no helper was generated for it.")
+	assert.Contains(t, s, "func queryRows[T any]")
+
+	gen = &GoGenerator{Package: "models", Nullable: NullablePointer}
+	gen.AddTable("T", table.Columns{{Name: "a", Type: types.Long}, {Name: "d", Type: types.Dynamic}})
+	src, err = gen.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, "// Code generated by kustogen. DO NOT EDIT.\n\npackage models\n\nimport (\n\t\"encoding/json\"\n)\n\n"+
+		"// T is a row of table T.\ntype T struct {\n\tA *int64          `kusto:\"a\"`\n\tD json.RawMessage `kusto:\"d\"`\n}\n", string(src))
+
+	_, err = (&GoGenerator{}).Generate()
+	assert.Error(t, err)
+}
@@ -0,0 +1,286 @@
+/*
+Package schema derives Kusto table schemas and ingestion mappings from Go structs, so that the Go type stays the source
+of truth for a table.
+
+Columns are named by the same `kusto` field tags that table.Row.ToStruct() understands, followed by optional
+comma-separated options:
+
+	type Event struct {
+		ID        uuid.UUID     `kusto:"EventId"`
+		Timestamp time.Time     // Column "Timestamp", a datetime.
+		Elapsed   time.Duration `kusto:",ordinal=5"`
+		Payload   string        `kusto:"Payload,type=dynamic"`
+		Internal  string        `kusto:"-"` // Not a column.
+	}
+
+	t, err := schema.FromStruct[Event]()
+
+Options are:
+
+  - type=<kusto type>: the column type, instead of the one derived from the Go type.
+  - ordinal=<n>: the position of the column in CSV data, instead of the position of the field.
+
+Go types map to Kusto types as follows: bool to bool, int8, int16, int32, uint8 and uint16 to int, other integers to
+long, floats to real, string to string, time.Time to datetime, time.Duration to timespan, uuid.UUID to guid,
+decimal.Decimal to decimal, value.* types to their Kusto type, and maps, slices, arrays, structs and interfaces to
+dynamic. Pointers map to the type they point to.
+*/
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Table is the schema of a table derived from a Go struct.
+type Table struct {
+	// Columns are the table columns, in field order.
+	Columns table.Columns
+
+	// sources are where each column is read from in ingested data, by column index.
+	sources []source
+}
+
+// source is where a column is read from in CSV and JSON data.
+type source struct {
+	ordinal int
+	path    string
+}
+
+// FromStruct returns the schema of a table whose rows are T, a struct or a pointer to a struct.
+func FromStruct[T any]() (*Table, error) {
+	var zero T
+	return FromType(reflect.TypeOf(zero))
+}
+
+// FromType is FromStruct() for a reflect.Type, for when the type is only known at runtime.
+func FromType(t reflect.Type) (*Table, error) {
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema.FromType: %v is not a struct", t)
+	}
+
+	tbl := &Table{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, err := parseTag(field)
+		if err != nil {
+			return nil, err
+		}
+		if tag.skip {
+			continue
+		}
+
+		colType := tag.colType
+		if colType == "" {
+			if colType, err = ColumnType(field.Type); err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+
+		ordinal := len(tbl.Columns)
+		if tag.ordinal != nil {
+			ordinal = *tag.ordinal
+		}
+
+		tbl.Columns = append(tbl.Columns, table.Column{Name: tag.name, Type: colType})
+		tbl.sources = append(tbl.sources, source{ordinal: ordinal, path: jsonPath(field)})
+	}
+
+	if err := tbl.Columns.Validate(); err != nil {
+		return nil, fmt.Errorf("schema.FromType(%v): %w", t, err)
+	}
+	return tbl, nil
+}
+
+// fieldTag is a parsed `kusto` tag.
+type fieldTag struct {
+	name    string
+	skip    bool
+	colType types.Column
+	ordinal *int
+}
+
+func parseTag(field reflect.StructField) (fieldTag, error) {
+	tag := field.Tag.Get("kusto")
+	if strings.TrimSpace(tag) == "-" {
+		return fieldTag{skip: true}, nil
+	}
+
+	parts := strings.Split(tag, ",")
+	ft := fieldTag{name: strings.TrimSpace(parts[0])}
+	if ft.name == "" {
+		ft.name = field.Name
+	}
+
+	for _, opt := range parts[1:] {
+		key, val, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "type":
+			ft.colType = types.Column(val)
+			if !ft.colType.Valid() {
+				return fieldTag{}, fmt.Errorf("field %s: %q is not a Kusto type", field.Name, val)
+			}
+		case "ordinal":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return fieldTag{}, fmt.Errorf("field %s: ordinal %q is not a non-negative integer", field.Name, val)
+			}
+			ft.ordinal = &n
+		case "":
+		default:
+			return fieldTag{}, fmt.Errorf("field %s: unknown kusto tag option %q", field.Name, opt)
+		}
+	}
+	return ft, nil
+}
+
+// jsonPath returns the path of field in the JSON encoding of its struct, honoring its `json` tag.
+func jsonPath(field reflect.StructField) string {
+	name := field.Name
+	if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		name = tag
+	}
+	if kql.RequiresQuoting(name) {
+		return "$['" + strings.ReplaceAll(name, "'", `\'`) + "']"
+	}
+	return "$." + name
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	uuidType     = reflect.TypeOf(uuid.UUID{})
+	decimalType  = reflect.TypeOf(decimal.Decimal{})
+)
+
+// valueTypes are the Kusto types of the value.* wrappers.
+var valueTypes = map[reflect.Type]types.Column{
+	reflect.TypeOf(value.Bool{}):     types.Bool,
+	reflect.TypeOf(value.DateTime{}): types.DateTime,
+	reflect.TypeOf(value.Dynamic{}):  types.Dynamic,
+	reflect.TypeOf(value.GUID{}):     types.GUID,
+	reflect.TypeOf(value.Int{}):      types.Int,
+	reflect.TypeOf(value.Long{}):     types.Long,
+	reflect.TypeOf(value.Real{}):     types.Real,
+	reflect.TypeOf(value.String{}):   types.String,
+	reflect.TypeOf(value.Timespan{}): types.Timespan,
+	reflect.TypeOf(value.Decimal{}):  types.Decimal,
+}
+
+// ColumnType returns the Kusto type of a column holding Go type t.
+func ColumnType(t reflect.Type) (types.Column, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if c, ok := valueTypes[t]; ok {
+		return c, nil
+	}
+	switch t {
+	case timeType:
+		return types.DateTime, nil
+	case durationType:
+		return types.Timespan, nil
+	case uuidType:
+		return types.GUID, nil
+	case decimalType:
+		return types.Decimal, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return types.Bool, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return types.Int, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return types.Long, nil
+	case reflect.Float32, reflect.Float64:
+		return types.Real, nil
+	case reflect.String:
+		return types.String, nil
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Interface:
+		return types.Dynamic, nil
+	}
+	return "", fmt.Errorf("type %v has no Kusto equivalent", t)
+}
+
+// CreateMerge returns the ".create-merge table" command that creates table tableName with the columns of t, or adds
+// the missing ones if it exists.
+func (t *Table) CreateMerge(tableName string) *kql.Builder {
+	stmt := kql.New(".create-merge table ").AddTable(tableName).AddLiteral(" (")
+	for i, c := range t.Columns {
+		if i > 0 {
+			stmt.AddLiteral(", ")
+		}
+		stmt.AddColumn(c.Name).AddLiteral(":").AddKeyword(string(c.Type))
+	}
+	return stmt.AddLiteral(")")
+}
+
+// columnMapping is an element of a CSV or JSON ingestion mapping.
+type columnMapping struct {
+	Column     string            `json:"Column"`
+	DataType   types.Column      `json:"DataType"`
+	Properties map[string]string `json:"Properties"`
+}
+
+// CSVMapping returns the JSON of a CSV ingestion mapping, where each column is read from its ordinal.
+func (t *Table) CSVMapping() string {
+	return t.mapping(func(s source) map[string]string {
+		return map[string]string{"Ordinal": strconv.Itoa(s.ordinal)}
+	})
+}
+
+// JSONMapping returns the JSON of a JSON ingestion mapping, where each column is read from the path of its field in
+// the encoding/json encoding of the struct.
+func (t *Table) JSONMapping() string {
+	return t.mapping(func(s source) map[string]string {
+		return map[string]string{"Path": s.path}
+	})
+}
+
+func (t *Table) mapping(properties func(s source) map[string]string) string {
+	m := make([]columnMapping, 0, len(t.Columns))
+	for i, c := range t.Columns {
+		m = append(m, columnMapping{Column: c.Name, DataType: c.Type, Properties: properties(t.sources[i])})
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		// Only strings are marshaled, this cannot fail.
+		panic(err)
+	}
+	return string(b)
+}
+
+// CreateCSVMapping returns the ".create-or-alter table ingestion csv mapping" command for CSVMapping().
+func (t *Table) CreateCSVMapping(tableName, mappingName string) *kql.Builder {
+	return createMapping(tableName, "csv", mappingName, t.CSVMapping())
+}
+
+// CreateJSONMapping returns the ".create-or-alter table ingestion json mapping" command for JSONMapping().
+func (t *Table) CreateJSONMapping(tableName, mappingName string) *kql.Builder {
+	return createMapping(tableName, "json", mappingName, t.JSONMapping())
+}
+
+func createMapping(tableName, kind, mappingName, mapping string) *kql.Builder {
+	return kql.New(".create-or-alter table ").AddTable(tableName).AddLiteral(" ingestion ").AddKeyword(kind).
+		AddLiteral(" mapping ").AddString(mappingName).AddLiteral(" ").AddString(mapping)
+}
@@ -0,0 +1,157 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	ID        uuid.UUID `kusto:"EventId" json:"id"`
+	Timestamp time.Time
+	Elapsed   time.Duration `kusto:",ordinal=5"`
+	Count     int           `json:"count,omitempty"`
+	Small     int16         `json:"-"`
+	Ratio     *float64      `json:"my ratio"`
+	Price     decimal.Decimal
+	Payload   string `kusto:"Payload,type=dynamic"`
+	Tags      []string
+	Props     map[string]interface{}
+	Nested    struct{ A int }
+	Any       interface{}
+	Level     value.Int
+	Name      value.String
+	Internal  string `kusto:"-"`
+	private   string
+}
+
+func TestFromStruct(t *testing.T) {
+	t.Parallel()
+
+	tbl, err := FromStruct[event]()
+	require.NoError(t, err)
+	assert.Equal(t, table.Columns{
+		{Name: "EventId", Type: types.GUID},
+		{Name: "Timestamp", Type: types.DateTime},
+		{Name: "Elapsed", Type: types.Timespan},
+		{Name: "Count", Type: types.Long},
+		{Name: "Small", Type: types.Int},
+		{Name: "Ratio", Type: types.Real},
+		{Name: "Price", Type: types.Decimal},
+		{Name: "Payload", Type: types.Dynamic},
+		{Name: "Tags", Type: types.Dynamic},
+		{Name: "Props", Type: types.Dynamic},
+		{Name: "Nested", Type: types.Dynamic},
+		{Name: "Any", Type: types.Dynamic},
+		{Name: "Level", Type: types.Int},
+		{Name: "Name", Type: types.String},
+	}, tbl.Columns)
+
+	ptr, err := FromStruct[*event]()
+	require.NoError(t, err)
+	assert.Equal(t, tbl.Columns, ptr.Columns)
+
+	assert.Equal(t,
+		".create-merge table ["+`"my-events"`+"] (EventId:guid, Timestamp:datetime, Elapsed:timespan, Count:long, Small:int, "+
+			"Ratio:real, Price:decimal, Payload:dynamic, Tags:dynamic, Props:dynamic, Nested:dynamic, Any:dynamic, Level:int, Name:string)",
+		tbl.CreateMerge("my-events").String())
+}
+
+func TestFromStructErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		typ  reflect.Type
+	}{
+		{desc: "NotStruct", typ: reflect.TypeOf(1)},
+		{desc: "Nil", typ: nil},
+		{desc: "BadType", typ: reflect.TypeOf(struct {
+			A int `kusto:"A,type=integer"`
+		}{})},
+		{desc: "BadOrdinal", typ: reflect.TypeOf(struct {
+			A int `kusto:"A,ordinal=-1"`
+		}{})},
+		{desc: "UnknownOption", typ: reflect.TypeOf(struct {
+			A int `kusto:"A,nullable"`
+		}{})},
+		{desc: "Unsupported", typ: reflect.TypeOf(struct{ A chan int }{})},
+		{desc: "Duplicate", typ: reflect.TypeOf(struct {
+			A int
+			B int `kusto:"A"`
+		}{})},
+		{desc: "NoColumns", typ: reflect.TypeOf(struct{ a int }{})},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := FromType(test.typ)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMappings(t *testing.T) {
+	t.Parallel()
+
+	type row struct {
+		ID      int64  `kusto:"Id" json:"id"`
+		Message string `kusto:",ordinal=3" json:"my-message"`
+		Level   int32  `kusto:"Level,type=long"`
+	}
+
+	tbl, err := FromStruct[row]()
+	require.NoError(t, err)
+
+	var csv []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(tbl.CSVMapping()), &csv))
+	assert.Equal(t, []map[string]interface{}{
+		{"Column": "Id", "DataType": "long", "Properties": map[string]interface{}{"Ordinal": "0"}},
+		{"Column": "Message", "DataType": "string", "Properties": map[string]interface{}{"Ordinal": "3"}},
+		{"Column": "Level", "DataType": "long", "Properties": map[string]interface{}{"Ordinal": "2"}},
+	}, csv)
+
+	var js []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(tbl.JSONMapping()), &js))
+	assert.Equal(t, []map[string]interface{}{
+		{"Column": "Id", "DataType": "long", "Properties": map[string]interface{}{"Path": "$.id"}},
+		{"Column": "Message", "DataType": "string", "Properties": map[string]interface{}{"Path": "$['my-message']"}},
+		{"Column": "Level", "DataType": "long", "Properties": map[string]interface{}{"Path": "$.Level"}},
+	}, js)
+
+	assert.Equal(t, `.create-or-alter table Logs ingestion csv mapping "logs_csv" `+kql.QuoteString(tbl.CSVMapping(), false),
+		tbl.CreateCSVMapping("Logs", "logs_csv").String())
+	assert.Equal(t, `.create-or-alter table Logs ingestion json mapping "logs_json" `+kql.QuoteString(tbl.JSONMapping(), false),
+		tbl.CreateJSONMapping("Logs", "logs_json").String())
+}
+
+// TestToStructWithOptions checks that tag options don't change how table.Row.ToStruct() reads the tags.
+func TestToStructWithOptions(t *testing.T) {
+	t.Parallel()
+
+	type row struct {
+		ID      int64  `kusto:"Id,ordinal=1"`
+		Payload string `kusto:"Payload,type=dynamic"`
+		Message string `kusto:",ordinal=0"`
+	}
+
+	r := &table.Row{
+		ColumnTypes: table.Columns{{Name: "Id", Type: types.Long}, {Name: "Payload", Type: types.String}, {Name: "Message", Type: types.String}},
+		Values:      value.Values{value.Long{Value: 1, Valid: true}, value.String{Value: "{}", Valid: true}, value.String{Value: "hi", Valid: true}},
+	}
+	var got row
+	require.NoError(t, r.ToStruct(&got))
+	assert.Equal(t, row{ID: 1, Payload: "{}", Message: "hi"}, got)
+}
@@ -36,7 +36,8 @@ func newFields(cols Columns, ptr reflect.Type) fields {
 	nFields := fields{colNameToFieldName: map[string]string{}}
 	for i := 0; i < ptr.Elem().NumField(); i++ {
 		field := ptr.Elem().Field(i)
-		if tag := field.Tag.Get("kusto"); strings.TrimSpace(tag) != "" {
+		// Options after the name, such as `kusto:"Name,type=long"`, are for schema.FromStruct().
+		if tag, _, _ := strings.Cut(field.Tag.Get("kusto"), ","); strings.TrimSpace(tag) != "" {
 			nFields.colNameToFieldName[tag] = field.Name
 		} else {
 			nFields.colNameToFieldName[field.Name] = field.Name
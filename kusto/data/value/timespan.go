@@ -69,21 +69,12 @@ func (t Timespan) Marshal() string {
 	val = val - (seconds * time.Second)
 	sb.WriteString(fmt.Sprintf("%02d:%02d:%02d", int(hours), int(minutes), int(seconds)))
 
-	// Add our sub-second string representation that is proceeded with a ".".
-	milliseconds := val / time.Millisecond
-	val = val - (milliseconds * time.Millisecond)
-	ticks := val / tick
-	if milliseconds > 0 || ticks > 0 {
-		sb.WriteString(fmt.Sprintf(".%03d%d", milliseconds, ticks))
+	// Add our sub-second string representation that is proceeded with a ".", without trailing zeros.
+	if ticks := val / tick; ticks > 0 {
+		sb.WriteString(strings.TrimRight(fmt.Sprintf(".%07d", int64(ticks)), "0"))
 	}
 
-	// Remove any trailing 0's.
-	str := strings.TrimRight(sb.String(), "0")
-	if strings.HasSuffix(str, ":") {
-		str = str + "00"
-	}
-
-	return str
+	return sb.String()
 }
 
 // Unmarshal unmarshals i into Timespan. i must be a string representing a Values timespan or nil.
@@ -520,6 +520,31 @@ func TestTimespan(t *testing.T) {
 	}
 }
 
+func TestTimespanMarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value time.Duration
+		want  string
+	}{
+		{value: 0, want: "00:00:00"},
+		{value: 30 * time.Second, want: "00:00:30"},
+		{value: 10 * time.Minute, want: "00:10:00"},
+		{value: 2*time.Hour + 4*time.Minute + 3*time.Second + 12300*time.Microsecond, want: "02:04:03.0123"},
+		{value: time.Millisecond + 100*time.Nanosecond, want: "00:00:00.0010001"},
+		{value: 30 * day, want: "30.00:00:00"},
+		{value: -(day + 20*time.Second), want: "-1.00:00:20"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.want, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.want, Timespan{Value: test.value, Valid: true}.Marshal())
+		})
+	}
+}
+
 func removeLeadingZeros(s string) string {
 	if len(s) == 0 {
 		return s
@@ -0,0 +1,88 @@
+package kusto
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+)
+
+// streamIngestor is implemented by the Conn of a Client, which streams data to its engine endpoint.
+type streamIngestor interface {
+	StreamIngest(ctx context.Context, db, table string, payload io.Reader, format DataFormatForStreaming, mappingName string, clientRequestId string, isBlobUri bool) error
+}
+
+// Database is a handle on a database of a Client, with QueryOptions that apply to all of its calls. It holds no
+// resources of its own, so it is cheap to create, and it is safe for concurrent use.
+type Database struct {
+	client  *Client
+	name    string
+	options []QueryOption
+}
+
+// Database returns a handle on database name. The options apply to the Query(), QueryToJson() and Mgmt() calls of the
+// handle, after the client's WithDefaultQueryOptions() and before the options of each call.
+func (c *Client) Database(name string, options ...QueryOption) *Database {
+	return &Database{client: c, name: name, options: options}
+}
+
+// Name returns the name of the database.
+func (d *Database) Name() string {
+	return d.name
+}
+
+// Client returns the Client of the database, such as to create an ingest.Ingestion for queued ingestion with
+// ingest.New(d.Client(), d.Name(), table).
+func (d *Database) Client() *Client {
+	return d.client
+}
+
+// Query runs query in the database. See Client.Query().
+func (d *Database) Query(ctx context.Context, query Statement, options ...QueryOption) (*RowIterator, error) {
+	return d.client.Query(ctx, d.name, query, d.withOptions(options)...)
+}
+
+// QueryToJson runs query in the database and returns the raw JSON response. See Client.QueryToJson().
+func (d *Database) QueryToJson(ctx context.Context, query Statement, options ...QueryOption) (string, error) {
+	return d.client.QueryToJson(ctx, d.name, query, d.withOptions(options)...)
+}
+
+// Mgmt runs a management command in the database. See Client.Mgmt().
+func (d *Database) Mgmt(ctx context.Context, query Statement, options ...QueryOption) (*RowIterator, error) {
+	return d.client.Mgmt(ctx, d.name, query, d.withOptions(options)...)
+}
+
+// Ingest streams payload into table with streaming ingestion, which must be enabled on the table or the database.
+// payload must not be compressed, as it is compressed with gzip. format is a data format such as ingest.CSV, and
+// mappingName is the name of an ingestion mapping, or "". Use the ingest package for queued ingestion, for files and
+// for blobs.
+func (d *Database) Ingest(ctx context.Context, table string, payload io.Reader, format DataFormatForStreaming, mappingName string) error {
+	conn, ok := d.client.conn.(streamIngestor)
+	if !ok {
+		return errors.ES(errors.OpIngestStream, errors.KClientArgs, "the client does not support streaming ingestion").SetNoRetry()
+	}
+
+	r, w := io.Pipe()
+	defer r.Close()
+	go func() {
+		zw := gzip.NewWriter(w)
+		_, err := io.Copy(zw, payload)
+		if err == nil {
+			err = zw.Close()
+		}
+		w.CloseWithError(err)
+	}()
+
+	return conn.StreamIngest(ctx, d.name, table, r, format, mappingName, "", false)
+}
+
+// withOptions returns the options of the database, followed by options.
+func (d *Database) withOptions(options []QueryOption) []QueryOption {
+	if len(d.options) == 0 {
+		return options
+	}
+	all := make([]QueryOption, 0, len(d.options)+len(options))
+	all = append(all, d.options...)
+	return append(all, options...)
+}
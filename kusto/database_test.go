@@ -0,0 +1,131 @@
+package kusto
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/internal/frames"
+	v2 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v2"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dbCall is a call received by dbConn.
+type dbCall struct {
+	db    string
+	opts  *queryOptions
+	table string
+	data  string
+}
+
+// dbConn is a queryer that records the database and options of its calls, and the data streamed to it.
+type dbConn struct {
+	mockConn
+	calls chan dbCall
+}
+
+func (d dbConn) query(_ context.Context, db string, _ Statement, opts *queryOptions) (execResp, error) {
+	d.calls <- dbCall{db: db, opts: opts}
+
+	ch := make(chan frames.Frame, 3)
+	ch <- v2.DataSetHeader{}
+	ch <- v2.DataTable{
+		TableKind: frames.PrimaryResult,
+		TableName: frames.PrimaryResult,
+		Columns:   table.Columns{{Name: "x", Type: types.Long}},
+		KustoRows: []value.Values{{value.Long{Value: 1, Valid: true}}},
+	}
+	ch <- v2.DataSetCompletion{}
+	close(ch)
+	return execResp{frameCh: ch}, nil
+}
+
+func (d dbConn) mgmt(ctx context.Context, db string, query Statement, opts *queryOptions) (execResp, error) {
+	d.calls <- dbCall{db: db, opts: opts}
+	return d.mockConn.mgmt(ctx, db, query, opts)
+}
+
+func (d dbConn) StreamIngest(_ context.Context, db, table string, payload io.Reader, _ DataFormatForStreaming, _ string, _ string, _ bool) error {
+	zr, err := gzip.NewReader(payload)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+	d.calls <- dbCall{db: db, table: table, data: string(data)}
+	return nil
+}
+
+type streamFormat string
+
+func (f streamFormat) CamelCase() string                      { return string(f) }
+func (f streamFormat) KnownOrDefault() DataFormatForStreaming { return f }
+
+func TestDefaultQueryOptions(t *testing.T) {
+	t.Parallel()
+
+	conn := dbConn{calls: make(chan dbCall, 1)}
+	client := NewMockClient()
+	WithDefaultQueryOptions(Application("app"), RequestDescription("default"))(client)
+	client.conn = conn
+
+	iter, err := client.Query(context.Background(), "db", kql.New("T"), RequestDescription("call"))
+	require.NoError(t, err)
+	iter.Stop()
+
+	call := <-conn.calls
+	assert.Equal(t, "app", call.opts.requestProperties.Application)
+	assert.Equal(t, "call", call.opts.requestProperties.Options[RequestDescriptionValue])
+	assert.Equal(t, true, call.opts.requestProperties.Options[RequestProgressiveEnabledValue])
+
+	client = NewMockClient()
+	WithDefaultQueryOptions(FrameIdleTimeout(-1))(client)
+	_, err = client.Query(context.Background(), "db", kql.New("T"))
+	assert.Error(t, err)
+}
+
+func TestDatabase(t *testing.T) {
+	t.Parallel()
+
+	conn := dbConn{calls: make(chan dbCall, 1)}
+	client := NewMockClient()
+	WithDefaultQueryOptions(Application("app"))(client)
+	client.conn = conn
+
+	db := client.Database("db", RequestReadonly(), RequestDescription("db"))
+	assert.Equal(t, "db", db.Name())
+	assert.Same(t, client, db.Client())
+
+	iter, err := db.Query(context.Background(), kql.New("T"), RequestDescription("call"))
+	require.NoError(t, err)
+	iter.Stop()
+
+	call := <-conn.calls
+	assert.Equal(t, "db", call.db)
+	assert.Equal(t, "app", call.opts.requestProperties.Application)
+	assert.Equal(t, true, call.opts.requestProperties.Options[RequestReadonlyValue])
+	assert.Equal(t, "call", call.opts.requestProperties.Options[RequestDescriptionValue])
+
+	iter, err = db.Mgmt(context.Background(), kql.New(".show tables"))
+	require.NoError(t, err)
+	iter.Stop()
+
+	call = <-conn.calls
+	assert.Equal(t, "db", call.db)
+	assert.Equal(t, "db", call.opts.requestProperties.Options[RequestDescriptionValue])
+
+	err = db.Ingest(context.Background(), "T", strings.NewReader("1,a\n2,b\n"), streamFormat("csv"), "")
+	require.NoError(t, err)
+
+	call = <-conn.calls
+	assert.Equal(t, dbCall{db: "db", table: "T", data: "1,a\n2,b\n"}, call)
+}
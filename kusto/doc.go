@@ -106,10 +106,10 @@ The reason for this is to discourage the use of string concatenation to build qu
 #### Queries with parameters
 
 * Can re-use the same query with different parameters.
-* Only work for queries, management commands are not supported.
+* Work for queries, and for the query part of management commands, such as `.set-or-append T <| ...`, where Mgmt() renders them as typed literals.
 
 It is recommended to use parameters for queries that contain user input.
-Management commands can not use parameters, and therefore should be built using the builder (see next section).
+Management commands without a query part can not use parameters, and therefore should be built using the builder (see next section).
 
 Parameters can be implicitly referenced in a query:
 
@@ -125,6 +125,21 @@ To Pass the parameters values to the query, create `kql.Parameters`:
 params :=  kql.NewParameters().AddDateTime("startTime", dt).AddInt("nodeIdValue", 1)
 ```
 
+The types can also be inferred from Go values, or from the fields of a struct with `kusto` tags:
+
+```go
+params, err := kql.NewParameters().Add("startTime", dt)
+```
+
+With a struct, the parameters are the fields with a `kusto` tag:
+
+	type request struct {
+		StartTime   time.Time `kusto:"startTime"`
+		NodeIdValue int       `kusto:"nodeIdValue"`
+	}
+
+	params, err = kql.ParametersFromStruct(request{StartTime: dt, NodeIdValue: 1})
+
 And then pass it to the `Query` method, as an option:
 ```go
 results, err := client.Query(ctx, database, query, QueryParameters(params))
@@ -252,6 +267,19 @@ err = iter.DoOnRowOrError(
 
 ```
 
+#### Default options and database handles
+
+Options that every call needs can be set once on the client with `WithDefaultQueryOptions`. The options of a call are
+applied after them. A `Database` handle carries the database name and more options, and has `Query`, `Mgmt` and
+`Ingest` (streaming ingestion) methods:
+
+```go
+client, err := kusto.New(kustoConnectionString, kusto.WithDefaultQueryOptions(kusto.Application("my-app"), kusto.RequestReadonly()))
+
+db := client.Database("MyDatabase", kusto.QueryConsistency("weakconsistency"), kusto.FrameIdleTimeout(time.Minute))
+iter, err := db.Query(ctx, kql.New("systemNodes | take 10"))
+```
+
 ### Ingestion
 
 The `ingest` package provides access to Kusto's ingestion service for importing data into Kusto. This requires
@@ -0,0 +1,94 @@
+package kusto
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+)
+
+// frameTimer enforces the FirstFrameTimeout() and FrameIdleTimeout() options of a call. It is only armed while the
+// call waits for a frame, and when it expires it cancels the context of the call, which aborts a stalled HTTP read
+// and stops the decoder. A nil *frameTimer enforces no timeouts.
+type frameTimer struct {
+	op         errors.Op
+	firstFrame time.Duration
+	idle       time.Duration
+	cancel     context.CancelFunc
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	received bool
+	expired  *errors.Error
+}
+
+// newFrameTimer returns a frameTimer for the timeouts in opts that calls cancel when it expires, or nil if opts sets
+// no timeouts.
+func newFrameTimer(op errors.Op, opts *queryOptions, cancel context.CancelFunc) *frameTimer {
+	if opts.firstFrameTimeout == 0 && opts.frameIdleTimeout == 0 {
+		return nil
+	}
+	return &frameTimer{op: op, firstFrame: opts.firstFrameTimeout, idle: opts.frameIdleTimeout, cancel: cancel}
+}
+
+// wait arms the timer, with the first frame timeout until a frame was received and the idle timeout after.
+// It does nothing if the timer is already armed.
+func (f *frameTimer) wait() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.timer != nil || f.expired != nil {
+		return
+	}
+
+	d, first := f.idle, !f.received
+	if first {
+		d = f.firstFrame
+	}
+	if d == 0 {
+		return
+	}
+	f.timer = time.AfterFunc(d, func() {
+		f.mu.Lock()
+		if first {
+			f.expired = errors.ES(f.op, errors.KTimeout, "the first frame was not received within %s of sending the request (FirstFrameTimeout)", d)
+		} else {
+			f.expired = errors.ES(f.op, errors.KTimeout, "no frame was received for %s (FrameIdleTimeout)", d)
+		}
+		f.mu.Unlock()
+		f.cancel()
+	})
+}
+
+// frame disarms the timer when a frame was received.
+func (f *frameTimer) frame() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	f.received = true
+}
+
+// err returns the KTimeout error if the timer expired, or err.
+func (f *frameTimer) err(err error) error {
+	if f == nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.expired != nil {
+		return f.expired
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package kusto
+
+import (
+	"context"
+	goErr "errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/internal/frames"
+	v2 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v2"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stallConn is a queryer that sends frames, then stalls like a connection that a proxy stopped forwarding, until the
+// context of the call is cancelled.
+type stallConn struct {
+	mockConn
+	frames []frames.Frame
+}
+
+func (s stallConn) stall(ctx context.Context) (execResp, error) {
+	ch := make(chan frames.Frame)
+	go func() {
+		defer close(ch)
+		for _, fr := range s.frames {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- fr:
+			}
+		}
+		<-ctx.Done()
+	}()
+	return execResp{frameCh: ch}, nil
+}
+
+func (s stallConn) query(ctx context.Context, _ string, _ Statement, _ *queryOptions) (execResp, error) {
+	return s.stall(ctx)
+}
+
+func (s stallConn) mgmt(ctx context.Context, _ string, _ Statement, _ *queryOptions) (execResp, error) {
+	return s.stall(ctx)
+}
+
+func requireTimeout(t *testing.T, err error) {
+	t.Helper()
+	require.Error(t, err)
+	assert.True(t, errors.Retry(err), "a timeout should be retryable")
+	var e *errors.Error
+	require.True(t, goErr.As(err, &e))
+	assert.Equal(t, errors.KTimeout, e.Kind)
+}
+
+func TestFirstFrameTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := NewMockClient()
+	client.conn = stallConn{}
+
+	start := time.Now()
+	_, err := client.Query(context.Background(), "db", kql.New("T"), FirstFrameTimeout(10*time.Millisecond))
+	requireTimeout(t, err)
+	assert.Less(t, time.Since(start), time.Minute)
+
+	iter, err := client.Mgmt(context.Background(), "db", kql.New(".show tables"), FirstFrameTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+	defer iter.Stop()
+	_, _, err = iter.NextRowOrError()
+	requireTimeout(t, err)
+}
+
+func TestFrameIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := NewMockClient()
+	client.conn = stallConn{frames: []frames.Frame{
+		v2.DataSetHeader{IsProgressive: true},
+		v2.TableHeader{TableKind: frames.PrimaryResult, Columns: table.Columns{{Name: "x", Type: types.Long}}},
+		v2.TableFragment{KustoRows: []value.Values{{value.Long{Value: 1, Valid: true}}}},
+	}}
+
+	iter, err := client.Query(context.Background(), "db", kql.New("T"), FirstFrameTimeout(time.Minute), FrameIdleTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+	defer iter.Stop()
+
+	row, inlineErr, err := iter.NextRowOrError()
+	require.NoError(t, err)
+	require.Nil(t, inlineErr)
+	assert.Equal(t, value.Long{Value: 1, Valid: true}, row.Values[0])
+
+	_, _, err = iter.NextRowOrError()
+	requireTimeout(t, err)
+}
+
+func TestFrameTimer(t *testing.T) {
+	t.Parallel()
+
+	cancelled := make(chan struct{})
+	timer := newFrameTimer(errors.OpQuery, &queryOptions{firstFrameTimeout: time.Minute, frameIdleTimeout: 10 * time.Millisecond},
+		func() { close(cancelled) })
+
+	// The timer only runs while waiting for a frame.
+	timer.wait()
+	timer.frame()
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, timer.err(nil))
+
+	timer.wait()
+	<-cancelled
+	requireTimeout(t, timer.err(context.Canceled))
+
+	assert.Nil(t, newFrameTimer(errors.OpQuery, &queryOptions{}, func() {}))
+	var none *frameTimer
+	none.wait()
+	none.frame()
+	assert.Equal(t, context.Canceled, none.err(context.Canceled))
+
+	_, err := setQueryOptions(context.Background(), errors.OpQuery, kql.New("T"), queryCall, nil, FrameIdleTimeout(-time.Second))
+	assert.Error(t, err)
+}
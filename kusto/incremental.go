@@ -0,0 +1,394 @@
+package kusto
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/Azure/azure-kusto-go/kusto/kql/lexer"
+)
+
+// CheckpointStore persists the cursors of IncrementalReaders, by key. Implementations must be safe for concurrent use.
+type CheckpointStore interface {
+	// Load returns the cursor saved for key, or "" if there is none.
+	Load(ctx context.Context, key string) (string, error)
+	// Save saves the cursor for key.
+	Save(ctx context.Context, key string, cursor string) error
+}
+
+// MemoryCheckpointStore is a CheckpointStore that keeps the cursors in memory, for tests and for readers that do not
+// need to resume after a restart.
+type MemoryCheckpointStore struct {
+	mu      sync.Mutex
+	cursors map[string]string
+}
+
+// NewMemoryCheckpointStore is the constructor for MemoryCheckpointStore.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{cursors: map[string]string{}}
+}
+
+// Load implements CheckpointStore.Load().
+func (m *MemoryCheckpointStore) Load(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cursors[key], nil
+}
+
+// Save implements CheckpointStore.Save().
+func (m *MemoryCheckpointStore) Save(_ context.Context, key string, cursor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursors[key] = cursor
+	return nil
+}
+
+// FileCheckpointStore is a CheckpointStore that keeps the cursors in a JSON file, which is replaced atomically on each
+// save, so that a reader resumes from its last cursor after a restart.
+type FileCheckpointStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileCheckpointStore is the constructor for FileCheckpointStore. The file at path is created on the first save.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load implements CheckpointStore.Load().
+func (f *FileCheckpointStore) Load(_ context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cursors, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	return cursors[key], nil
+}
+
+// Save implements CheckpointStore.Save().
+func (f *FileCheckpointStore) Save(_ context.Context, key string, cursor string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cursors, err := f.read()
+	if err != nil {
+		return err
+	}
+	cursors[key] = cursor
+
+	b, err := json.Marshal(cursors)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not save the checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not save the checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not save the checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not save the checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("could not save the checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (f *FileCheckpointStore) read() (map[string]string, error) {
+	cursors := map[string]string{}
+	b, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the checkpoint file: %w", err)
+	}
+	if err := json.Unmarshal(b, &cursors); err != nil {
+		return nil, fmt.Errorf("checkpoint file %s is not valid: %w", f.path, err)
+	}
+	return cursors, nil
+}
+
+// IncrementalBatch is rows that were ingested between two database cursors. A poll delivers the rows of its cursor
+// range in batches of at most IncrementalBatchSize() rows, which have the same From and To.
+type IncrementalBatch struct {
+	// From is the cursor after which the rows were ingested, "" for the first batch of a reader without a start cursor.
+	From string
+	// To is the cursor at or before which the rows were ingested. It is saved once the last batch of the poll is
+	// handled.
+	To string
+	// Rows are rows returned by the query for this range.
+	Rows []*table.Row
+}
+
+// IncrementalHandler handles a batch of an IncrementalReader. If it returns an error, the cursor is not saved and the
+// rows of the poll, including those of the batches it already handled, are read again by the next poll.
+type IncrementalHandler func(ctx context.Context, batch IncrementalBatch) error
+
+// IncrementalOption is an optional argument to NewIncrementalReader().
+type IncrementalOption func(r *IncrementalReader)
+
+// IncrementalInterval sets the time between two polls of IncrementalReader.Run(). The default is one minute.
+func IncrementalInterval(d time.Duration) IncrementalOption {
+	return func(r *IncrementalReader) {
+		r.interval = d
+	}
+}
+
+// IncrementalCheckpoint sets the store where the cursor is saved, under key. Readers of different queries that share
+// a store must have different keys. The default is a MemoryCheckpointStore. If key is "", it is derived from the
+// database and the text of the query, so that a reader whose query changed starts over from its start cursor.
+func IncrementalCheckpoint(store CheckpointStore, key string) IncrementalOption {
+	return func(r *IncrementalReader) {
+		r.store = store
+		r.key = key
+	}
+}
+
+// IncrementalScopedTables sets the tables that are read incrementally. By default, these are the tables that the
+// query refers to. Tables that are not scoped are read in full by every poll.
+func IncrementalScopedTables(tables ...string) IncrementalOption {
+	return func(r *IncrementalReader) {
+		r.tables = tables
+	}
+}
+
+// IncrementalStartCursor sets the cursor to start from when the store has no cursor yet. By default, the first poll
+// reads all the rows of the tables. Use the cursor_current() of a previous query to read only new rows.
+func IncrementalStartCursor(cursor string) IncrementalOption {
+	return func(r *IncrementalReader) {
+		r.start = cursor
+	}
+}
+
+// IncrementalBatchSize sets the maximum number of rows of an IncrementalBatch, so that a poll does not hold all the
+// rows of its cursor range in memory. The default is 10,000.
+func IncrementalBatchSize(rows int) IncrementalOption {
+	return func(r *IncrementalReader) {
+		r.batchSize = rows
+	}
+}
+
+// IncrementalQueryOptions adds options to the queries of the reader.
+func IncrementalQueryOptions(options ...QueryOption) IncrementalOption {
+	return func(r *IncrementalReader) {
+		r.options = append(r.options, options...)
+	}
+}
+
+// IncrementalReader reads the rows that are ingested into tables, using database cursors. Each poll gets the
+// cursor_current() of the database and runs the query on the rows ingested after the saved cursor and at or before
+// the current one, with the QueryCursorAfterDefault(), QueryCursorBeforeOrAtDefault() and QueryCursorScopedTables()
+// options. Once the rows are handled, the current cursor is saved in the CheckpointStore, so a reader that restarts
+// with the same store and key resumes where it stopped. Rows are delivered at least once: rows handled by a poll
+// whose cursor was not saved are delivered again.
+//
+// The tables must have an IngestionTime policy, which is enabled by default. An IncrementalReader must not be polled
+// concurrently.
+type IncrementalReader struct {
+	client    *Client
+	db        string
+	query     Statement
+	interval  time.Duration
+	store     CheckpointStore
+	key       string
+	tables    []string
+	start     string
+	batchSize int
+	options   []QueryOption
+}
+
+// NewIncrementalReader returns an IncrementalReader that runs query in database db.
+func NewIncrementalReader(client *Client, db string, query Statement, options ...IncrementalOption) (*IncrementalReader, error) {
+	r := &IncrementalReader{
+		client:    client,
+		db:        db,
+		query:     query,
+		interval:  time.Minute,
+		store:     NewMemoryCheckpointStore(),
+		batchSize: 10000,
+	}
+	for _, o := range options {
+		o(r)
+	}
+
+	if r.interval <= 0 {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "the interval of an IncrementalReader must be positive").SetNoRetry()
+	}
+	if r.key == "" {
+		r.key = checkpointKey(db, query)
+	}
+	if r.batchSize <= 0 {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "the batch size of an IncrementalReader must be positive").SetNoRetry()
+	}
+	if r.store == nil {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "an IncrementalReader needs a CheckpointStore").SetNoRetry()
+	}
+	if r.tables == nil {
+		tables, err := lexer.Tables(query.String())
+		if err != nil {
+			return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "the query is not valid KQL: %s", err).SetNoRetry()
+		}
+		r.tables = tables
+	}
+	if len(r.tables) == 0 {
+		return nil, errors.ES(errors.OpQuery, errors.KClientArgs, "the query of an IncrementalReader must read from a table, or use IncrementalScopedTables()").SetNoRetry()
+	}
+	return r, nil
+}
+
+// Cursor returns the cursor that the next poll reads after.
+func (r *IncrementalReader) Cursor(ctx context.Context) (string, error) {
+	cursor, err := r.store.Load(ctx, r.key)
+	if err != nil {
+		return "", errors.E(errors.OpQuery, errors.KOther, fmt.Errorf("could not load the cursor: %w", err))
+	}
+	if cursor == "" {
+		cursor = r.start
+	}
+	return cursor, nil
+}
+
+// Poll reads the rows ingested since the saved cursor and passes them to handler as they are received, in batches of
+// at most IncrementalBatchSize() rows, then saves the new cursor. handler is not called if no rows were ingested.
+func (r *IncrementalReader) Poll(ctx context.Context, handler IncrementalHandler) error {
+	from, err := r.Cursor(ctx)
+	if err != nil {
+		return err
+	}
+	to, err := r.currentCursor(ctx)
+	if err != nil {
+		return err
+	}
+	if to == from {
+		return nil
+	}
+
+	options := append([]QueryOption{}, r.options...)
+	options = append(options, QueryCursorBeforeOrAtDefault(to), QueryCursorScopedTables(r.tables))
+	if from != "" {
+		options = append(options, QueryCursorAfterDefault(from))
+	}
+	iter, err := r.client.Query(ctx, r.db, r.query, options...)
+	if err != nil {
+		return err
+	}
+	defer iter.Stop()
+
+	var rows []*table.Row
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+		batch := IncrementalBatch{From: from, To: to, Rows: rows}
+		rows = nil
+		return handler(ctx, batch)
+	}
+	err = iter.DoOnRowOrError(func(row *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		rows = append(rows, row)
+		if len(rows) == r.batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if err := r.store.Save(ctx, r.key, to); err != nil {
+		return errors.E(errors.OpQuery, errors.KOther, fmt.Errorf("could not save the cursor: %w", err))
+	}
+	return nil
+}
+
+// Run polls immediately, then at each interval, until ctx is done or a poll fails, and returns the error.
+// Run can be called again after it returns, and resumes from the saved cursor.
+func (r *IncrementalReader) Run(ctx context.Context, handler IncrementalHandler) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Poll(ctx, handler); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stream is Run, with the batches sent on ch. The cursor of a batch is saved once ch receives it, so a batch that is
+// received but not processed before a restart is not delivered again; use Run to save it after processing instead.
+// Stream does not close ch.
+func (r *IncrementalReader) Stream(ctx context.Context, ch chan<- IncrementalBatch) error {
+	return r.Run(ctx, func(ctx context.Context, batch IncrementalBatch) error {
+		select {
+		case ch <- batch:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// checkpointKey is the default key of the cursor of a reader of query in database db.
+func checkpointKey(db string, query Statement) string {
+	sum := sha256.Sum256([]byte(query.String()))
+	return db + "/" + hex.EncodeToString(sum[:8])
+}
+
+// currentCursor returns the cursor_current() of the database.
+func (r *IncrementalReader) currentCursor(ctx context.Context) (string, error) {
+	iter, err := r.client.Query(ctx, r.db, kql.New("print Cursor = cursor_current()"), r.options...)
+	if err != nil {
+		return "", err
+	}
+	defer iter.Stop()
+
+	var cursor string
+	err = iter.Do(func(row *table.Row) error {
+		if len(row.Values) != 1 {
+			return errors.ES(errors.OpQuery, errors.KInternal, "cursor_current() returned %d columns", len(row.Values))
+		}
+		s, ok := row.Values[0].(value.String)
+		if !ok {
+			return errors.ES(errors.OpQuery, errors.KInternal, "cursor_current() returned a %T", row.Values[0])
+		}
+		cursor = s.Value
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if cursor == "" {
+		return "", errors.ES(errors.OpQuery, errors.KInternal, "cursor_current() returned no cursor")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,298 @@
+package kusto
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/internal/frames"
+	v2 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v2"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cursorConn is a queryer that serves cursor_current() and returns the rows ingested into a table at each cursor.
+type cursorConn struct {
+	mockConn
+
+	mu sync.Mutex
+	// rows are the values of the rows, by the cursor they were ingested at. The current cursor is len(rows).
+	rows [][]int64
+	// options are the cursor options of the queries that read the table.
+	options []map[string]interface{}
+}
+
+func (c *cursorConn) ingest(values ...int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rows = append(c.rows, values)
+}
+
+func (c *cursorConn) query(_ context.Context, _ string, query Statement, opts *queryOptions) (execResp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var columns table.Columns
+	var rows []value.Values
+	if query.String() == "print Cursor = cursor_current()" {
+		columns = table.Columns{{Name: "Cursor", Type: types.String}}
+		rows = []value.Values{{value.String{Value: fmt.Sprint(len(c.rows)), Valid: true}}}
+	} else {
+		options := map[string]interface{}{}
+		for _, k := range []string{QueryCursorAfterDefaultValue, QueryCursorBeforeOrAtDefaultValue, QueryCursorScopedTablesValue} {
+			if v, ok := opts.requestProperties.Options[k]; ok {
+				options[k] = v
+			}
+		}
+		c.options = append(c.options, options)
+
+		after, before := 0, 0
+		if s, ok := options[QueryCursorAfterDefaultValue].(string); ok {
+			fmt.Sscan(s, &after)
+		}
+		fmt.Sscan(options[QueryCursorBeforeOrAtDefaultValue].(string), &before)
+		columns = table.Columns{{Name: "x", Type: types.Long}}
+		for _, values := range c.rows[after:before] {
+			for _, v := range values {
+				rows = append(rows, value.Values{value.Long{Value: v, Valid: true}})
+			}
+		}
+	}
+
+	ch := make(chan frames.Frame, 3)
+	ch <- v2.DataSetHeader{}
+	ch <- v2.DataTable{
+		TableKind: frames.PrimaryResult,
+		TableName: frames.PrimaryResult,
+		Columns:   columns,
+		KustoRows: rows,
+	}
+	ch <- v2.DataSetCompletion{}
+	close(ch)
+	return execResp{frameCh: ch}, nil
+}
+
+func batchValues(batch IncrementalBatch) []int64 {
+	var got []int64
+	for _, row := range batch.Rows {
+		got = append(got, row.Values[0].(value.Long).Value)
+	}
+	return got
+}
+
+func TestIncrementalReaderPoll(t *testing.T) {
+	t.Parallel()
+
+	conn := &cursorConn{}
+	client := NewMockClient()
+	client.conn = conn
+	store := NewMemoryCheckpointStore()
+
+	r, err := NewIncrementalReader(client, "db", kql.New("Events | where x > 0"), IncrementalCheckpoint(store, "events"))
+	require.NoError(t, err)
+
+	var batches []IncrementalBatch
+	handler := func(_ context.Context, batch IncrementalBatch) error {
+		batches = append(batches, batch)
+		return nil
+	}
+
+	conn.ingest(1, 2)
+	require.NoError(t, r.Poll(context.Background(), handler))
+	// Nothing was ingested since the last poll, so the table is not queried.
+	require.NoError(t, r.Poll(context.Background(), handler))
+	conn.ingest(3)
+	conn.ingest(4)
+	require.NoError(t, r.Poll(context.Background(), handler))
+
+	require.Len(t, batches, 2)
+	assert.Equal(t, "", batches[0].From)
+	assert.Equal(t, "1", batches[0].To)
+	assert.Equal(t, []int64{1, 2}, batchValues(batches[0]))
+	assert.Equal(t, "1", batches[1].From)
+	assert.Equal(t, "3", batches[1].To)
+	assert.Equal(t, []int64{3, 4}, batchValues(batches[1]))
+
+	assert.Equal(t, []map[string]interface{}{
+		{QueryCursorBeforeOrAtDefaultValue: "1", QueryCursorScopedTablesValue: []string{"Events"}},
+		{QueryCursorAfterDefaultValue: "1", QueryCursorBeforeOrAtDefaultValue: "3", QueryCursorScopedTablesValue: []string{"Events"}},
+	}, conn.options)
+
+	cursor, err := store.Load(context.Background(), "events")
+	require.NoError(t, err)
+	assert.Equal(t, "3", cursor)
+}
+
+func TestIncrementalReaderRestart(t *testing.T) {
+	t.Parallel()
+
+	conn := &cursorConn{}
+	client := NewMockClient()
+	client.conn = conn
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	query := kql.New("Events")
+
+	var got []int64
+	handler := func(_ context.Context, batch IncrementalBatch) error {
+		got = append(got, batchValues(batch)...)
+		return nil
+	}
+
+	conn.ingest(1)
+	r, err := NewIncrementalReader(client, "db", query, IncrementalCheckpoint(store, "events"))
+	require.NoError(t, err)
+	require.NoError(t, r.Poll(context.Background(), handler))
+
+	// A failed handler does not save the cursor, so the batch is read again.
+	conn.ingest(2)
+	failed := r.Poll(context.Background(), func(context.Context, IncrementalBatch) error { return fmt.Errorf("failed") })
+	assert.Error(t, failed)
+
+	// A new reader with the same store resumes from the saved cursor.
+	r, err = NewIncrementalReader(client, "db", query, IncrementalCheckpoint(store, "events"))
+	require.NoError(t, err)
+	cursor, err := r.Cursor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "1", cursor)
+	require.NoError(t, r.Poll(context.Background(), handler))
+
+	assert.Equal(t, []int64{1, 2}, got)
+}
+
+func TestIncrementalReaderBatchSize(t *testing.T) {
+	t.Parallel()
+
+	conn := &cursorConn{}
+	client := NewMockClient()
+	client.conn = conn
+	store := NewMemoryCheckpointStore()
+
+	r, err := NewIncrementalReader(client, "db", kql.New("Events"), IncrementalCheckpoint(store, "events"), IncrementalBatchSize(2))
+	require.NoError(t, err)
+
+	conn.ingest(1, 2, 3, 4, 5)
+	var got [][]int64
+	err = r.Poll(context.Background(), func(ctx context.Context, batch IncrementalBatch) error {
+		assert.Equal(t, "", batch.From)
+		assert.Equal(t, "1", batch.To)
+		// The cursor is only saved once the last batch is handled.
+		cursor, err := store.Load(ctx, "events")
+		require.NoError(t, err)
+		assert.Equal(t, "", cursor)
+
+		got = append(got, batchValues(batch))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]int64{{1, 2}, {3, 4}, {5}}, got)
+
+	cursor, err := store.Load(context.Background(), "events")
+	require.NoError(t, err)
+	assert.Equal(t, "1", cursor)
+}
+
+func TestIncrementalReaderDefaultKey(t *testing.T) {
+	t.Parallel()
+
+	conn := &cursorConn{}
+	client := NewMockClient()
+	client.conn = conn
+	store := NewMemoryCheckpointStore()
+
+	conn.ingest(1)
+	all, err := NewIncrementalReader(client, "db", kql.New("Events"), IncrementalCheckpoint(store, ""))
+	require.NoError(t, err)
+	require.NoError(t, all.Poll(context.Background(), func(context.Context, IncrementalBatch) error { return nil }))
+
+	// A reader of another query on the same store does not resume from the cursor of the first one.
+	negative, err := NewIncrementalReader(client, "db", kql.New("Events | where x < 0"), IncrementalCheckpoint(store, ""))
+	require.NoError(t, err)
+	cursor, err := negative.Cursor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "", cursor)
+
+	all, err = NewIncrementalReader(client, "db", kql.New("Events"), IncrementalCheckpoint(store, ""))
+	require.NoError(t, err)
+	cursor, err = all.Cursor(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "1", cursor)
+}
+
+func TestIncrementalReaderStream(t *testing.T) {
+	t.Parallel()
+
+	conn := &cursorConn{}
+	client := NewMockClient()
+	client.conn = conn
+	conn.ingest(1)
+	conn.ingest(2)
+
+	r, err := NewIncrementalReader(client, "db", kql.New("Events"), IncrementalInterval(time.Millisecond), IncrementalStartCursor("1"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan IncrementalBatch)
+	done := make(chan error, 1)
+	go func() { done <- r.Stream(ctx, ch) }()
+
+	assert.Equal(t, []int64{2}, batchValues(<-ch))
+	conn.ingest(3)
+	assert.Equal(t, []int64{3}, batchValues(<-ch))
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestNewIncrementalReaderErrors(t *testing.T) {
+	t.Parallel()
+
+	client := NewMockClient()
+
+	_, err := NewIncrementalReader(client, "db", kql.New("print now()"))
+	assert.Error(t, err)
+	_, err = NewIncrementalReader(client, "db", kql.New("Events"), IncrementalInterval(0))
+	assert.Error(t, err)
+	_, err = NewIncrementalReader(client, "db", kql.New("Events"), IncrementalCheckpoint(nil, "k"))
+	assert.Error(t, err)
+	_, err = NewIncrementalReader(client, "db", kql.New("Events"), IncrementalBatchSize(0))
+	assert.Error(t, err)
+
+	r, err := NewIncrementalReader(client, "db", kql.New("print now()"), IncrementalScopedTables("Events"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Events"}, r.tables)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	store := NewFileCheckpointStore(path)
+
+	cursor, err := store.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "", cursor)
+
+	require.NoError(t, store.Save(ctx, "a", "1"))
+	require.NoError(t, store.Save(ctx, "b", "2"))
+	require.NoError(t, store.Save(ctx, "a", "3"))
+
+	store = NewFileCheckpointStore(path)
+	cursor, err = store.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "3", cursor)
+	cursor, err = store.Load(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "2", cursor)
+
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
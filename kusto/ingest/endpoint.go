@@ -1,11 +1,23 @@
 package ingest
 
 import (
+	"net/url"
 	"strings"
 )
 
 const ingestPrefix = "ingest-"
 
+// removeIngestPrefix returns the engine endpoint for s, in case s is a data management endpoint derived by adding
+// "ingest-" to the engine host. Only a prefix of the host is removed, so hosts that merely contain "ingest-" (or
+// explicit ingestion endpoints that don't follow the convention) are left untouched.
 func removeIngestPrefix(s string) string {
-	return strings.Replace(s, ingestPrefix, "", 1)
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	if !strings.HasPrefix(strings.ToLower(u.Host), ingestPrefix) {
+		return s
+	}
+	u.Host = u.Host[len(ingestPrefix):]
+	return u.String()
 }
@@ -0,0 +1,32 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveIngestPrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		endpoint string
+		want     string
+	}{
+		{desc: "Engine", endpoint: "https://cluster.kusto.windows.net", want: "https://cluster.kusto.windows.net"},
+		{desc: "Ingest", endpoint: "https://ingest-cluster.kusto.windows.net", want: "https://cluster.kusto.windows.net"},
+		{desc: "IngestUpperCase", endpoint: "https://INGEST-cluster.kusto.windows.net", want: "https://cluster.kusto.windows.net"},
+		{desc: "ContainsIngest", endpoint: "https://myingest-cluster.kusto.windows.net", want: "https://myingest-cluster.kusto.windows.net"},
+		{desc: "IngestInPath", endpoint: "https://proxy.contoso.com/ingest-cluster", want: "https://proxy.contoso.com/ingest-cluster"},
+		{desc: "Emulator", endpoint: "http://localhost:8080", want: "http://localhost:8080"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.want, removeIngestPrefix(test.endpoint))
+		})
+	}
+}
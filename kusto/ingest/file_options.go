@@ -227,8 +227,8 @@ func InferFormatFromFileName(fName string) DataFormat {
 
 // IngestionMapping provides runtime mapping of the data being imported to the fields in the table.
 // "ref" will be JSON encoded, so it can be any type that can be JSON marshalled. If you pass a string
-// or []byte, it will be interpreted as already being JSON encoded.
-// mappingKind can only be: CSV, JSON, AVRO, Parquet or ORC.
+// or []byte, it will be interpreted as already being JSON encoded. A []ColumnMapping is validated with MarshalMapping().
+// mappingKind can only be: CSV, JSON, AVRO, Parquet, ORC or W3CLogFile.
 // The mappingKind parameter will also automatically set the FileFormat option.
 func IngestionMapping(mapping interface{}, mappingKind DataFormat) FileOption {
 	return option{
@@ -247,6 +247,11 @@ func IngestionMapping(mapping interface{}, mappingKind DataFormat) FileOption {
 				j = v
 			case []byte:
 				j = string(v)
+			case []ColumnMapping:
+				var err error
+				if j, err = MarshalMapping(mappingKind, v...); err != nil {
+					return err
+				}
 			default:
 				b, err := json.Marshal(mapping)
 				if err != nil {
@@ -272,7 +277,8 @@ func IngestionMapping(mapping interface{}, mappingKind DataFormat) FileOption {
 }
 
 // IngestionMappingRef provides the name of a pre-created mapping for the data being imported to the fields in the table.
-// mappingKind can only be: CSV, JSON, AVRO, Parquet or ORC.
+// Mappings can be created with CreateMapping().
+// mappingKind can only be: CSV, JSON, AVRO, Parquet, ORC or W3CLogFile.
 // For more details, see: https://docs.microsoft.com/en-us/azure/kusto/management/create-ingestion-mapping-command
 // The mappingKind parameter will also automatically set the FileFormat option.
 func IngestionMappingRef(refName string, mappingKind DataFormat) FileOption {
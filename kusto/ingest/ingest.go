@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"sync"
 
 	"github.com/Azure/azure-kusto-go/kusto"
@@ -13,6 +14,7 @@ import (
 	"github.com/Azure/azure-kusto-go/kusto/ingest/internal/properties"
 	"github.com/Azure/azure-kusto-go/kusto/ingest/internal/queued"
 	"github.com/Azure/azure-kusto-go/kusto/ingest/internal/resources"
+	"github.com/Azure/azure-kusto-go/kusto/ingest/internal/utils"
 	"github.com/google/uuid"
 )
 
@@ -39,6 +41,10 @@ type Ingestion struct {
 
 	bufferSize int
 	maxBuffers int
+
+	// inline is set when talking to the Kusto emulator, which has no ingestion resources. Data is then ingested
+	// with ".ingest inline" commands instead of being queued.
+	inline bool
 }
 
 // Option is an optional argument to New().
@@ -75,6 +81,19 @@ func stringToIngestionMappingType(s string) (DataFormat, error) {
 
 // New is a constructor for Ingestion.
 func New(client QueryClient, db, table string, options ...Option) (*Ingestion, error) {
+	if isEmulator(client) {
+		i := &Ingestion{
+			client: client,
+			db:     db,
+			table:  table,
+			inline: true,
+		}
+		for _, option := range options {
+			option(i)
+		}
+		return i, nil
+	}
+
 	mgr, err := resources.New(client)
 	if err != nil {
 		return nil, err
@@ -104,12 +123,14 @@ func New(client QueryClient, db, table string, options ...Option) (*Ingestion, e
 func (i *Ingestion) prepForIngestion(ctx context.Context, options []FileOption, props properties.All, source SourceScope) (*Result, properties.All, error) {
 	result := newResult()
 
-	auth, err := i.mgr.AuthContext(ctx)
-	if err != nil {
-		return nil, properties.All{}, err
-	}
+	if !i.inline {
+		auth, err := i.mgr.AuthContext(ctx)
+		if err != nil {
+			return nil, properties.All{}, err
+		}
 
-	props.Ingestion.Additional.AuthContext = auth
+		props.Ingestion.Additional.AuthContext = auth
+	}
 
 	for _, o := range options {
 		if err := o.Run(&props, QueuedClient, source); err != nil {
@@ -117,12 +138,14 @@ func (i *Ingestion) prepForIngestion(ctx context.Context, options []FileOption,
 		}
 	}
 
-	ingestionFormat, err := stringToIngestionMappingType(i.ingestionFormat)
-	if err != nil {
-		return nil, properties.All{}, err
+	if i.ingestionFormat != "" {
+		ingestionFormat, err := stringToIngestionMappingType(i.ingestionFormat)
+		if err != nil {
+			return nil, properties.All{}, err
+		}
+		props.Ingestion.Additional.Format = ingestionFormat
+		props.Ingestion.Additional.IngestionMappingType = ingestionFormat
 	}
-	props.Ingestion.Additional.Format = ingestionFormat
-	props.Ingestion.Additional.IngestionMappingType = ingestionFormat
 
 	if source == FromReader && props.Ingestion.Additional.Format == DFUnknown {
 		props.Ingestion.Additional.Format = CSV
@@ -136,7 +159,7 @@ func (i *Ingestion) prepForIngestion(ctx context.Context, options []FileOption,
 		).SetNoRetry()
 	}
 
-	if props.Ingestion.ReportLevel != properties.None {
+	if props.Ingestion.ReportLevel != properties.None && !i.inline {
 		if props.Source.ID == uuid.Nil {
 			props.Source.ID = uuid.New()
 		}
@@ -190,6 +213,10 @@ func (i *Ingestion) fromFile(ctx context.Context, fPath string, options []FileOp
 
 	result.record.IngestionSourcePath = fPath
 
+	if i.inline {
+		return i.fromFileInline(ctx, fPath, local, props)
+	}
+
 	if local {
 		err = i.fs.Local(ctx, fPath, props)
 	} else {
@@ -218,6 +245,10 @@ func (i *Ingestion) fromReader(ctx context.Context, reader io.Reader, options []
 		return nil, err
 	}
 
+	if i.inline {
+		return ingestInline(ctx, i.client, reader, props)
+	}
+
 	path, err := i.fs.Reader(ctx, reader, props)
 	if err != nil {
 		return nil, err
@@ -263,7 +294,7 @@ func (i *Ingestion) getStreamConn() (streamIngestor, error) {
 		return i.streamConn, nil
 	}
 
-	sc, err := kusto.NewConn(removeIngestPrefix(i.client.Endpoint()), i.client.Auth(), i.client.HttpClient(), i.client.ClientDetails())
+	sc, err := kusto.NewConn(engineEndpoint(i.client), i.client.Auth(), i.client.HttpClient(), i.client.ClientDetails(), connOptions(i.client)...)
 	if err != nil {
 		return nil, err
 	}
@@ -271,6 +302,29 @@ func (i *Ingestion) getStreamConn() (streamIngestor, error) {
 	return i.streamConn, nil
 }
 
+// fromFileInline ingests a local file with ".ingest inline", for the Kusto emulator.
+func (i *Ingestion) fromFileInline(ctx context.Context, fPath string, local bool, props properties.All) (*Result, error) {
+	if !local {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "blob ingestion is not supported by the Kusto emulator, use a local file or a reader").SetNoRetry()
+	}
+
+	if utils.CompressionDiscovery(fPath) == properties.ZIP {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "zip files are not supported by the Kusto emulator, use gzip or uncompressed files").SetNoRetry()
+	}
+
+	if err := queued.CompleteFormatFromFileName(&props, fPath); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(fPath)
+	if err != nil {
+		return nil, errors.E(errors.OpFileIngest, errors.KLocalFileSystem, err).SetNoRetry()
+	}
+	defer file.Close()
+
+	return ingestInline(ctx, i.client, file, props)
+}
+
 func (i *Ingestion) newProp() properties.All {
 	return properties.All{
 		Ingestion: properties.Ingestion{
@@ -281,9 +335,11 @@ func (i *Ingestion) newProp() properties.All {
 }
 
 func (i *Ingestion) Close() error {
-	i.mgr.Close()
 	var err error
-	err = i.fs.Close()
+	if !i.inline {
+		i.mgr.Close()
+		err = i.fs.Close()
+	}
 	if i.streamConn != nil {
 		err2 := i.streamConn.Close()
 		if err == nil {
@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/ingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+)
+
+// ingestInline ingests the content of reader with an ".ingest inline" command. This is how queued ingestion is done
+// against the local Kusto emulator, which has no ingestion resources (queues, blobs) to upload the data to.
+func ingestInline(ctx context.Context, client QueryClient, reader io.Reader, props properties.All) (*Result, error) {
+	if props.Ingestion.ReportMethod == properties.ReportStatusToTable || props.Ingestion.ReportMethod == properties.ReportStatusToQueueAndTable {
+		return nil, errors.ES(errors.OpFileIngest, errors.KClientArgs, "reporting status to a table is not supported by the Kusto emulator").SetNoRetry()
+	}
+
+	data, err := readInline(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := inlineStmt(props, data)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := client.Mgmt(ctx, props.Ingestion.DatabaseName, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	err = iter.DoOnRowOrError(func(r *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := props.ApplyDeleteLocalSourceOption(); err != nil {
+		return nil, err
+	}
+
+	result := newResult()
+	result.putProps(props)
+	result.record.Status = Succeeded
+	return result, nil
+}
+
+// readInline reads all of reader, decompressing it if it is gzipped, as ".ingest inline" only accepts plain text.
+func readInline(reader io.Reader) (string, error) {
+	br := bufio.NewReader(reader)
+	header, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return "", errors.E(errors.OpFileIngest, errors.KIO, err)
+	}
+
+	var r io.Reader = br
+	if len(header) == 2 && header[0] == 0x1f && header[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return "", errors.E(errors.OpFileIngest, errors.KIO, err).SetNoRetry()
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", errors.E(errors.OpFileIngest, errors.KIO, err)
+	}
+	return string(data), nil
+}
+
+// inlineStmt builds the ".ingest inline" command for data, carrying over the ingestion properties it supports.
+func inlineStmt(props properties.All, data string) (kusto.Statement, error) {
+	additional := props.Ingestion.Additional
+	format := additional.Format
+	if format == DFUnknown {
+		format = CSV
+	}
+
+	stmt := kql.New(".ingest inline into table ").AddTable(props.Ingestion.TableName).
+		AddLiteral(" with (format=").AddString(format.String())
+
+	if additional.IngestionMappingRef != "" {
+		stmt.AddLiteral(", ingestionMappingReference=").AddString(additional.IngestionMappingRef)
+	}
+	if additional.IngestionMapping != "" {
+		stmt.AddLiteral(", ingestionMapping=").AddString(additional.IngestionMapping)
+	}
+	if additional.ValidationPolicy != "" {
+		stmt.AddLiteral(", validationPolicy=").AddString(additional.ValidationPolicy)
+	}
+	if additional.IgnoreFirstRecord {
+		stmt.AddLiteral(", ignoreFirstRecord=true")
+	}
+	if len(additional.Tags) > 0 {
+		tags, err := json.Marshal(additional.Tags)
+		if err != nil {
+			return nil, errors.E(errors.OpFileIngest, errors.KInternal, err).SetNoRetry()
+		}
+		stmt.AddLiteral(", tags=").AddString(string(tags))
+	}
+	if additional.IngestIfNotExists != "" {
+		stmt.AddLiteral(", ingestIfNotExists=").AddString(additional.IngestIfNotExists)
+	}
+	if !additional.CreationTime.IsZero() {
+		stmt.AddLiteral(", creationTime=").AddString(kql.FormatDatetime(additional.CreationTime))
+	}
+
+	// Everything after "<|" is taken as is by the service, so the data must not be escaped.
+	return stmt.AddLiteral(") <|\n").AddUnsafe(data), nil
+}
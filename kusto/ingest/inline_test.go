@@ -0,0 +1,126 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type emulatorMockClient struct {
+	mockClient
+}
+
+func (emulatorMockClient) IsEmulator() bool {
+	return true
+}
+
+func TestIngestionInline(t *testing.T) {
+	t.Parallel()
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, err := zw.Write([]byte("1,a\n2,b\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"a": 1}`), 0600))
+
+	tests := []struct {
+		desc    string
+		ingest  func(i *Ingestion) (*Result, error)
+		mgmtErr error
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "Reader",
+			ingest: func(i *Ingestion) (*Result, error) {
+				return i.FromReader(context.Background(), strings.NewReader("1,a\n2,b\n"))
+			},
+			want: ".ingest inline into table Table with (format=\"csv\") <|\n1,a\n2,b\n",
+		},
+		{
+			desc: "GzippedReader",
+			ingest: func(i *Ingestion) (*Result, error) {
+				return i.FromReader(context.Background(), bytes.NewReader(gzipped.Bytes()), IngestionMappingRef("map", CSV))
+			},
+			want: ".ingest inline into table Table with (format=\"csv\", ingestionMappingReference=\"map\") <|\n1,a\n2,b\n",
+		},
+		{
+			desc: "File",
+			ingest: func(i *Ingestion) (*Result, error) {
+				return i.FromFile(context.Background(), jsonPath)
+			},
+			want: ".ingest inline into table Table with (format=\"json\") <|\n{\"a\": 1}",
+		},
+		{
+			desc: "Blob",
+			ingest: func(i *Ingestion) (*Result, error) {
+				return i.FromFile(context.Background(), "https://account.blob.core.windows.net/container/data.csv")
+			},
+			wantErr: true,
+		},
+		{
+			desc: "CommandError",
+			ingest: func(i *Ingestion) (*Result, error) {
+				return i.FromReader(context.Background(), strings.NewReader("1,a\n"))
+			},
+			mgmtErr: fmt.Errorf("table not found"),
+			want:    ".ingest inline into table Table with (format=\"csv\") <|\n1,a\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			client := emulatorMockClient{mockClient{
+				endpoint: "http://localhost:8080",
+				onMgmt: func(ctx context.Context, db string, query kusto.Statement, options ...kusto.MgmtOption) (*kusto.RowIterator, error) {
+					got = query.String()
+					rows, err := kusto.NewMockRows(table.Columns{{Name: "ExtentId", Type: types.GUID}})
+					if err != nil {
+						return nil, err
+					}
+					if test.mgmtErr != nil {
+						if err := rows.Error(test.mgmtErr); err != nil {
+							return nil, err
+						}
+					}
+					iter := &kusto.RowIterator{}
+					return iter, iter.Mock(rows)
+				},
+			}}
+
+			in, err := New(client, "Database", "Table")
+			require.NoError(t, err)
+			defer in.Close()
+
+			res, err := test.ingest(in)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.want, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, Succeeded, res.record.Status)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -124,7 +124,7 @@ var dfDescriptions = []dfDescriptor{
 	{"Tsv", "tsv", ".tsv", false},
 	{"Tsve", "tsve", ".tsve", false},
 	{"Txt", "txt", ".txt", false},
-	{"W3cLogFile", "w3clogfile", ".w3clogfile", false},
+	{"W3cLogFile", "w3clogfile", ".w3clogfile", true},
 	{"SingleJson", "singlejson", "", false},
 }
 
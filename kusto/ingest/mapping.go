@@ -0,0 +1,358 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+)
+
+// MappingTransform is a transformation applied to the source of a column of an ingestion mapping.
+// For more details, see: https://learn.microsoft.com/en-us/azure/data-explorer/kusto/management/mappings#mapping-transformations
+type MappingTransform string
+
+//goland:noinspection GoUnusedConst - Part of the API
+const (
+	// SourceLocation is the URI of the blob or file the row was ingested from. It needs no source path.
+	SourceLocation MappingTransform = "SourceLocation"
+	// SourceLineNumber is the line number of the row in the ingested file. It needs no source path.
+	SourceLineNumber MappingTransform = "SourceLineNumber"
+	// DateTimeFromUnixSeconds converts a number of seconds since the Unix epoch to a datetime.
+	DateTimeFromUnixSeconds MappingTransform = "DateTimeFromUnixSeconds"
+	// DateTimeFromUnixMilliseconds converts a number of milliseconds since the Unix epoch to a datetime.
+	DateTimeFromUnixMilliseconds MappingTransform = "DateTimeFromUnixMilliseconds"
+	// DateTimeFromUnixMicroseconds converts a number of microseconds since the Unix epoch to a datetime.
+	DateTimeFromUnixMicroseconds MappingTransform = "DateTimeFromUnixMicroseconds"
+	// DateTimeFromUnixNanoseconds converts a number of nanoseconds since the Unix epoch to a datetime.
+	DateTimeFromUnixNanoseconds MappingTransform = "DateTimeFromUnixNanoseconds"
+	// DropMappedFields maps the object at the path to a dynamic column, without the properties mapped to other columns.
+	DropMappedFields MappingTransform = "DropMappedFields"
+	// BytesAsBase64 maps a byte array to a base64 encoded string.
+	BytesAsBase64 MappingTransform = "BytesAsBase64"
+)
+
+// sourceless returns true if the transformation produces a value without reading the source data.
+func (t MappingTransform) sourceless() bool {
+	return t == SourceLocation || t == SourceLineNumber
+}
+
+func (t MappingTransform) valid() bool {
+	switch t {
+	case SourceLocation, SourceLineNumber, DateTimeFromUnixSeconds, DateTimeFromUnixMilliseconds,
+		DateTimeFromUnixMicroseconds, DateTimeFromUnixNanoseconds, DropMappedFields, BytesAsBase64:
+		return true
+	}
+	return false
+}
+
+// ColumnMapping maps the source data of one column of a table. It is implemented by CsvColumnMapping,
+// JsonColumnMapping, AvroColumnMapping, ParquetColumnMapping and W3CLogColumnMapping.
+type ColumnMapping interface {
+	// Kind is the mapping kind the column mapping belongs to.
+	Kind() DataFormat
+	element() (mappingElement, error)
+}
+
+// mappingElement is the JSON of a column mapping.
+type mappingElement struct {
+	Column     string            `json:"Column"`
+	DataType   types.Column      `json:"DataType,omitempty"`
+	Properties map[string]string `json:"Properties"`
+}
+
+// newElement returns the mappingElement of column with its source property set, or an error if there is no single
+// source for the column. source is the name of the property that reads the source data, such as "Path".
+func newElement(column string, dataType types.Column, source, value, constValue string, transform MappingTransform) (mappingElement, error) {
+	fail := func(format string, args ...interface{}) (mappingElement, error) {
+		return mappingElement{}, errors.ES(errors.OpUnknown, errors.KClientArgs, "column mapping %q: "+format, append([]interface{}{column}, args...)...).SetNoRetry()
+	}
+
+	if column == "" {
+		return fail("Column must be set")
+	}
+	if dataType != "" && !dataType.Valid() {
+		return fail("DataType %q is not a Kusto type", dataType)
+	}
+	if transform != "" && !transform.valid() {
+		return fail("unknown Transform %q", transform)
+	}
+
+	e := mappingElement{Column: column, DataType: dataType, Properties: map[string]string{}}
+	switch {
+	case constValue != "":
+		if value != "" || transform != "" {
+			return fail("ConstValue cannot be combined with %s or Transform", source)
+		}
+		e.Properties["ConstValue"] = constValue
+	case transform.sourceless():
+		if value != "" {
+			return fail("Transform %s reads no data and cannot be combined with %s", transform, source)
+		}
+		e.Properties["Transform"] = string(transform)
+	default:
+		if value == "" {
+			return fail("one of %s, ConstValue or a SourceLocation or SourceLineNumber Transform must be set", source)
+		}
+		e.Properties[source] = value
+		if transform != "" {
+			e.Properties["Transform"] = string(transform)
+		}
+	}
+	return e, nil
+}
+
+// CsvColumnMapping maps a column of a table to a field of CSV data, and of the other delimited formats.
+type CsvColumnMapping struct {
+	// Column is the name of the table column.
+	Column string
+	// DataType is the type of the column, for when the mapping creates it. It is optional.
+	DataType types.Column
+	// Ordinal is the position of the field in the record, starting at 0.
+	Ordinal int
+	// ConstValue is a value used for the column instead of the data.
+	ConstValue string
+	// Transform can only be SourceLocation or SourceLineNumber for CSV data.
+	Transform MappingTransform
+}
+
+// Kind implements ColumnMapping.Kind.
+func (CsvColumnMapping) Kind() DataFormat {
+	return CSV
+}
+
+func (m CsvColumnMapping) element() (mappingElement, error) {
+	if m.Ordinal < 0 {
+		return mappingElement{}, errors.ES(errors.OpUnknown, errors.KClientArgs, "column mapping %q: Ordinal cannot be negative", m.Column).SetNoRetry()
+	}
+	if m.Transform != "" && !m.Transform.sourceless() {
+		return mappingElement{}, errors.ES(errors.OpUnknown, errors.KClientArgs, "column mapping %q: Transform %s is not supported for CSV", m.Column, m.Transform).SetNoRetry()
+	}
+	ordinal := ""
+	if m.ConstValue == "" && m.Transform == "" {
+		ordinal = strconv.Itoa(m.Ordinal)
+	}
+	return newElement(m.Column, m.DataType, "Ordinal", ordinal, m.ConstValue, m.Transform)
+}
+
+// JsonColumnMapping maps a column of a table to a value of JSON data.
+type JsonColumnMapping struct {
+	// Column is the name of the table column.
+	Column string
+	// DataType is the type of the column, for when the mapping creates it. It is optional.
+	DataType types.Column
+	// Path is the JSON path of the value in the record, such as "$.user.id", or "$" for the whole record.
+	Path string
+	// ConstValue is a value used for the column instead of the data.
+	ConstValue string
+	// Transform is applied to the value at Path.
+	Transform MappingTransform
+}
+
+// Kind implements ColumnMapping.Kind.
+func (JsonColumnMapping) Kind() DataFormat {
+	return JSON
+}
+
+func (m JsonColumnMapping) element() (mappingElement, error) {
+	return pathElement(m.Column, m.DataType, m.Path, m.ConstValue, m.Transform)
+}
+
+// AvroColumnMapping maps a column of a table to a field of Avro data.
+type AvroColumnMapping struct {
+	// Column is the name of the table column.
+	Column string
+	// DataType is the type of the column, for when the mapping creates it. It is optional.
+	DataType types.Column
+	// Path is the JSON path of the field in the record, such as "$.user.id".
+	Path string
+	// ConstValue is a value used for the column instead of the data.
+	ConstValue string
+	// Transform is applied to the field at Path.
+	Transform MappingTransform
+}
+
+// Kind implements ColumnMapping.Kind.
+func (AvroColumnMapping) Kind() DataFormat {
+	return AVRO
+}
+
+func (m AvroColumnMapping) element() (mappingElement, error) {
+	return pathElement(m.Column, m.DataType, m.Path, m.ConstValue, m.Transform)
+}
+
+// ParquetColumnMapping maps a column of a table to a field of Parquet data.
+type ParquetColumnMapping struct {
+	// Column is the name of the table column.
+	Column string
+	// DataType is the type of the column, for when the mapping creates it. It is optional.
+	DataType types.Column
+	// Path is the JSON path of the field in the record, such as "$.user.id".
+	Path string
+	// ConstValue is a value used for the column instead of the data.
+	ConstValue string
+	// Transform is applied to the field at Path.
+	Transform MappingTransform
+}
+
+// Kind implements ColumnMapping.Kind.
+func (ParquetColumnMapping) Kind() DataFormat {
+	return Parquet
+}
+
+func (m ParquetColumnMapping) element() (mappingElement, error) {
+	return pathElement(m.Column, m.DataType, m.Path, m.ConstValue, m.Transform)
+}
+
+func pathElement(column string, dataType types.Column, path, constValue string, transform MappingTransform) (mappingElement, error) {
+	if path != "" && !strings.HasPrefix(path, "$") {
+		return mappingElement{}, errors.ES(errors.OpUnknown, errors.KClientArgs, "column mapping %q: Path %q must start with $", column, path).SetNoRetry()
+	}
+	return newElement(column, dataType, "Path", path, constValue, transform)
+}
+
+// W3CLogColumnMapping maps a column of a table to a field of a W3C Extended Log File.
+type W3CLogColumnMapping struct {
+	// Column is the name of the table column.
+	Column string
+	// DataType is the type of the column, for when the mapping creates it. It is optional.
+	DataType types.Column
+	// Field is the name of the field in the log, such as "cs-uri-stem".
+	Field string
+	// ConstValue is a value used for the column instead of the data.
+	ConstValue string
+	// Transform is applied to the field.
+	Transform MappingTransform
+}
+
+// Kind implements ColumnMapping.Kind.
+func (W3CLogColumnMapping) Kind() DataFormat {
+	return W3CLogFile
+}
+
+func (m W3CLogColumnMapping) element() (mappingElement, error) {
+	return newElement(m.Column, m.DataType, "Field", m.Field, m.ConstValue, m.Transform)
+}
+
+// MarshalMapping validates columns as an ingestion mapping of kind mappingKind and returns its JSON, as accepted by
+// IngestionMapping() and the ".create ingestion mapping" command.
+func MarshalMapping(mappingKind DataFormat, columns ...ColumnMapping) (string, error) {
+	if !mappingKind.IsValidMappingKind() {
+		return "", errors.ES(errors.OpUnknown, errors.KClientArgs, "%v is not an ingestion mapping kind", mappingKind).SetNoRetry()
+	}
+	if len(columns) == 0 {
+		return "", errors.ES(errors.OpUnknown, errors.KClientArgs, "an ingestion mapping needs at least one column").SetNoRetry()
+	}
+
+	elements := make([]mappingElement, 0, len(columns))
+	for _, c := range columns {
+		if c.Kind() != mappingKind {
+			return "", errors.ES(errors.OpUnknown, errors.KClientArgs, "a %T cannot be part of a %v mapping", c, mappingKind).SetNoRetry()
+		}
+		e, err := c.element()
+		if err != nil {
+			return "", err
+		}
+		elements = append(elements, e)
+	}
+
+	b, err := json.Marshal(elements)
+	if err != nil {
+		return "", errors.E(errors.OpUnknown, errors.KInternal, err).SetNoRetry()
+	}
+	return string(b), nil
+}
+
+// NamedMapping is an ingestion mapping of a table, which can be referenced with IngestionMappingRef().
+type NamedMapping struct {
+	Name  string
+	Kind  DataFormat
+	Table string
+	// Mapping is the JSON array of the column mappings.
+	Mapping string
+}
+
+// CreateMapping creates the ingestion mapping name of table tableName, or replaces it if it exists.
+func CreateMapping(ctx context.Context, client QueryClient, db, tableName, name string, mappingKind DataFormat, columns ...ColumnMapping) error {
+	mapping, err := MarshalMapping(mappingKind, columns...)
+	if err != nil {
+		return err
+	}
+
+	stmt := kql.New(".create-or-alter table ").AddTable(tableName).AddLiteral(" ingestion ").AddKeyword(mappingKind.String()).
+		AddLiteral(" mapping ").AddString(name).AddLiteral(" ").AddString(mapping)
+	return runMappingCommand(ctx, client, db, stmt)
+}
+
+// ListMappings returns the ingestion mappings of table tableName.
+func ListMappings(ctx context.Context, client QueryClient, db, tableName string) ([]NamedMapping, error) {
+	iter, err := client.Mgmt(ctx, db, kql.New(".show table ").AddTable(tableName).AddLiteral(" ingestion mappings"))
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	var out []NamedMapping
+	err = iter.DoOnRowOrError(func(row *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		var rec struct {
+			Name    string
+			Kind    string
+			Mapping string
+			Table   string
+		}
+		if err := row.ToStruct(&rec); err != nil {
+			return err
+		}
+		kind, err := mappingKindFromString(rec.Kind)
+		if err != nil {
+			return err
+		}
+		out = append(out, NamedMapping{Name: rec.Name, Kind: kind, Table: rec.Table, Mapping: rec.Mapping})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DropMapping drops the ingestion mapping name of kind mappingKind of table tableName.
+func DropMapping(ctx context.Context, client QueryClient, db, tableName, name string, mappingKind DataFormat) error {
+	if !mappingKind.IsValidMappingKind() {
+		return errors.ES(errors.OpUnknown, errors.KClientArgs, "%v is not an ingestion mapping kind", mappingKind).SetNoRetry()
+	}
+	stmt := kql.New(".drop table ").AddTable(tableName).AddLiteral(" ingestion ").AddKeyword(mappingKind.String()).
+		AddLiteral(" mapping ").AddString(name)
+	return runMappingCommand(ctx, client, db, stmt)
+}
+
+func runMappingCommand(ctx context.Context, client QueryClient, db string, stmt *kql.Builder) error {
+	iter, err := client.Mgmt(ctx, db, stmt)
+	if err != nil {
+		return err
+	}
+	defer iter.Stop()
+	return iter.DoOnRowOrError(func(_ *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		return nil
+	})
+}
+
+// mappingKindFromString returns the mapping kind named s, as listed by ".show ingestion mappings".
+func mappingKindFromString(s string) (DataFormat, error) {
+	for d := AVRO; d <= SingleJSON; d++ {
+		if d.IsValidMappingKind() && strings.EqualFold(d.String(), s) {
+			return d, nil
+		}
+	}
+	return DFUnknown, errors.ES(errors.OpUnknown, errors.KInternal, "unknown ingestion mapping kind %q", s).SetNoRetry()
+}
@@ -0,0 +1,158 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/ingest/internal/properties"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalMapping(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		kind    DataFormat
+		columns []ColumnMapping
+		want    string
+		err     bool
+	}{
+		{
+			desc: "CSV",
+			kind: CSV,
+			columns: []ColumnMapping{
+				CsvColumnMapping{Column: "a", Ordinal: 0},
+				CsvColumnMapping{Column: "b", DataType: types.Long, Ordinal: 2},
+				CsvColumnMapping{Column: "c", ConstValue: "x"},
+				CsvColumnMapping{Column: "d", Transform: SourceLineNumber},
+			},
+			want: `[{"Column":"a","Properties":{"Ordinal":"0"}},{"Column":"b","DataType":"long","Properties":{"Ordinal":"2"}},` +
+				`{"Column":"c","Properties":{"ConstValue":"x"}},{"Column":"d","Properties":{"Transform":"SourceLineNumber"}}]`,
+		},
+		{
+			desc: "JSON",
+			kind: JSON,
+			columns: []ColumnMapping{
+				JsonColumnMapping{Column: "ts", Path: "$.ts", Transform: DateTimeFromUnixMilliseconds},
+				JsonColumnMapping{Column: "src", Transform: SourceLocation},
+			},
+			want: `[{"Column":"ts","Properties":{"Path":"$.ts","Transform":"DateTimeFromUnixMilliseconds"}},{"Column":"src","Properties":{"Transform":"SourceLocation"}}]`,
+		},
+		{
+			desc:    "Avro",
+			kind:    AVRO,
+			columns: []ColumnMapping{AvroColumnMapping{Column: "a", Path: "$.a"}},
+			want:    `[{"Column":"a","Properties":{"Path":"$.a"}}]`,
+		},
+		{
+			desc:    "Parquet",
+			kind:    Parquet,
+			columns: []ColumnMapping{ParquetColumnMapping{Column: "a", Path: "$.a", Transform: BytesAsBase64}},
+			want:    `[{"Column":"a","Properties":{"Path":"$.a","Transform":"BytesAsBase64"}}]`,
+		},
+		{
+			desc:    "W3C log",
+			kind:    W3CLogFile,
+			columns: []ColumnMapping{W3CLogColumnMapping{Column: "uri", Field: "cs-uri-stem"}},
+			want:    `[{"Column":"uri","Properties":{"Field":"cs-uri-stem"}}]`,
+		},
+		{desc: "Not a mapping kind", kind: TSV, columns: []ColumnMapping{CsvColumnMapping{Column: "a"}}, err: true},
+		{desc: "No columns", kind: CSV, err: true},
+		{desc: "Wrong kind", kind: CSV, columns: []ColumnMapping{JsonColumnMapping{Column: "a", Path: "$.a"}}, err: true},
+		{desc: "No column name", kind: JSON, columns: []ColumnMapping{JsonColumnMapping{Path: "$.a"}}, err: true},
+		{desc: "No source", kind: JSON, columns: []ColumnMapping{JsonColumnMapping{Column: "a"}}, err: true},
+		{desc: "Bad path", kind: JSON, columns: []ColumnMapping{JsonColumnMapping{Column: "a", Path: "a"}}, err: true},
+		{desc: "Path and const", kind: JSON, columns: []ColumnMapping{JsonColumnMapping{Column: "a", Path: "$.a", ConstValue: "x"}}, err: true},
+		{desc: "Unknown transform", kind: JSON, columns: []ColumnMapping{JsonColumnMapping{Column: "a", Path: "$.a", Transform: "Upper"}}, err: true},
+		{desc: "Bad data type", kind: JSON, columns: []ColumnMapping{JsonColumnMapping{Column: "a", Path: "$.a", DataType: "number"}}, err: true},
+		{desc: "CSV transform", kind: CSV, columns: []ColumnMapping{CsvColumnMapping{Column: "a", Transform: DateTimeFromUnixSeconds}}, err: true},
+		{desc: "Negative ordinal", kind: CSV, columns: []ColumnMapping{CsvColumnMapping{Column: "a", Ordinal: -1}}, err: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			got, err := MarshalMapping(test.kind, test.columns...)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, test.want, got)
+		})
+	}
+}
+
+func TestIngestionMappingTyped(t *testing.T) {
+	t.Parallel()
+
+	p := &properties.All{}
+	err := IngestionMapping([]ColumnMapping{JsonColumnMapping{Column: "a", Path: "$.a"}}, JSON).Run(p, QueuedClient, FromFile)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"Column":"a","Properties":{"Path":"$.a"}}]`, p.Ingestion.Additional.IngestionMapping)
+	assert.Equal(t, JSON, p.Ingestion.Additional.IngestionMappingType)
+
+	err = IngestionMapping([]ColumnMapping{JsonColumnMapping{Column: "a", Path: "$.a"}}, CSV).Run(p, QueuedClient, FromFile)
+	assert.Error(t, err)
+}
+
+func TestMappingCommands(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	client := mockClient{
+		onMgmt: func(ctx context.Context, db string, query kusto.Statement, options ...kusto.MgmtOption) (*kusto.RowIterator, error) {
+			got = append(got, query.String())
+
+			rows, err := kusto.NewMockRows(table.Columns{
+				{Name: "Name", Type: types.String},
+				{Name: "Kind", Type: types.String},
+				{Name: "Mapping", Type: types.String},
+				{Name: "LastUpdatedOn", Type: types.DateTime},
+				{Name: "Database", Type: types.String},
+				{Name: "Table", Type: types.String},
+			})
+			if err != nil {
+				return nil, err
+			}
+			err = rows.Row(value.Values{
+				value.String{Value: "m", Valid: true},
+				value.String{Value: "Json", Valid: true},
+				value.String{Value: `[{"Column":"a","Properties":{"Path":"$.a"}}]`, Valid: true},
+				value.DateTime{},
+				value.String{Value: "db", Valid: true},
+				value.String{Value: "my-table", Valid: true},
+			})
+			if err != nil {
+				return nil, err
+			}
+			iter := &kusto.RowIterator{}
+			return iter, iter.Mock(rows)
+		},
+	}
+
+	ctx := context.Background()
+	err := CreateMapping(ctx, client, "db", "my-table", "m", JSON, JsonColumnMapping{Column: "a", Path: "$.a"})
+	require.NoError(t, err)
+
+	mappings, err := ListMappings(ctx, client, "db", "my-table")
+	require.NoError(t, err)
+	assert.Equal(t, []NamedMapping{{Name: "m", Kind: JSON, Table: "my-table", Mapping: `[{"Column":"a","Properties":{"Path":"$.a"}}]`}}, mappings)
+
+	require.NoError(t, DropMapping(ctx, client, "db", "my-table", "m", JSON))
+	assert.Error(t, DropMapping(ctx, client, "db", "my-table", "m", TSV))
+
+	assert.Equal(t, []string{
+		`.create-or-alter table ["my-table"] ingestion json mapping "m" ` + kql.QuoteString(`[{"Column":"a","Properties":{"Path":"$.a"}}]`, false),
+		`.show table ["my-table"] ingestion mappings`,
+		`.drop table ["my-table"] ingestion json mapping "m"`,
+	}, got)
+}
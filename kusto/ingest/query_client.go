@@ -17,3 +17,43 @@ type QueryClient interface {
 	HttpClient() *http.Client
 	ClientDetails() *kusto.ClientDetails
 }
+
+// connOptioner is implemented by clients, such as *kusto.Client, that can supply settings for the Conns created on their behalf.
+type connOptioner interface {
+	ConnOptions() []kusto.ConnOption
+}
+
+// connOptions returns the kusto.ConnOptions for client, if it provides any.
+func connOptions(client QueryClient) []kusto.ConnOption {
+	if c, ok := client.(connOptioner); ok {
+		return c.ConnOptions()
+	}
+	return nil
+}
+
+// emulatorClient is implemented by clients, such as *kusto.Client, that know whether they talk to a local Kusto emulator.
+type emulatorClient interface {
+	IsEmulator() bool
+}
+
+// isEmulator returns true if client talks to a local Kusto emulator, which has no queued ingestion resources.
+func isEmulator(client QueryClient) bool {
+	if c, ok := client.(emulatorClient); ok {
+		return c.IsEmulator()
+	}
+	return false
+}
+
+// ingestionURIClient is implemented by clients, such as *kusto.Client, that know their data management endpoint.
+// Their Endpoint() is always the engine endpoint.
+type ingestionURIClient interface {
+	IngestionURI() string
+}
+
+// engineEndpoint returns the engine endpoint of client, used for streaming ingestion.
+func engineEndpoint(client QueryClient) string {
+	if _, ok := client.(ingestionURIClient); ok {
+		return client.Endpoint()
+	}
+	return removeIngestPrefix(client.Endpoint())
+}
@@ -39,7 +39,7 @@ type blobUri struct {
 // More information can be found here:
 // https://docs.microsoft.com/en-us/azure/kusto/management/create-ingestion-mapping-command
 func NewStreaming(client QueryClient, db, table string) (*Streaming, error) {
-	streamConn, err := kusto.NewConn(removeIngestPrefix(client.Endpoint()), client.Auth(), client.HttpClient(), client.ClientDetails())
+	streamConn, err := kusto.NewConn(engineEndpoint(client), client.Auth(), client.HttpClient(), client.ClientDetails(), connOptions(client)...)
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +34,8 @@ type ConnectionStringBuilder struct {
 	ApplicationForTracing            string
 	UserForTracing                   string
 	TokenCredential                  azcore.TokenCredential
+	NoAuth                           bool
+	IngestionURI                     string
 }
 
 const (
@@ -168,6 +170,7 @@ func (kcsb *ConnectionStringBuilder) resetConnectionString() {
 	kcsb.ClientOptions = nil
 	kcsb.DefaultAuth = false
 	kcsb.TokenCredential = nil
+	kcsb.NoAuth = false
 }
 
 // WithAadUserPassAuth Creates a Kusto Connection string builder that will authenticate with AAD user name and password.
@@ -303,6 +306,24 @@ func (kcsb *ConnectionStringBuilder) WithTokenCredential(tokenCredential azcore.
 	return kcsb
 }
 
+// WithIngestionURI Sets the data management (ingestion) endpoint explicitly, instead of deriving it by adding "ingest-"
+// to the DataSource host. This is needed behind private link, custom DNS or reverse proxies.
+func (kcsb *ConnectionStringBuilder) WithIngestionURI(uri string) *ConnectionStringBuilder {
+	requireNonEmpty(dataSource, kcsb.DataSource)
+	kcsb.IngestionURI = uri
+	return kcsb
+}
+
+// WithNoAuth Creates a Kusto Connection string builder that sends no credentials, for use with the local Kusto emulator.
+// Clients built from it allow plain http endpoints such as http://localhost:8080, skip cloud metadata discovery and
+// trusted endpoint validation, and ingest without queued ingestion resources.
+func (kcsb *ConnectionStringBuilder) WithNoAuth() *ConnectionStringBuilder {
+	requireNonEmpty(dataSource, kcsb.DataSource)
+	kcsb.resetConnectionString()
+	kcsb.NoAuth = true
+	return kcsb
+}
+
 // Method to be used for generating TokenCredential
 func (kcsb *ConnectionStringBuilder) newTokenProvider() (*TokenProvider, error) {
 	tkp := &TokenProvider{}
@@ -121,6 +121,25 @@ func TestWithWorkloadIdentity(t *testing.T) {
 	assert.EqualValues(t, want, *actual)
 }
 
+func TestWithNoAuth(t *testing.T) {
+	want := ConnectionStringBuilder{
+		DataSource: "http://localhost:8080",
+		NoAuth:     true,
+	}
+
+	actual := NewConnectionStringBuilder("http://localhost:8080").WithAadAppKey("clientID", "key", "authorityID").WithNoAuth()
+	assert.EqualValues(t, want, *actual)
+
+	client, err := New(actual)
+	assert.NoError(t, err)
+	assert.True(t, client.IsEmulator())
+	assert.False(t, client.auth.TokenProvider.AuthorizationRequired())
+
+	// No metadata discovery or trusted endpoint check, which would fail for localhost:8080.
+	conn := client.conn.(*Conn)
+	assert.NoError(t, conn.validateEndpoint())
+}
+
 func TestWitAadUserTokenErr(t *testing.T) {
 	defer func() {
 		if res := recover(); res == nil {
@@ -215,3 +234,87 @@ func TestGetTokenProviderHappy(t *testing.T) {
 	}
 
 }
+
+func TestIngestionURI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		kcsb     *ConnectionStringBuilder
+		options  []Option
+		want     string
+		wantErrs bool
+	}{
+		{
+			desc: "Derived",
+			kcsb: NewConnectionStringBuilder("https://cluster.kusto.windows.net"),
+			want: "https://ingest-cluster.kusto.windows.net",
+		},
+		{
+			desc: "Kcsb",
+			kcsb: NewConnectionStringBuilder("https://cluster.contoso.com").WithIngestionURI("https://dm.contoso.com"),
+			want: "https://dm.contoso.com",
+		},
+		{
+			desc:    "OptionOverridesKcsb",
+			kcsb:    NewConnectionStringBuilder("https://cluster.contoso.com").WithIngestionURI("https://dm.contoso.com"),
+			options: []Option{WithIngestionURI("https://proxy.contoso.com/dm")},
+			want:    "https://proxy.contoso.com/dm",
+		},
+		{
+			desc:    "EngineStartsWithIngest",
+			kcsb:    NewConnectionStringBuilder("https://ingest-cluster.contoso.com"),
+			options: []Option{WithIngestionURI("https://dm.contoso.com")},
+			want:    "https://dm.contoso.com",
+		},
+		{
+			desc:     "IngestEndpointAsEngine",
+			kcsb:     NewConnectionStringBuilder("https://ingest-cluster.kusto.windows.net"),
+			wantErrs: true,
+		},
+		{
+			desc:     "SchemeMismatch",
+			kcsb:     NewConnectionStringBuilder("https://cluster.contoso.com").WithIngestionURI("http://dm.contoso.com"),
+			wantErrs: true,
+		},
+		{
+			desc:     "SameAsEngine",
+			kcsb:     NewConnectionStringBuilder("https://cluster.contoso.com").WithIngestionURI("https://Cluster.contoso.com/"),
+			wantErrs: true,
+		},
+		{
+			desc:     "Swapped",
+			kcsb:     NewConnectionStringBuilder("https://ingest-cluster.kusto.windows.net").WithIngestionURI("https://cluster.kusto.windows.net"),
+			wantErrs: true,
+		},
+		{
+			desc: "SameHostOtherPath",
+			kcsb: NewConnectionStringBuilder("https://proxy.contoso.com/engine").WithIngestionURI("https://proxy.contoso.com/dm"),
+			want: "https://proxy.contoso.com/dm",
+		},
+		{
+			desc:     "NotAbsolute",
+			kcsb:     NewConnectionStringBuilder("https://cluster.contoso.com").WithIngestionURI("dm.contoso.com"),
+			wantErrs: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := New(test.kcsb, test.options...)
+			if test.wantErrs {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, client.IngestionURI())
+
+			conn, err := client.getConn(mgmtCall, connOptions{queryOptions: &queryOptions{requestProperties: &requestProperties{}, queryIngestion: true}})
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, conn.(*Conn).endpoint)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/kql/lexer"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"strings"
@@ -22,6 +23,8 @@ func (s stringConstant) String() string {
 
 type Builder struct {
 	builder strings.Builder
+	// unsafe are the [start, end) offsets of the fragments added with AddUnsafe.
+	unsafe [][2]int
 }
 
 func New(value stringConstant) *Builder {
@@ -31,7 +34,10 @@ func New(value stringConstant) *Builder {
 }
 
 func FromBuilder(builder *Builder) *Builder {
-	return New(stringConstant(builder.String()))
+	b := New(stringConstant(builder.String()))
+	// Keep the fragments added with AddUnsafe, so that CheckUnsafe still checks them.
+	b.unsafe = append([][2]int(nil), builder.unsafe...)
+	return b
 }
 
 // String implements fmt.Stringer.
@@ -47,10 +53,29 @@ func (b *Builder) addBase(value fmt.Stringer) *Builder {
 // This turns off safety features that could allow a service client to compromise your data store.
 // USE AT YOUR OWN RISK!
 func (b *Builder) AddUnsafe(value string) *Builder {
+	start := b.builder.Len()
 	b.builder.WriteString(value)
+	b.unsafe = append(b.unsafe, [2]int{start, b.builder.Len()})
 	return b
 }
 
+// CheckUnsafe returns an error if a fragment added with AddUnsafe introduces new tokens into the query, such as a
+// value that closes a string literal and continues the query. A fragment that is a single token, such as a name or a
+// number, or that stays inside a string literal, passes. It also returns an error if the query has an unclosed string.
+func (b *Builder) CheckUnsafe() error {
+	text := b.String()
+	for _, span := range b.unsafe {
+		tokens, err := lexer.Introduces(text, span[0], span[1])
+		if err != nil {
+			return err
+		}
+		if len(tokens) > 0 {
+			return fmt.Errorf("the unsafe fragment %q introduces %d new tokens, starting with %q", text[span[0]:span[1]], len(tokens), tokens[0].Text)
+		}
+	}
+	return nil
+}
+
 func (b *Builder) AddLiteral(value stringConstant) *Builder {
 	return b.addBase(value)
 }
@@ -105,4 +130,5 @@ func (b *Builder) SupportsInlineParameters() bool {
 // Reset resets the stringBuilder
 func (b *Builder) Reset() {
 	b.builder.Reset()
+	b.unsafe = nil
 }
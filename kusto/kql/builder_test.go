@@ -1,6 +1,7 @@
 package kql
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -45,6 +46,16 @@ func TestBuilder(t *testing.T) {
 			New("MyTable | where i != ").AddReal(32.5),
 			"MyTable | where i != real(32.5)",
 		},
+		{
+			"Test add real NaN",
+			New("MyTable | where i != ").AddReal(math.NaN()),
+			"MyTable | where i != real(nan)",
+		},
+		{
+			"Test add real infinity",
+			New("MyTable | where i != ").AddReal(math.Inf(1)),
+			"MyTable | where i != real(+inf)",
+		},
 		{
 			"Test add bool",
 			New("MyTable | where i != ").AddBool(true),
@@ -71,6 +82,13 @@ func TestBuilder(t *testing.T) {
 			).AddTimespan(49*time.Hour + 2*time.Minute + 3*time.Second + 4*time.Microsecond),
 			"MyTable | where i != timespan(2.01:02:03.0000040)",
 		},
+		{
+			"Test add negative duration",
+			New(
+				"MyTable | where i != ",
+			).AddTimespan(-(49*time.Hour + 2*time.Minute + 3*time.Second + 4*time.Microsecond)),
+			"MyTable | where i != timespan(-2.01:02:03.0000040)",
+		},
 		{
 			"Test add dynamic",
 			New(
@@ -99,6 +117,13 @@ func TestBuilder(t *testing.T) {
 			).AddString("foo\"bar"),
 			"MyTable | where i != \"foo\\\"bar\"",
 		},
+		{
+			"Test add empty string",
+			New(
+				"MyTable | where i != ",
+			).AddString(""),
+			"MyTable | where i != \"\"",
+		},
 		{
 			"Test add keyword",
 			New(
@@ -130,3 +155,37 @@ func TestBuilder(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckUnsafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       *Builder
+		wantErr bool
+	}{
+		{"No unsafe", New("T | take 1"), false},
+		{"Name", New("T | project ").AddUnsafe("Name"), false},
+		{"Inside string", New(`T | where a == "`).AddUnsafe("x y").AddLiteral(`"`), false},
+		{"Closes string", New(`T | where a == "`).AddUnsafe(`x" or 1==1 //`).AddLiteral(`"`), true},
+		{"New operator", New("T | take ").AddUnsafe("1 | count"), true},
+		{"Unclosed string", New("T | where a == ").AddUnsafe(`"x`), true},
+		{"Copied", FromBuilder(New("T | take ").AddUnsafe("1 | count")).AddLiteral(" | take 1"), true},
+		{"Copied then added", FromBuilder(New("T | take 1")).AddLiteral(" | project ").AddUnsafe("a, b"), true},
+		{"Reset", func() *Builder {
+			b := New("T | take ").AddUnsafe("1 | count")
+			b.Reset()
+			return b.AddLiteral("T")
+		}(), false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := test.b.CheckUnsafe()
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
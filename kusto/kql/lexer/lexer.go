@@ -0,0 +1,272 @@
+// Package lexer tokenizes KQL queries and management commands, for validation before they are sent to the service.
+//
+// The lexer knows the lexical structure of KQL (string literals in all their quoting forms, comments, numbers and
+// operators), not its grammar. Tokenize() reports unclosed string literals, and Parse() also reports unbalanced
+// brackets and splits the text into statements, such as let, declare and set statements, queries and commands.
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is the kind of a Token.
+type Kind int
+
+const (
+	// Identifier is a name, such as a table, column or function name, or a keyword. Quoted names, such as ['my-x'], are
+	// an Operator, a String and an Operator.
+	Identifier Kind = iota
+	// String is a string literal in any of its forms: "x", 'x', @"x", h"x", h@"x" or ```x```.
+	String
+	// Number is a numeric literal, with its suffix, such as 10, 1.5e3, 0x1F or 10ms.
+	Number
+	// Operator is an operator or punctuation, such as |, ==, <| or (.
+	Operator
+	// Comment is a comment, from // to the end of the line.
+	Comment
+	// Command is the name of a management command, with its leading dot, such as .show or .create-or-alter.
+	Command
+)
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	switch k {
+	case Identifier:
+		return "Identifier"
+	case String:
+		return "String"
+	case Number:
+		return "Number"
+	case Operator:
+		return "Operator"
+	case Comment:
+		return "Comment"
+	case Command:
+		return "Command"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Token is a token of KQL text.
+type Token struct {
+	Kind Kind
+	// Text is the token as it appears in the source, including quotes.
+	Text string
+	// Offset is the byte offset of the token in the source.
+	Offset int
+}
+
+// End is the byte offset just past the token in the source.
+func (t Token) End() int {
+	return t.Offset + len(t.Text)
+}
+
+// Value is the value of a String token, without quotes and escapes, and the text of other tokens.
+func (t Token) Value() string {
+	if t.Kind != String {
+		return t.Text
+	}
+
+	s := strings.TrimLeft(t.Text, "hH")
+	if strings.HasPrefix(s, "```") {
+		return s[3 : len(s)-3]
+	}
+	if s[0] == '@' {
+		quote := s[1:2]
+		return strings.ReplaceAll(s[2:len(s)-1], quote+quote, quote)
+	}
+
+	s = s[1 : len(s)-1]
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// Error is a lexical error in KQL text.
+type Error struct {
+	// Offset is the byte offset of the error in the source.
+	Offset int
+	Msg    string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Msg)
+}
+
+// operators are the operators longer than one character, longest first.
+var operators = []string{"<|", "==", "!=", "<>", "<=", ">=", "=~", "!~", "=>", ".."}
+
+// Tokenize splits text into tokens, without whitespace. It fails with an *Error if a string literal is not closed.
+func Tokenize(text string) ([]Token, error) {
+	var tokens []Token
+	// statementStart is true when the next token starts a statement, which is where commands start.
+	statementStart := true
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		start := i
+		kind := Operator
+		switch {
+		case strings.HasPrefix(text[i:], "//"):
+			kind = Comment
+			if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(text)
+			}
+		case isStringStart(text[i:]):
+			kind = String
+			end, err := stringEnd(text, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case r == '.' && statementStart && i+1 < len(text) && isLetter(rune(text[i+1])):
+			kind = Command
+			i++
+			for i < len(text) && (isIdentifierPart(rune(text[i])) || text[i] == '-') {
+				i++
+			}
+		case r >= '0' && r <= '9':
+			kind = Number
+			i = numberEnd(text, i)
+		case isLetter(r) || r == '_' || r == '$':
+			kind = Identifier
+			i += size
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if !isIdentifierPart(r) {
+					break
+				}
+				i += size
+			}
+		default:
+			i += size
+			for _, op := range operators {
+				if strings.HasPrefix(text[start:], op) {
+					i = start + len(op)
+					break
+				}
+			}
+		}
+
+		tok := Token{Kind: kind, Text: text[start:i], Offset: start}
+		tokens = append(tokens, tok)
+		if kind != Comment {
+			statementStart = tok.Text == ";"
+		}
+	}
+	return tokens, nil
+}
+
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r)
+}
+
+func isIdentifierPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// isStringStart returns true if s starts with a string literal, which may have h, H and @ prefixes.
+func isStringStart(s string) bool {
+	if len(s) > 0 && (s[0] == 'h' || s[0] == 'H') {
+		s = s[1:]
+	}
+	if len(s) > 0 && s[0] == '@' {
+		s = s[1:]
+	}
+	return len(s) > 0 && (s[0] == '"' || s[0] == '\'' || strings.HasPrefix(s, "```"))
+}
+
+// stringEnd returns the offset just past the string literal that starts at offset start of text.
+func stringEnd(text string, start int) (int, error) {
+	i := start
+	if text[i] == 'h' || text[i] == 'H' {
+		i++
+	}
+	verbatim := text[i] == '@'
+	if verbatim {
+		i++
+	}
+
+	if strings.HasPrefix(text[i:], "```") {
+		end := strings.Index(text[i+3:], "```")
+		if end < 0 {
+			return 0, &Error{Offset: start, Msg: "the multi-line string literal is not closed"}
+		}
+		return i + 3 + end + 3, nil
+	}
+
+	quote := text[i]
+	for j := i + 1; j < len(text); j++ {
+		switch {
+		case text[j] == '\n':
+			return 0, &Error{Offset: start, Msg: "the string literal is not closed before the end of the line"}
+		case !verbatim && text[j] == '\\':
+			j++
+		case text[j] == quote:
+			if verbatim && j+1 < len(text) && text[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, nil
+		}
+	}
+	return 0, &Error{Offset: start, Msg: "the string literal is not closed"}
+}
+
+// numberEnd returns the offset just past the number that starts at offset start of text.
+func numberEnd(text string, start int) int {
+	i := start
+	digits := func() {
+		for i < len(text) && text[i] >= '0' && text[i] <= '9' {
+			i++
+		}
+	}
+
+	digits()
+	if i+1 < len(text) && text[i] == '.' && text[i+1] >= '0' && text[i+1] <= '9' {
+		i++
+		digits()
+	}
+	if i+1 < len(text) && (text[i] == 'e' || text[i] == 'E') {
+		j := i + 1
+		if text[j] == '+' || text[j] == '-' {
+			j++
+		}
+		if j < len(text) && text[j] >= '0' && text[j] <= '9' {
+			i = j
+			digits()
+		}
+	}
+	// Suffixes, such as the units of timespans (10ms, 1d) and hexadecimal digits (0x1F).
+	for i < len(text) && isIdentifierPart(rune(text[i])) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,113 @@
+package lexer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []Token
+	}{
+		{
+			name: "Query",
+			text: "T | where a >= 1.5e3 and b != 10ms",
+			expected: []Token{
+				{Identifier, "T", 0}, {Operator, "|", 2}, {Identifier, "where", 4}, {Identifier, "a", 10},
+				{Operator, ">=", 12}, {Number, "1.5e3", 15}, {Identifier, "and", 21}, {Identifier, "b", 25},
+				{Operator, "!=", 27}, {Number, "10ms", 30},
+			},
+		},
+		{
+			name: "Strings",
+			text: `"a\"b" 'c' @"d""e" @'f' h"g" H@'h' ` + "```i\n\"j```",
+			expected: []Token{
+				{String, `"a\"b"`, 0}, {String, "'c'", 7}, {String, `@"d""e"`, 11}, {String, "@'f'", 19},
+				{String, `h"g"`, 24}, {String, "H@'h'", 29}, {String, "```i\n\"j```", 35},
+			},
+		},
+		{
+			name: "Comments",
+			text: "T // a \"comment\n| take 1",
+			expected: []Token{
+				{Identifier, "T", 0}, {Comment, "// a \"comment", 2}, {Operator, "|", 16}, {Identifier, "take", 18},
+				{Number, "1", 23},
+			},
+		},
+		{
+			name: "Command",
+			text: "// c\n.create-or-alter table T <| U | extend x = a.b",
+			expected: []Token{
+				{Comment, "// c", 0}, {Command, ".create-or-alter", 5}, {Identifier, "table", 22}, {Identifier, "T", 28},
+				{Operator, "<|", 30}, {Identifier, "U", 33}, {Operator, "|", 35}, {Identifier, "extend", 37},
+				{Identifier, "x", 44}, {Operator, "=", 46}, {Identifier, "a", 48}, {Operator, ".", 49}, {Identifier, "b", 50},
+			},
+		},
+		{
+			name: "Quoted name and hex",
+			text: `['my-x'] | take 0x1F`,
+			expected: []Token{
+				{Operator, "[", 0}, {String, "'my-x'", 1}, {Operator, "]", 7}, {Operator, "|", 9}, {Identifier, "take", 11},
+				{Number, "0x1F", 16},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Tokenize(test.text)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		offset int
+	}{
+		{`T | where a == "x`, 15},
+		{`T | where a == 'x\'`, 15},
+		{`T | where a == @"x""`, 15},
+		{"T | where a == h'x\n'", 15},
+		{"print ```x", 6},
+	}
+
+	for _, test := range tests {
+		_, err := Tokenize(test.text)
+		var lexErr *Error
+		require.True(t, errors.As(err, &lexErr), test.text)
+		assert.Equal(t, test.offset, lexErr.Offset, test.text)
+	}
+}
+
+func TestTokenValue(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		`"a\"b\\c\n"`: "a\"b\\c\n",
+		`'a\'b'`:      "a'b",
+		`@"c:\x"""`:   `c:\x"`,
+		`h'secret'`:   "secret",
+		`H@'a''b'`:    "a'b",
+		"```a\nb```":  "a\nb",
+	}
+	for text, expected := range tests {
+		tokens, err := Tokenize(text)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.Equal(t, expected, tokens[0].Value(), text)
+	}
+}
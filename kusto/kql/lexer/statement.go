@@ -0,0 +1,438 @@
+package lexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StatementKind is the kind of a Statement.
+type StatementKind int
+
+const (
+	// QueryStatement is a tabular expression statement, such as T | take 10.
+	QueryStatement StatementKind = iota
+	// CommandStatement is a management command, such as .show tables.
+	CommandStatement
+	// LetStatement is a let statement, such as let n = 10.
+	LetStatement
+	// DeclareStatement is a declare statement, such as declare query_parameters(n:long).
+	DeclareStatement
+	// SetStatement is a set statement, such as set notruncation.
+	SetStatement
+	// OtherStatement is any other statement, such as an alias or pattern statement.
+	OtherStatement
+)
+
+// String implements fmt.Stringer.
+func (k StatementKind) String() string {
+	switch k {
+	case QueryStatement:
+		return "QueryStatement"
+	case CommandStatement:
+		return "CommandStatement"
+	case LetStatement:
+		return "LetStatement"
+	case DeclareStatement:
+		return "DeclareStatement"
+	case SetStatement:
+		return "SetStatement"
+	case OtherStatement:
+		return "OtherStatement"
+	}
+	return fmt.Sprintf("StatementKind(%d)", int(k))
+}
+
+// Statement is a statement of KQL text.
+type Statement struct {
+	Kind StatementKind
+	// Tokens are the tokens of the statement, without comments and the terminating semicolon.
+	Tokens []Token
+}
+
+var brackets = map[string]string{")": "(", "]": "[", "}": "{"}
+
+// Parse tokenizes text and splits it into statements. A management command is always the last statement, as the
+// semicolons that follow it belong to its query, such as in .set T <| let n = 1; U.
+// It fails with an *Error if a string literal is not closed or if brackets are not balanced.
+func Parse(text string) ([]Statement, error) {
+	tokens, err := Tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var open []Token
+	var statements []Statement
+	var current []Token
+	command := false
+	for _, tok := range tokens {
+		if tok.Kind == Comment {
+			continue
+		}
+		if tok.Kind == Operator {
+			switch tok.Text {
+			case "(", "[", "{":
+				open = append(open, tok)
+			case ")", "]", "}":
+				if len(open) == 0 || open[len(open)-1].Text != brackets[tok.Text] {
+					return nil, &Error{Offset: tok.Offset, Msg: fmt.Sprintf("%q is not opened", tok.Text)}
+				}
+				open = open[:len(open)-1]
+			case ";":
+				if len(open) == 0 && !command {
+					if len(current) > 0 {
+						statements = append(statements, newStatement(current))
+					}
+					current = nil
+					continue
+				}
+			}
+		}
+		if len(current) == 0 && tok.Kind == Command {
+			command = true
+		}
+		current = append(current, tok)
+	}
+	if len(open) > 0 {
+		tok := open[len(open)-1]
+		return nil, &Error{Offset: tok.Offset, Msg: fmt.Sprintf("%q is not closed", tok.Text)}
+	}
+	if len(current) > 0 {
+		statements = append(statements, newStatement(current))
+	}
+	return statements, nil
+}
+
+func newStatement(tokens []Token) Statement {
+	kind := QueryStatement
+	switch first := tokens[0]; {
+	case first.Kind == Command:
+		kind = CommandStatement
+	case first.Kind == Identifier && len(tokens) > 1 && tokens[1].Kind == Identifier:
+		switch first.Text {
+		case "let":
+			kind = LetStatement
+		case "declare":
+			kind = DeclareStatement
+		case "set":
+			kind = SetStatement
+		case "alias", "pattern", "restrict":
+			kind = OtherStatement
+		}
+	}
+	return Statement{Kind: kind, Tokens: tokens}
+}
+
+// IsCommand returns true if text is a management command, possibly after comments.
+func IsCommand(text string) (bool, error) {
+	statements, err := Parse(text)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range statements {
+		if s.Kind == CommandStatement {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Parameters returns the sorted names of the query parameters that text declares with declare query_parameters.
+func Parameters(text string) ([]string, error) {
+	statements, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, s := range statements {
+		t := s.Tokens
+		if s.Kind != DeclareStatement || len(t) < 3 || t[1].Text != "query_parameters" || t[2].Text != "(" {
+			continue
+		}
+		params, _, err := parameterList(t[2:])
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range params {
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Parameter is a parameter of a parameter list, such as n:long = 10 or T:(*).
+type Parameter struct {
+	Name string
+	// Type is the type of a scalar parameter, such as long. It is "" for a tabular parameter.
+	Type string
+	// HasDefault is true if the parameter has a default value.
+	HasDefault bool
+}
+
+// ParameterList parses a parenthesized parameter list, such as the parameters of a stored function as listed by
+// .show function: (T:(*), n:long, s:string = "a"). It fails with an *Error if text is not a parameter list.
+func ParameterList(text string) ([]Parameter, error) {
+	statements, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) != 1 {
+		return nil, &Error{Msg: "expected a single parameter list"}
+	}
+
+	t := statements[0].Tokens
+	params, end, err := parameterList(t)
+	if err != nil {
+		return nil, err
+	}
+	if end < len(t) {
+		return nil, &Error{Offset: t[end].Offset, Msg: fmt.Sprintf("unexpected %q after the parameter list", t[end].Text)}
+	}
+	return params, nil
+}
+
+// parameterList parses the parameter list that t starts with, whose brackets are balanced, and returns the index of
+// the token after it.
+func parameterList(t []Token) ([]Parameter, int, error) {
+	fail := func(i int, msg string) ([]Parameter, int, error) {
+		offset := 0
+		if i < len(t) {
+			offset = t[i].Offset
+		} else if len(t) > 0 {
+			offset = t[len(t)-1].End()
+		}
+		return nil, 0, &Error{Offset: offset, Msg: msg}
+	}
+	text := func(i int) string {
+		if i < len(t) {
+			return t[i].Text
+		}
+		return ""
+	}
+	// skip returns the index of the first "," or ")" after i that is not inside brackets.
+	skip := func(i int) int {
+		depth := 0
+		for ; i < len(t); i++ {
+			switch t[i].Text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth == 0 {
+					return i
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					return i
+				}
+			}
+		}
+		return i
+	}
+
+	if text(0) != "(" {
+		return fail(0, "expected a parameter list")
+	}
+	var params []Parameter
+	i := 1
+	if text(i) == ")" {
+		return params, i + 1, nil
+	}
+	for {
+		p := Parameter{Name: name(t[i:])}
+		if p.Name == "" {
+			return fail(i, "expected a parameter name")
+		}
+		i++
+		if t[i-1].Text == "[" {
+			i += 2
+		}
+		if text(i) != ":" {
+			return fail(i, fmt.Sprintf("expected the type of parameter %q", p.Name))
+		}
+		i++
+
+		switch {
+		case text(i) == "(":
+			// A tabular parameter, with its schema, such as (*) or (x:long, y:string).
+			i = skip(i + 1)
+			for text(i) == "," {
+				i = skip(i + 1)
+			}
+			if text(i) != ")" {
+				return fail(i, fmt.Sprintf("the schema of parameter %q is not closed", p.Name))
+			}
+			i++
+		case i < len(t) && t[i].Kind == Identifier:
+			p.Type = t[i].Text
+			i++
+		default:
+			return fail(i, fmt.Sprintf("expected the type of parameter %q", p.Name))
+		}
+		if text(i) == "=" {
+			p.HasDefault = true
+			i = skip(i + 1)
+		}
+		params = append(params, p)
+
+		switch text(i) {
+		case ",":
+			i++
+		case ")":
+			return params, i + 1, nil
+		default:
+			return fail(i, "expected \",\" or \")\" in the parameter list")
+		}
+	}
+}
+
+// Tables returns the sorted names of the tables that text refers to: the sources of its tabular expressions, the
+// operands of union, join and lookup, and the arguments of table(). Names defined by let statements are not tables.
+//
+// This is a lexical approximation: a function called without parentheses or a scalar let variable at the start of an
+// expression is reported as a table.
+func Tables(text string) ([]string, error) {
+	statements, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	lets := map[string]bool{}
+	for _, s := range statements {
+		t := s.Tokens
+		switch s.Kind {
+		case QueryStatement:
+			sources(t, found)
+		case LetStatement:
+			if len(t) > 3 && t[2].Text == "=" {
+				lets[t[1].Text] = true
+				sources(t[3:], found)
+			}
+		case CommandStatement:
+			for i, tok := range t {
+				if tok.Text == "<|" {
+					sources(t[i+1:], found)
+					break
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(found))
+	for n := range found {
+		if !lets[n] {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// nonTables are the keywords that start tabular expressions without a table.
+var nonTables = map[string]bool{
+	"print": true, "range": true, "datatable": true, "externaldata": true, "evaluate": true, "find": true,
+	"search": true, "union": true,
+}
+
+// sources adds the tables that the tabular expression t refers to to found.
+func sources(t []Token, found map[string]bool) {
+	add := func(t []Token) {
+		if n := name(t); n != "" && !nonTables[n] && !isCall(t, 0) {
+			found[n] = true
+		}
+	}
+
+	// opens are the indexes of the parentheses that open tabular expressions.
+	opens := map[int]bool{}
+	// start is true when the next token starts a tabular expression.
+	start := true
+	for i := 0; i < len(t); i++ {
+		tok := t[i]
+		if start {
+			start = tok.Text == "("
+			add(t[i:])
+		}
+		if opens[i] {
+			start = true
+		}
+
+		switch {
+		case tok.Kind == Identifier && (tok.Text == "union" || tok.Text == "join" || tok.Text == "lookup"):
+			// Skip the parameters, such as kind=inner or hint.strategy=shuffle.
+			j := i + 1
+			for {
+				k := j
+				for k+1 < len(t) && t[k].Kind == Identifier && t[k+1].Text == "." {
+					k += 2
+				}
+				if k+2 >= len(t) || t[k].Kind != Identifier || t[k+1].Text != "=" {
+					break
+				}
+				j = k + 3
+			}
+			if j < len(t) {
+				opens[j] = t[j].Text == "("
+				add(t[j:])
+			}
+			if tok.Text == "union" {
+				depth := 0
+				for k := j; k+1 < len(t) && (depth > 0 || t[k].Text != "|"); k++ {
+					switch t[k].Text {
+					case "(", "[", "{":
+						depth++
+					case ")", "]", "}":
+						depth--
+					}
+					if depth == 0 && t[k].Text == "," {
+						opens[k+1] = t[k+1].Text == "("
+						add(t[k+1:])
+					}
+				}
+			}
+		case tok.Text == "table" && i+3 < len(t) && t[i+1].Text == "(" && t[i+2].Kind == String:
+			found[t[i+2].Value()] = true
+		case tok.Text == "database" && i+5 < len(t) && t[i+1].Text == "(" && t[i+3].Text == ")" && t[i+4].Text == ".":
+			add(t[i+5:])
+		}
+	}
+}
+
+// isCall returns true if the identifier at t[i] is called as a function.
+func isCall(t []Token, i int) bool {
+	return t[i].Kind == Identifier && i+1 < len(t) && t[i+1].Text == "("
+}
+
+// name returns the name that t starts with: an identifier or a quoted name, such as ['my-x'], or "".
+func name(t []Token) string {
+	if len(t) > 0 && t[0].Kind == Identifier && !strings.HasPrefix(t[0].Text, "$") {
+		return t[0].Text
+	}
+	if len(t) > 2 && t[0].Text == "[" && t[1].Kind == String && t[2].Text == "]" {
+		return t[1].Value()
+	}
+	return ""
+}
+
+// Introduces returns the tokens that the fragment text[start:end] introduces: the tokens that overlap the fragment,
+// but the first one. A fragment that is a single token, or that is inside a single token such as a string literal,
+// introduces no tokens, while a fragment that closes a string literal and continues the query does.
+func Introduces(text string, start, end int) ([]Token, error) {
+	tokens, err := Tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var overlapping []Token
+	for _, tok := range tokens {
+		if tok.Offset < end && tok.End() > start {
+			overlapping = append(overlapping, tok)
+		}
+	}
+	if len(overlapping) <= 1 {
+		return nil, nil
+	}
+	return overlapping[1:], nil
+}
@@ -0,0 +1,143 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	statements, err := Parse("set notruncation;\ndeclare query_parameters(n:long);\nlet s = \"a;b\";\n" +
+		"T | where x in (1, 2) | take n;\n.set-or-append U <| let m = 1; V")
+	require.NoError(t, err)
+
+	var kinds []StatementKind
+	for _, s := range statements {
+		kinds = append(kinds, s.Kind)
+	}
+	assert.Equal(t, []StatementKind{SetStatement, DeclareStatement, LetStatement, QueryStatement, CommandStatement}, kinds)
+	assert.Equal(t, ".set-or-append", statements[4].Tokens[0].Text)
+	assert.Equal(t, "V", statements[4].Tokens[len(statements[4].Tokens)-1].Text)
+
+	for _, text := range []string{"T | where f(a", "T | where a)", "print dynamic([1)]", `T | where a == "x`} {
+		_, err := Parse(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestIsCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		".show tables":                   true,
+		"  // comment\n.drop table T":    true,
+		"set notruncation; .show tables": true,
+		"T | take 1":                     false,
+		"print '.show tables'":           false,
+		"T | extend x = a.b":             false,
+		"let x = 1; T | where y > x":     false,
+	}
+	for text, expected := range tests {
+		got, err := IsCommand(text)
+		require.NoError(t, err)
+		assert.Equal(t, expected, got, text)
+	}
+
+	_, err := IsCommand(`.show table "T`)
+	assert.Error(t, err)
+}
+
+func TestParameters(t *testing.T) {
+	t.Parallel()
+
+	got, err := Parameters("declare query_parameters(n:long, s:string = 'a,b', ['my-p']:dynamic = dynamic([1, 2]));\nT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"my-p", "n", "s"}, got)
+
+	got, err = Parameters("T | take 1")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestTables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"T | take 1", []string{"T"}},
+		{"['my-table'] | count", []string{"my-table"}},
+		{"let Recent = T | where x > 1;\nRecent | join kind=inner (U | take 1) on Id", []string{"T", "U"}},
+		{"T | join hint.strategy=shuffle V on Id | lookup (W) on Id", []string{"T", "V", "W"}},
+		{"union withsource=Source A, (B | take 1), ['C']", []string{"A", "B", "C"}},
+		{"table('X') | take 1", []string{"X"}},
+		{"database('db').T | count", []string{"T"}},
+		{"print now()", nil},
+		{"f(1) | take 1", nil},
+		{".set-or-append T <| U | where a == 'b'", []string{"U"}},
+	}
+
+	for _, test := range tests {
+		got, err := Tables(test.text)
+		require.NoError(t, err)
+		if test.expected == nil {
+			assert.Empty(t, got, test.text)
+		} else {
+			assert.Equal(t, test.expected, got, test.text)
+		}
+	}
+}
+
+func TestIntroduces(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text       string
+		start, end int
+		expected   []string
+	}{
+		{`T | where a == "x"`, 15, 18, nil},
+		{`T | where a == "x"`, 16, 17, nil},
+		{`T | where a == "x" or 1==1 //"`, 16, 29, []string{"or", "1", "==", "1", `//"`}},
+		{"T | take 10", 9, 11, nil},
+		{"T | take 10 | count", 9, 19, []string{"|", "count"}},
+	}
+
+	for _, test := range tests {
+		got, err := Introduces(test.text, test.start, test.end)
+		require.NoError(t, err)
+		var texts []string
+		for _, tok := range got {
+			texts = append(texts, tok.Text)
+		}
+		assert.Equal(t, test.expected, texts, test.text)
+	}
+}
+
+func TestParameterList(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParameterList("(T:(*), U:(x:long, ['y-z']:string), n:long, ['my-p']:dynamic = dynamic([1, 2]), s:string = \"a,b\")")
+	require.NoError(t, err)
+	assert.Equal(t, []Parameter{
+		{Name: "T"},
+		{Name: "U"},
+		{Name: "n", Type: "long"},
+		{Name: "my-p", Type: "dynamic", HasDefault: true},
+		{Name: "s", Type: "string", HasDefault: true},
+	}, got)
+
+	got, err = ParameterList("()")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	for _, text := range []string{"", "n:long", "(n)", "(n:long", "(n:long) | take 1", "(n:long; m:long)", "(:long)", "(n:long m:long)"} {
+		_, err := ParameterList(text)
+		var lexErr *Error
+		assert.ErrorAs(t, err, &lexErr, text)
+	}
+}
@@ -0,0 +1,620 @@
+package kql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// MaxDatatableLength is the size, in bytes, of the largest literal that AddDatatable() and AddDatatableFromStructs()
+// render. Larger client-side data should be ingested into a table instead.
+const MaxDatatableLength = 1 << 20
+
+// AddList adds values as a parenthesized list, such as ("a", "b"), for the in and !in operators.
+// The values must all have the same Kusto type, where int and long count as the same type. See AddDatatableFromStructs()
+// for how Go types map to Kusto types.
+func (b *Builder) AddList(values ...interface{}) (*Builder, error) {
+	text, err := listOf(values)
+	if err != nil {
+		return b, fmt.Errorf("AddList: %w", err)
+	}
+	return b.addBase(stringConstant(text)), nil
+}
+
+// LiteralOf returns v as a KQL literal, such as datetime(2023-01-02T03:04:05Z), so that values from the rows of a query
+// can be used in another. A value that is not Valid is the null of its type, such as long(null), except for strings,
+// which cannot be null and are "". It fails if v is nil or a value.Decimal that is not a number.
+func LiteralOf(v value.Kusto) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("LiteralOf: nil has no Kusto type")
+	}
+	val, err := kustoValue(v)
+	if err != nil {
+		return "", fmt.Errorf("LiteralOf: %w", err)
+	}
+	return val.String(), nil
+}
+
+// AddValue adds v as a KQL literal, as LiteralOf() renders it.
+func (b *Builder) AddValue(v value.Kusto) (*Builder, error) {
+	if v == nil {
+		return b, fmt.Errorf("AddValue: nil has no Kusto type")
+	}
+	val, err := kustoValue(v)
+	if err != nil {
+		return b, fmt.Errorf("AddValue: %w", err)
+	}
+	return b.addBase(val), nil
+}
+
+// AddValueAs adds v as a literal of Kusto type t, such as the argument of a function parameter of type t. v is a Go
+// value or a value.* type, mapped to a Kusto type as in AddDatatableFromStructs(), and integers are converted to
+// int, long or real where they fit. nil, nil pointers and value.* types that are not Valid are the null of t. For
+// dynamic, v is JSON encoded, so a string is a JSON string: pass a value.Dynamic or a json.RawMessage for a JSON
+// document, which must be valid.
+func (b *Builder) AddValueAs(v interface{}, t types.Column) (*Builder, error) {
+	val, err := valueAs(v, t)
+	if err != nil {
+		return b, fmt.Errorf("AddValueAs: %w", err)
+	}
+	return b.addBase(val), nil
+}
+
+// AddDynamicArray adds values as a dynamic array, such as dynamic(["a", 1]). Each value is JSON encoded.
+func (b *Builder) AddDynamicArray(values ...interface{}) (*Builder, error) {
+	elements := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if k, ok := v.(value.Kusto); ok {
+			v = jsonValue(k)
+		}
+		elements = append(elements, v)
+	}
+
+	j, err := json.Marshal(elements)
+	if err != nil {
+		return b, fmt.Errorf("AddDynamicArray: %w", err)
+	}
+	return b.addBase(newValue(j, types.Dynamic)), nil
+}
+
+// AddDatatable adds a datatable literal with columns and rows, such as datatable(Id:long, Name:string)[long(1), "a"].
+// Each value of a row must be of the type of its column, or a value.* type that is not Valid, for a null.
+// It fails if the literal would be longer than MaxDatatableLength.
+func (b *Builder) AddDatatable(columns table.Columns, rows []value.Values) (*Builder, error) {
+	if err := columns.Validate(); err != nil {
+		return b, fmt.Errorf("AddDatatable: %w", err)
+	}
+
+	cells := make([]string, 0, len(rows)*len(columns))
+	size := 0
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return b, fmt.Errorf("AddDatatable: row %d has %d values, expected %d", i, len(row), len(columns))
+		}
+		for j, v := range row {
+			text, err := cellLiteral(v, columns[j].Type)
+			if err != nil {
+				return b, fmt.Errorf("AddDatatable: row %d, column %s: %w", i, columns[j].Name, err)
+			}
+			if size += len(text) + 2; size > MaxDatatableLength {
+				return b, fmt.Errorf("AddDatatable: the datatable is larger than %d bytes", MaxDatatableLength)
+			}
+			cells = append(cells, text)
+		}
+	}
+
+	decls := make([]string, 0, len(columns))
+	for _, c := range columns {
+		decls = append(decls, NormalizeName(c.Name)+":"+string(c.Type))
+	}
+	return b.addBase(stringConstant("datatable(" + strings.Join(decls, ", ") + ")[" + strings.Join(cells, ", ") + "]")), nil
+}
+
+// AddDatatableFromStructs adds a datatable literal with a row for each element of rows, a slice of structs or of
+// pointers to structs. Columns are named by the `kusto` field tags that table.Row.ToStruct() understands, and typed
+// from the field types: bool to bool, int8, int16, int32, uint8 and uint16 to int, other integers to long, floats to
+// real, string to string, time.Time to datetime, time.Duration to timespan, uuid.UUID to guid, decimal.Decimal to
+// decimal, value.* types to their Kusto type, and maps, slices, arrays and structs to dynamic. Nil pointers are nulls.
+// A `kusto:"Name,type=dynamic"` tag stores any field as JSON, where strings and []byte are already JSON encoded: they
+// must be valid JSON, or empty for null. It fails if the literal would be longer than MaxDatatableLength.
+func (b *Builder) AddDatatableFromStructs(rows interface{}) (*Builder, error) {
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return b, fmt.Errorf("AddDatatableFromStructs: a %T is not a slice of structs", rows)
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return b, fmt.Errorf("AddDatatableFromStructs: a %T is not a slice of structs", rows)
+	}
+
+	fields, err := structFields(elem)
+	if err != nil {
+		return b, fmt.Errorf("AddDatatableFromStructs: %w", err)
+	}
+	columns := make(table.Columns, 0, len(fields))
+	for _, f := range fields {
+		columns = append(columns, table.Column{Name: f.name, Type: f.kustoType})
+	}
+
+	values := make([]value.Values, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		row := rv.Index(i)
+		if row.Kind() == reflect.Pointer {
+			if row.IsNil() {
+				return b, fmt.Errorf("AddDatatableFromStructs: row %d is nil", i)
+			}
+			row = row.Elem()
+		}
+		vals := make(value.Values, 0, len(fields))
+		for j, f := range fields {
+			v, err := structCell(row.Field(f.index), f.kustoType)
+			if err != nil {
+				return b, fmt.Errorf("AddDatatableFromStructs: row %d, column %s: %w", i, columns[j].Name, err)
+			}
+			vals = append(vals, v)
+		}
+		values = append(values, vals)
+	}
+
+	return b.AddDatatable(columns, values)
+}
+
+// structField is a field of a struct that maps to a Kusto column or parameter.
+type structField struct {
+	index     int
+	name      string
+	kustoType types.Column
+}
+
+// structFields returns the fields of struct type t, named by their `kusto` tags as table.Row.ToStruct() does, and typed
+// from their Go types, or by a type=dynamic tag option. Unexported fields and fields tagged "-" are skipped.
+func structFields(t reflect.Type) ([]structField, error) {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("kusto")
+		if strings.TrimSpace(tag) == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name = strings.TrimSpace(name); name == "" {
+			name = f.Name
+		}
+
+		kustoType, ok := goColumnType(f.Type)
+		for _, opt := range strings.Split(opts, ",") {
+			if opt = strings.TrimSpace(opt); strings.HasPrefix(opt, "type=") {
+				t := types.Column(strings.TrimPrefix(opt, "type="))
+				kustoType, ok = t, t == types.Dynamic || t == kustoType
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("field %s of type %v has no Kusto type", f.Name, f.Type)
+		}
+		fields = append(fields, structField{index: i, name: name, kustoType: kustoType})
+	}
+	return fields, nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	uuidType     = reflect.TypeOf(uuid.UUID{})
+	decimalType  = reflect.TypeOf(decimal.Decimal{})
+	kustoType    = reflect.TypeOf((*value.Kusto)(nil)).Elem()
+)
+
+// valueTypes are the Kusto types of the value.* types.
+var valueTypes = map[reflect.Type]types.Column{
+	reflect.TypeOf(value.Bool{}):     types.Bool,
+	reflect.TypeOf(value.DateTime{}): types.DateTime,
+	reflect.TypeOf(value.Dynamic{}):  types.Dynamic,
+	reflect.TypeOf(value.GUID{}):     types.GUID,
+	reflect.TypeOf(value.Int{}):      types.Int,
+	reflect.TypeOf(value.Long{}):     types.Long,
+	reflect.TypeOf(value.Real{}):     types.Real,
+	reflect.TypeOf(value.String{}):   types.String,
+	reflect.TypeOf(value.Timespan{}): types.Timespan,
+	reflect.TypeOf(value.Decimal{}):  types.Decimal,
+}
+
+// goColumnType returns the Kusto type of Go type t.
+func goColumnType(t reflect.Type) (types.Column, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if c, ok := valueTypes[t]; ok {
+		return c, true
+	}
+	switch t {
+	case timeType:
+		return types.DateTime, true
+	case durationType:
+		return types.Timespan, true
+	case uuidType:
+		return types.GUID, true
+	case decimalType:
+		return types.Decimal, true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return types.Bool, true
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return types.Int, true
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return types.Long, true
+	case reflect.Float32, reflect.Float64:
+		return types.Real, true
+	case reflect.String:
+		return types.String, true
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return types.Dynamic, true
+	}
+	return "", false
+}
+
+// goValue converts the Go value v to a typed Value. value.* types that are not Valid are typed nulls.
+func goValue(v interface{}) (Value, error) {
+	if v == nil {
+		return nil, fmt.Errorf("nil has no Kusto type")
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("nil has no Kusto type")
+		}
+		rv = rv.Elem()
+	}
+	if k, ok := rv.Interface().(value.Kusto); ok {
+		return kustoValue(k)
+	}
+
+	t, ok := goColumnType(rv.Type())
+	if !ok {
+		return nil, fmt.Errorf("a %T has no Kusto type", v)
+	}
+
+	var val interface{}
+	switch t {
+	case types.DateTime, types.GUID, types.Decimal:
+		val = rv.Interface()
+	case types.Timespan:
+		val = time.Duration(rv.Int())
+	case types.Bool:
+		val = rv.Bool()
+	case types.Int:
+		if rv.CanInt() {
+			val = int32(rv.Int())
+		} else {
+			val = int32(rv.Uint())
+		}
+	case types.Long:
+		if rv.CanInt() {
+			val = rv.Int()
+		} else if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows long", rv.Uint())
+		} else {
+			val = int64(rv.Uint())
+		}
+	case types.Real:
+		val = rv.Float()
+	case types.String:
+		val = rv.String()
+	case types.Dynamic:
+		j, err := json.Marshal(rv.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("a %T cannot be encoded as dynamic: %w", v, err)
+		}
+		val = j
+	}
+	return newValue(val, t), nil
+}
+
+// nullableValue is goValue, where a nil pointer is a null of the type it points to.
+func nullableValue(v interface{}) (Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		t, ok := goColumnType(rv.Type())
+		if !ok {
+			return nil, fmt.Errorf("a %T has no Kusto type", v)
+		}
+		return nullValue{kustoType: t}, nil
+	}
+	return goValue(v)
+}
+
+// nullLiteral is the null of type t, such as long(null). Strings cannot be null, so it is "" for strings.
+func nullLiteral(t types.Column) string {
+	if t == types.String {
+		return `""`
+	}
+	return string(t) + "(null)"
+}
+
+// nullValue is a typed null Value.
+type nullValue struct {
+	kustoType types.Column
+}
+
+func (v nullValue) Value() interface{} {
+	return nil
+}
+
+func (v nullValue) Type() types.Column {
+	return v.kustoType
+}
+
+func (v nullValue) String() string {
+	return nullLiteral(v.kustoType)
+}
+
+// kustoValue converts v to a typed Value.
+func kustoValue(v value.Kusto) (Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("nil has no Kusto type")
+		}
+		rv = rv.Elem()
+	}
+
+	var val interface{}
+	var valid bool
+	switch v := rv.Interface().(type) {
+	case value.Bool:
+		val, valid = v.Value, v.Valid
+	case value.DateTime:
+		val, valid = v.Value, v.Valid
+	case value.Dynamic:
+		val, valid = v.Value, v.Valid
+		// The JSON is rendered verbatim inside dynamic(...), so it must not be able to close it.
+		if valid && !json.Valid(v.Value) {
+			return nil, fmt.Errorf("%q is not valid JSON, so it cannot be a dynamic value", v.Value)
+		}
+	case value.GUID:
+		val, valid = v.Value, v.Valid
+	case value.Int:
+		val, valid = v.Value, v.Valid
+	case value.Long:
+		val, valid = v.Value, v.Valid
+	case value.Real:
+		val, valid = v.Value, v.Valid
+	case value.String:
+		val, valid = v.Value, v.Valid
+	case value.Timespan:
+		val, valid = v.Value, v.Valid
+	case value.Decimal:
+		valid = v.Valid
+		if valid {
+			d, err := decimal.NewFromString(v.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a decimal", v.Value)
+			}
+			val = d
+		}
+	default:
+		return nil, fmt.Errorf("a %T has no Kusto type", v)
+	}
+
+	t := valueTypes[rv.Type()]
+	if !valid {
+		return nullValue{kustoType: t}, nil
+	}
+	return newValue(val, t), nil
+}
+
+// valueAs converts the Go value v to a typed Value of type t, as AddValueAs() documents it.
+func valueAs(v interface{}, t types.Column) (Value, error) {
+	if !t.Valid() {
+		return nil, fmt.Errorf("%q is not a Kusto type", t)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if v == nil || rv.Kind() == reflect.Pointer {
+		return nullValue{kustoType: t}, nil
+	}
+
+	if t == types.Dynamic {
+		if k, ok := rv.Interface().(value.Kusto); ok {
+			if _, ok := k.(value.Dynamic); ok {
+				return kustoValue(k)
+			}
+			v = jsonValue(k)
+		} else {
+			v = rv.Interface()
+		}
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("a %T cannot be encoded as dynamic: %w", v, err)
+		}
+		return newValue(j, types.Dynamic), nil
+	}
+
+	val, err := goValue(rv.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := val.(nullValue); ok {
+		return nullValue{kustoType: t}, nil
+	}
+	if val.Type() == t {
+		return val, nil
+	}
+
+	switch n := val.Value().(type) {
+	case int32:
+		switch t {
+		case types.Long:
+			return newValue(int64(n), t), nil
+		case types.Real:
+			return newValue(float64(n), t), nil
+		}
+	case int64:
+		switch t {
+		case types.Int:
+			if n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("%d overflows int", n)
+			}
+			return newValue(int32(n), t), nil
+		case types.Real:
+			return newValue(float64(n), t), nil
+		}
+	}
+	return nil, fmt.Errorf("a %s value cannot be a %s", val.Type(), t)
+}
+
+// jsonValue returns the Go value of v to encode in JSON, nil if v is not Valid.
+func jsonValue(v value.Kusto) interface{} {
+	switch v := v.(type) {
+	case value.Dynamic:
+		if !v.Valid {
+			return nil
+		}
+		return json.RawMessage(v.Value)
+	case value.Timespan:
+		if !v.Valid {
+			return nil
+		}
+		return FormatTimespan(v.Value)
+	case value.DateTime:
+		if !v.Valid {
+			return nil
+		}
+		return FormatDatetime(v.Value)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Struct || !rv.FieldByName("Valid").Bool() {
+		return nil
+	}
+	return rv.FieldByName("Value").Interface()
+}
+
+// listOf renders values as a parenthesized list of literals of the same type.
+func listOf(values []interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", fmt.Errorf("the list of values is empty")
+	}
+
+	texts := make([]string, 0, len(values))
+	var listType types.Column
+	for i, v := range values {
+		val, err := goValue(v)
+		if err != nil {
+			return "", fmt.Errorf("element %d: %w", i, err)
+		}
+		t := val.Type()
+		if t == types.Int {
+			t = types.Long
+		}
+		if i == 0 {
+			listType = t
+		} else if t != listType {
+			return "", fmt.Errorf("element %d is a %s, but the list holds %s values", i, t, listType)
+		}
+		texts = append(texts, val.String())
+	}
+	return "(" + strings.Join(texts, ", ") + ")", nil
+}
+
+// cellLiteral renders v as a literal of column type t.
+func cellLiteral(v value.Kusto, t types.Column) (string, error) {
+	if v == nil {
+		return nullLiteral(t), nil
+	}
+	val, err := kustoValue(v)
+	if err != nil {
+		return "", err
+	}
+	if val.Type() != t {
+		return "", fmt.Errorf("a %s value cannot be stored in a %s column", val.Type(), t)
+	}
+	return val.String(), nil
+}
+
+// structCell converts the struct field f to a value of column type t.
+func structCell(f reflect.Value, t types.Column) (value.Kusto, error) {
+	for f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			return nil, nil
+		}
+		f = f.Elem()
+	}
+	if f.Type().Implements(kustoType) {
+		k := f.Interface().(value.Kusto)
+		if t == types.Dynamic && valueTypes[f.Type()] != types.Dynamic {
+			j, err := json.Marshal(jsonValue(k))
+			if err != nil {
+				return nil, err
+			}
+			return value.Dynamic{Value: j, Valid: true}, nil
+		}
+		return k, nil
+	}
+
+	if t == types.Dynamic {
+		// Strings and []byte are JSON documents, which kustoValue validates. Empty ones are nulls.
+		switch raw := f.Interface().(type) {
+		case string:
+			return value.Dynamic{Value: []byte(raw), Valid: raw != ""}, nil
+		case []byte:
+			return value.Dynamic{Value: raw, Valid: len(raw) > 0}, nil
+		}
+		j, err := json.Marshal(f.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return value.Dynamic{Value: j, Valid: true}, nil
+	}
+
+	switch t {
+	case types.Bool:
+		return value.Bool{Value: f.Bool(), Valid: true}, nil
+	case types.DateTime:
+		return value.DateTime{Value: f.Interface().(time.Time), Valid: true}, nil
+	case types.Timespan:
+		return value.Timespan{Value: time.Duration(f.Int()), Valid: true}, nil
+	case types.GUID:
+		return value.GUID{Value: f.Interface().(uuid.UUID), Valid: true}, nil
+	case types.Decimal:
+		return value.Decimal{Value: f.Interface().(decimal.Decimal).String(), Valid: true}, nil
+	case types.Real:
+		return value.Real{Value: f.Float(), Valid: true}, nil
+	case types.String:
+		return value.String{Value: f.String(), Valid: true}, nil
+	case types.Int:
+		if f.CanInt() {
+			return value.Int{Value: int32(f.Int()), Valid: true}, nil
+		}
+		return value.Int{Value: int32(f.Uint()), Valid: true}, nil
+	case types.Long:
+		if f.CanInt() {
+			return value.Long{Value: f.Int(), Valid: true}, nil
+		}
+		if f.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows long", f.Uint())
+		}
+		return value.Long{Value: int64(f.Uint()), Valid: true}, nil
+	}
+	return nil, fmt.Errorf("a %v cannot be stored in a %s column", f.Type(), t)
+}
@@ -0,0 +1,324 @@
+package kql
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddList(t *testing.T) {
+	t.Parallel()
+
+	n := int64(7)
+	tests := []struct {
+		name     string
+		values   []interface{}
+		expected string
+	}{
+		{"Strings", []interface{}{"a", `b"c`, ""}, `("a", "b\"c", "")`},
+		{"Longs and ints", []interface{}{int64(1), int32(2), 3, &n}, "(long(1), int(2), long(3), long(7))"},
+		{"Uints", []interface{}{uint8(1), uint64(2)}, "(int(1), long(2))"},
+		{"Datetimes", []interface{}{time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}, "(datetime(2023-01-02T03:04:05Z))"},
+		{"Timespans", []interface{}{time.Minute, value.Timespan{Value: time.Hour, Valid: true}}, "(timespan(00:01:00.0000000), timespan(01:00:00.0000000))"},
+		{
+			"Guids",
+			[]interface{}{uuid.MustParse("11111111-2222-3333-4444-555555555555")},
+			"(guid(11111111-2222-3333-4444-555555555555))",
+		},
+		{"Decimals", []interface{}{decimal.RequireFromString("1.5"), value.Decimal{Value: "2", Valid: true}}, "(decimal(1.5), decimal(2))"},
+		{"Values", []interface{}{value.Long{Value: 1, Valid: true}, value.Long{}}, "(long(1), long(null))"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			b, err := New("T | where a in ").AddList(test.values...)
+			require.NoError(t, err)
+			assert.Equal(t, "T | where a in "+test.expected, b.String())
+		})
+	}
+}
+
+func TestAddListErrors(t *testing.T) {
+	t.Parallel()
+
+	var nilPtr *int64
+	tests := []struct {
+		name   string
+		values []interface{}
+	}{
+		{"Empty", nil},
+		{"Mixed types", []interface{}{"a", 1}},
+		{"Nil", []interface{}{nil}},
+		{"Nil pointer", []interface{}{nilPtr}},
+		{"Unsupported type", []interface{}{func() {}}},
+		{"Overflow", []interface{}{uint64(1 << 63)}},
+		{"Bad decimal", []interface{}{value.Decimal{Value: "x", Valid: true}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			b := New("T | where a in ")
+			_, err := b.AddList(test.values...)
+			assert.Error(t, err)
+			assert.Equal(t, "T | where a in ", b.String())
+		})
+	}
+}
+
+func TestAddDynamicArray(t *testing.T) {
+	t.Parallel()
+
+	b, err := New("print ").AddDynamicArray(
+		"a'b",
+		1,
+		value.Dynamic{Value: []byte(`{"x":1}`), Valid: true},
+		value.String{},
+		value.Timespan{Value: time.Minute, Valid: true},
+		[]int{1, 2},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `print dynamic(["a'b",1,{"x":1},null,"00:01:00.0000000",[1,2]])`, b.String())
+
+	b, err = New("print ").AddDynamicArray()
+	require.NoError(t, err)
+	assert.Equal(t, "print dynamic([])", b.String())
+
+	_, err = New("print ").AddDynamicArray(func() {})
+	assert.Error(t, err)
+}
+
+func TestAddDatatable(t *testing.T) {
+	t.Parallel()
+
+	columns := table.Columns{
+		{Name: "Id", Type: types.Long},
+		{Name: "my name", Type: types.String},
+		{Name: "When", Type: types.DateTime},
+		{Name: "Took", Type: types.Timespan},
+		{Name: "Data", Type: types.Dynamic},
+	}
+	rows := []value.Values{
+		{
+			value.Long{Value: 1, Valid: true},
+			value.String{Value: `a" | drop table T //`, Valid: true},
+			value.DateTime{Value: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+			value.Timespan{Value: time.Second, Valid: true},
+			value.Dynamic{Value: []byte(`{"a":1}`), Valid: true},
+		},
+		{value.Long{}, value.String{}, value.DateTime{}, nil, value.Dynamic{}},
+	}
+
+	b, err := New("").AddDatatable(columns, rows)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`datatable(Id:long, ["my name"]:string, When:datetime, Took:timespan, Data:dynamic)[`+
+			`long(1), "a\" | drop table T //", datetime(2023-01-02T03:04:05Z), timespan(00:00:01.0000000), dynamic({"a":1}), `+
+			`long(null), "", datetime(null), timespan(null), dynamic(null)]`,
+		b.String())
+
+	b, err = New("").AddDatatable(columns[:1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "datatable(Id:long)[]", b.String())
+}
+
+func TestAddDatatableErrors(t *testing.T) {
+	t.Parallel()
+
+	columns := table.Columns{{Name: "Id", Type: types.Long}, {Name: "Name", Type: types.String}}
+	big := strings.Repeat("x", MaxDatatableLength)
+
+	tests := []struct {
+		name    string
+		columns table.Columns
+		rows    []value.Values
+	}{
+		{"No columns", nil, nil},
+		{"Short row", columns, []value.Values{{value.Long{Value: 1, Valid: true}}}},
+		{"Wrong type", columns, []value.Values{{value.Int{Value: 1, Valid: true}, value.String{Value: "a", Valid: true}}}},
+		{"Too large", columns, []value.Values{{value.Long{Value: 1, Valid: true}, value.String{Value: big, Valid: true}}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			b := New("")
+			_, err := b.AddDatatable(test.columns, test.rows)
+			assert.Error(t, err)
+			assert.Equal(t, "", b.String())
+		})
+	}
+}
+
+type datatableRow struct {
+	ID       int64 `kusto:"Id"`
+	Name     string
+	Count    *int32
+	When     time.Time
+	Labels   []string
+	Raw      string `kusto:"Raw,type=dynamic"`
+	Ignored  string `kusto:"-"`
+	internal string
+}
+
+func TestAddDatatableFromStructs(t *testing.T) {
+	t.Parallel()
+
+	count := int32(3)
+	when := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := []*datatableRow{
+		{ID: 1, Name: "a'b", Count: &count, When: when, Labels: []string{"x"}, Raw: `{"k":"v"}`, Ignored: "no", internal: "no"},
+		{ID: 2, Raw: "[]"},
+	}
+
+	b, err := New("").AddDatatableFromStructs(rows)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`datatable(Id:long, Name:string, Count:int, When:datetime, Labels:dynamic, Raw:dynamic)[`+
+			`long(1), "a\'b", int(3), datetime(2023-01-02T03:04:05Z), dynamic(["x"]), dynamic({"k":"v"}), `+
+			`long(2), "", int(null), datetime(0001-01-01T00:00:00Z), dynamic(null), dynamic([])]`,
+		b.String())
+
+	// A dynamic string is rendered verbatim, so it must be JSON that cannot close the literal.
+	for _, raw := range []string{"1)] | union SecretTable //", `{"a":1}) | union T | extend x=dynamic({`, "[1]]"} {
+		_, err = New("").AddDatatableFromStructs([]datatableRow{{Raw: raw}})
+		assert.Error(t, err, raw)
+	}
+	b, err = New("").AddDatatableFromStructs([]datatableRow{{Raw: `"1)] | union SecretTable //"`}})
+	require.NoError(t, err)
+	assert.Contains(t, b.String(), `dynamic("1)] | union SecretTable //")]`)
+
+	_, err = New("").AddDatatableFromStructs([]int{1})
+	assert.Error(t, err)
+	_, err = New("").AddDatatableFromStructs([]*datatableRow{nil})
+	assert.Error(t, err)
+	_, err = New("").AddDatatableFromStructs([]struct {
+		A int64 `kusto:"A,type=string"`
+	}{{A: 1}})
+	assert.Error(t, err)
+}
+
+func TestLiteralOf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		v        value.Kusto
+		expected string
+	}{
+		{"Bool", value.Bool{Value: true, Valid: true}, "bool(true)"},
+		{"Int", value.Int{Value: -1, Valid: true}, "int(-1)"},
+		{"Long", value.Long{Value: 1, Valid: true}, "long(1)"},
+		{"Real", value.Real{Value: 1.5, Valid: true}, "real(1.5)"},
+		{"Real NaN", value.Real{Value: math.NaN(), Valid: true}, "real(nan)"},
+		{"Real infinity", value.Real{Value: math.Inf(-1), Valid: true}, "real(-inf)"},
+		{"Decimal", value.Decimal{Value: "1.50", Valid: true}, "decimal(1.5)"},
+		{"String", value.String{Value: `a"b`, Valid: true}, `"a\"b"`},
+		{"DateTime", value.DateTime{Value: time.Date(2023, 1, 2, 3, 4, 5, 100, time.UTC), Valid: true}, "datetime(2023-01-02T03:04:05.0000001Z)"},
+		{"Timespan", value.Timespan{Value: 26 * time.Hour, Valid: true}, "timespan(1.02:00:00.0000000)"},
+		{"Negative timespan", value.Timespan{Value: -(26*time.Hour + time.Millisecond), Valid: true}, "timespan(-1.02:00:00.0010000)"},
+		{"GUID", value.GUID{Value: uuid.MustParse("11111111-2222-3333-4444-555555555555"), Valid: true}, "guid(11111111-2222-3333-4444-555555555555)"},
+		{"Dynamic", value.Dynamic{Value: []byte(`{"a":[1,"b"]}`), Valid: true}, `dynamic({"a":[1,"b"]})`},
+		{"Pointer", &value.Long{Value: 2, Valid: true}, "long(2)"},
+		{"Null bool", value.Bool{}, "bool(null)"},
+		{"Null int", value.Int{}, "int(null)"},
+		{"Null long", value.Long{}, "long(null)"},
+		{"Null real", value.Real{}, "real(null)"},
+		{"Null decimal", value.Decimal{}, "decimal(null)"},
+		{"Null string", value.String{}, `""`},
+		{"Null datetime", value.DateTime{}, "datetime(null)"},
+		{"Null timespan", value.Timespan{}, "timespan(null)"},
+		{"Null guid", value.GUID{}, "guid(null)"},
+		{"Null dynamic", value.Dynamic{}, "dynamic(null)"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := LiteralOf(test.v)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+
+			b, err := New("print x = ").AddValue(test.v)
+			require.NoError(t, err)
+			assert.Equal(t, "print x = "+test.expected, b.String())
+		})
+	}
+
+	_, err := LiteralOf(nil)
+	assert.Error(t, err)
+	_, err = LiteralOf(value.Decimal{Value: "x", Valid: true})
+	assert.Error(t, err)
+	_, err = LiteralOf(value.Dynamic{Value: []byte("1) | union SecretTable //"), Valid: true})
+	assert.Error(t, err)
+	b := New("print ")
+	_, err = b.AddValue(nil)
+	assert.Error(t, err)
+	assert.Equal(t, "print ", b.String())
+}
+
+func TestAddValueAs(t *testing.T) {
+	t.Parallel()
+
+	var nilLong *int64
+	tests := []struct {
+		name     string
+		v        interface{}
+		t        types.Column
+		expected string
+	}{
+		{"Same type", "a", types.String, `"a"`},
+		{"Int to long", int32(1), types.Long, "long(1)"},
+		{"Long to int", 1, types.Int, "int(1)"},
+		{"Long to real", int64(2), types.Real, "real(2)"},
+		{"Value", value.Long{Value: 3, Valid: true}, types.Real, "real(3)"},
+		{"Decimal value", value.Decimal{Value: "1.50", Valid: true}, types.Decimal, "decimal(1.5)"},
+		{"Nil", nil, types.DateTime, "datetime(null)"},
+		{"Nil pointer", nilLong, types.Long, "long(null)"},
+		{"Null value", value.Long{}, types.Real, "real(null)"},
+		{"Null string", value.String{}, types.String, `""`},
+		{"Dynamic string", `"]) | take 1 //`, types.Dynamic, `dynamic("\"]) | take 1 //")`},
+		{"Dynamic map", map[string]int{"a": 1}, types.Dynamic, `dynamic({"a":1})`},
+		{"Dynamic value", value.Dynamic{Value: []byte(`[1, 2]`), Valid: true}, types.Dynamic, "dynamic([1, 2])"},
+		{"Dynamic from long value", value.Long{Value: 4, Valid: true}, types.Dynamic, "dynamic(4)"},
+		{"Dynamic null", value.Dynamic{}, types.Dynamic, "dynamic(null)"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			b, err := New("").AddValueAs(test.v, test.t)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, b.String())
+		})
+	}
+
+	for _, v := range []interface{}{"1", int64(1) << 40, 1.5, value.Dynamic{Value: []byte(`1) | take 1`), Valid: true}} {
+		column := types.Long
+		switch v.(type) {
+		case int64:
+			column = types.Int
+		case value.Dynamic:
+			column = types.Dynamic
+		}
+		_, err := New("").AddValueAs(v, column)
+		assert.Error(t, err, "%v", v)
+	}
+	_, err := New("").AddValueAs(1, "number")
+	assert.Error(t, err)
+}
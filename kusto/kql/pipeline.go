@@ -0,0 +1,475 @@
+package kql
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operator is a comparison operator of Query.Where().
+type Operator string
+
+//goland:noinspection GoUnusedConst - Part of the API
+const (
+	Equal          Operator = "=="
+	NotEqual       Operator = "!="
+	EqualCI        Operator = "=~"
+	NotEqualCI     Operator = "!~"
+	Less           Operator = "<"
+	LessOrEqual    Operator = "<="
+	Greater        Operator = ">"
+	GreaterOrEqual Operator = ">="
+	Has            Operator = "has"
+	NotHas         Operator = "!has"
+	Contains       Operator = "contains"
+	NotContains    Operator = "!contains"
+	StartsWith     Operator = "startswith"
+	EndsWith       Operator = "endswith"
+	MatchesRegex   Operator = "matches regex"
+	// In, NotIn and InCI take a slice or an array of values.
+	In    Operator = "in"
+	NotIn Operator = "!in"
+	InCI  Operator = "in~"
+)
+
+var operators = map[Operator]bool{
+	Equal: true, NotEqual: true, EqualCI: true, NotEqualCI: true, Less: true, LessOrEqual: true, Greater: true,
+	GreaterOrEqual: true, Has: true, NotHas: true, Contains: true, NotContains: true, StartsWith: true, EndsWith: true,
+	MatchesRegex: true, In: true, NotIn: true, InCI: true,
+}
+
+// JoinKind is the kind of a Query.Join().
+type JoinKind string
+
+//goland:noinspection GoUnusedConst - Part of the API
+const (
+	InnerUnique JoinKind = "innerunique"
+	Inner       JoinKind = "inner"
+	LeftOuter   JoinKind = "leftouter"
+	RightOuter  JoinKind = "rightouter"
+	FullOuter   JoinKind = "fullouter"
+	LeftAnti    JoinKind = "leftanti"
+	RightAnti   JoinKind = "rightanti"
+	LeftSemi    JoinKind = "leftsemi"
+	RightSemi   JoinKind = "rightsemi"
+)
+
+// Expr is a scalar expression of a Query, such as a column, a literal or a function call.
+type Expr struct {
+	text string
+	err  error
+}
+
+// String implements fmt.Stringer.
+func (e Expr) String() string {
+	return e.text
+}
+
+// Col is a reference to column name.
+func Col(name string) Expr {
+	if name == "" {
+		return Expr{err: fmt.Errorf("a column name cannot be empty")}
+	}
+	return Expr{text: NormalizeName(name)}
+}
+
+// Lit is a literal value, rendered with the typed Builder.Add* methods.
+func Lit(value interface{}) Expr {
+	text, err := literal(value)
+	return Expr{text: text, err: err}
+}
+
+// Call is a call to function with args. Each argument is an Expr, or a value rendered as a literal.
+func Call(function string, args ...interface{}) Expr {
+	if function == "" || RequiresQuoting(function) {
+		return Expr{err: fmt.Errorf("%q is not a function name", function)}
+	}
+
+	texts := make([]string, 0, len(args))
+	for _, a := range args {
+		text, err := literal(a)
+		if err != nil {
+			return Expr{err: fmt.Errorf("argument of %s(): %w", function, err)}
+		}
+		texts = append(texts, text)
+	}
+	return Expr{text: function + "(" + strings.Join(texts, ", ") + ")"}
+}
+
+// As names the result of e, as in "name = e".
+func (e Expr) As(name string) Expr {
+	if e.err != nil {
+		return e
+	}
+	if name == "" {
+		return Expr{err: fmt.Errorf("a column name cannot be empty")}
+	}
+	return Expr{text: NormalizeName(name) + " = " + e.text}
+}
+
+// Count is the count() aggregation.
+func Count() Expr { return Call("count") }
+
+// Sum is the sum() aggregation of column.
+func Sum(column string) Expr { return Call("sum", Col(column)) }
+
+// Avg is the avg() aggregation of column.
+func Avg(column string) Expr { return Call("avg", Col(column)) }
+
+// Min is the min() aggregation of column.
+func Min(column string) Expr { return Call("min", Col(column)) }
+
+// Max is the max() aggregation of column.
+func Max(column string) Expr { return Call("max", Col(column)) }
+
+// DCount is the dcount() aggregation of column.
+func DCount(column string) Expr { return Call("dcount", Col(column)) }
+
+// SortKey is a column of Query.OrderBy().
+type SortKey struct {
+	text string
+	err  error
+}
+
+// Asc sorts by column in ascending order.
+func Asc(column string) SortKey {
+	e := Col(column)
+	return SortKey{text: e.text + " asc", err: e.err}
+}
+
+// Desc sorts by column in descending order.
+func Desc(column string) SortKey {
+	e := Col(column)
+	return SortKey{text: e.text + " desc", err: e.err}
+}
+
+// Query is a KQL query built from typed operators. Identifiers are escaped with NormalizeName and values are rendered
+// as typed literals, so values from untrusted input are safe to use. A Query is immutable: every method returns a new
+// Query, which allows a base query to be shared and extended.
+//
+//	q := kql.From("Events").
+//		Where("Level", kql.GreaterOrEqual, userLevel).
+//		Where("Source", kql.In, userSources).
+//		Summarize(kql.Count().As("Total")).By("Source").
+//		OrderBy(kql.Desc("Total")).
+//		Take(10)
+//	stmt, err := q.Build()
+//
+// Errors, such as values of an unsupported type, are reported by Build().
+type Query struct {
+	lets  []letStatement
+	parts []string
+	err   error
+}
+
+type letStatement struct {
+	name string
+	text string
+}
+
+// From starts a query that reads table, or a tabular let binding.
+func From(table string) *Query {
+	if table == "" {
+		return &Query{err: fmt.Errorf("a table name cannot be empty")}
+	}
+	return &Query{parts: []string{NormalizeName(table)}}
+}
+
+// clone returns a copy of q that does not share its slices.
+func (q *Query) clone() *Query {
+	return &Query{
+		lets:  append([]letStatement(nil), q.lets...),
+		parts: append([]string(nil), q.parts...),
+		err:   q.err,
+	}
+}
+
+// with returns a copy of q with operator appended, or with err if err is not nil.
+func (q *Query) with(operator string, err error) *Query {
+	n := q.clone()
+	if n.err != nil {
+		return n
+	}
+	if err != nil {
+		n.err = err
+		return n
+	}
+	n.parts = append(n.parts, " | "+operator)
+	return n
+}
+
+// nested adds the let statements of sub to q and returns the text of sub. Let statements can only appear at the start
+// of a query, so the ones of sub are moved to q.
+func (q *Query) nested(sub *Query) (string, error) {
+	if sub == nil {
+		return "", fmt.Errorf("a nested query cannot be nil")
+	}
+	if sub.err != nil {
+		return "", sub.err
+	}
+	for _, l := range sub.lets {
+		if err := q.addLet(l); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(sub.parts, ""), nil
+}
+
+func (q *Query) addLet(l letStatement) error {
+	for _, existing := range q.lets {
+		if existing.name == l.name {
+			if existing.text != l.text {
+				return fmt.Errorf("let %s is bound to two different values", l.name)
+			}
+			return nil
+		}
+	}
+	q.lets = append(q.lets, l)
+	return nil
+}
+
+// Let binds name to value before the query. value is a *Query for a tabular binding, an Expr, or a value rendered as
+// a literal. The binding can be used with From() and Col().
+func (q *Query) Let(name string, value interface{}) *Query {
+	n := q.clone()
+	if n.err != nil {
+		return n
+	}
+	if name == "" {
+		n.err = fmt.Errorf("a let name cannot be empty")
+		return n
+	}
+
+	var text string
+	var err error
+	if sub, ok := value.(*Query); ok {
+		text, err = n.nested(sub)
+	} else {
+		text, err = literal(value)
+	}
+	if err == nil {
+		err = n.addLet(letStatement{name: NormalizeName(name), text: text})
+	}
+	if err != nil {
+		n.err = fmt.Errorf("let %s: %w", name, err)
+	}
+	return n
+}
+
+// Where filters the rows on column op value. value is an Expr, such as another column, or a value rendered as a
+// literal. For In, NotIn and InCI, value is a slice or an array of values.
+func (q *Query) Where(column string, op Operator, value interface{}) *Query {
+	if !operators[op] {
+		return q.with("", fmt.Errorf("unknown operator %q", op))
+	}
+	col := Col(column)
+	if col.err != nil {
+		return q.with("", col.err)
+	}
+
+	var text string
+	var err error
+	switch op {
+	case In, NotIn, InCI:
+		text, err = listLiteral(value)
+	default:
+		text, err = literal(value)
+	}
+	if err != nil {
+		return q.with("", fmt.Errorf("where %s %s: %w", column, op, err))
+	}
+	return q.with("where "+col.text+" "+string(op)+" "+text, nil)
+}
+
+// Project keeps only columns.
+func (q *Query) Project(columns ...string) *Query {
+	exprs := make([]Expr, 0, len(columns))
+	for _, c := range columns {
+		exprs = append(exprs, Col(c))
+	}
+	return q.operator("project", exprs)
+}
+
+// Extend adds calculated columns. Name them with Expr.As().
+func (q *Query) Extend(exprs ...Expr) *Query {
+	return q.operator("extend", exprs)
+}
+
+func (q *Query) operator(name string, exprs []Expr) *Query {
+	if len(exprs) == 0 {
+		return q.with("", fmt.Errorf("%s needs at least one column", name))
+	}
+	text, err := joinExprs(exprs)
+	if err != nil {
+		return q.with("", fmt.Errorf("%s: %w", name, err))
+	}
+	return q.with(name+" "+text, nil)
+}
+
+func joinExprs(exprs []Expr) (string, error) {
+	texts := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		if e.err != nil {
+			return "", e.err
+		}
+		texts = append(texts, e.text)
+	}
+	return strings.Join(texts, ", "), nil
+}
+
+// Summarize is a Query that ends with a summarize operator, which can be grouped with By().
+type Summarize struct {
+	*Query
+}
+
+// Summarize aggregates the rows with aggs, such as Count() or Sum("Size").As("TotalSize").
+func (q *Query) Summarize(aggs ...Expr) *Summarize {
+	return &Summarize{Query: q.operator("summarize", aggs)}
+}
+
+// By groups the aggregation by keys. Each key is a column name, or an Expr such as Call("bin", Col("Timestamp"), time.Hour).
+func (s *Summarize) By(keys ...interface{}) *Query {
+	n := s.Query.clone()
+	if n.err != nil {
+		return n
+	}
+
+	exprs := make([]Expr, 0, len(keys))
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			exprs = append(exprs, Col(k))
+		case Expr:
+			exprs = append(exprs, k)
+		default:
+			n.err = fmt.Errorf("summarize by: a %T is not a column name or an Expr", k)
+			return n
+		}
+	}
+	if len(exprs) == 0 {
+		return n
+	}
+	text, err := joinExprs(exprs)
+	if err != nil {
+		n.err = fmt.Errorf("summarize by: %w", err)
+		return n
+	}
+	n.parts[len(n.parts)-1] += " by " + text
+	return n
+}
+
+// Join joins the rows with the ones of right, where the columns on are equal.
+func (q *Query) Join(kind JoinKind, right *Query, on ...string) *Query {
+	n := q.clone()
+	if n.err != nil {
+		return n
+	}
+	if len(on) == 0 {
+		return n.with("", fmt.Errorf("join needs at least one column"))
+	}
+	switch kind {
+	case InnerUnique, Inner, LeftOuter, RightOuter, FullOuter, LeftAnti, RightAnti, LeftSemi, RightSemi:
+	default:
+		return n.with("", fmt.Errorf("unknown join kind %q", kind))
+	}
+
+	text, err := n.nested(right)
+	if err != nil {
+		return n.with("", fmt.Errorf("join: %w", err))
+	}
+	exprs := make([]Expr, 0, len(on))
+	for _, c := range on {
+		exprs = append(exprs, Col(c))
+	}
+	cols, err := joinExprs(exprs)
+	if err != nil {
+		return n.with("", fmt.Errorf("join: %w", err))
+	}
+	return n.with("join kind="+string(kind)+" ("+text+") on "+cols, nil)
+}
+
+// OrderBy sorts the rows by keys, built with Asc() and Desc().
+func (q *Query) OrderBy(keys ...SortKey) *Query {
+	if len(keys) == 0 {
+		return q.with("", fmt.Errorf("order by needs at least one column"))
+	}
+	texts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.err != nil {
+			return q.with("", fmt.Errorf("order by: %w", k.err))
+		}
+		texts = append(texts, k.text)
+	}
+	return q.with("order by "+strings.Join(texts, ", "), nil)
+}
+
+// Take returns at most n rows.
+func (q *Query) Take(n int) *Query {
+	if n < 0 {
+		return q.with("", fmt.Errorf("take %d: the count cannot be negative", n))
+	}
+	return q.with("take "+strconv.Itoa(n), nil)
+}
+
+// Union adds the rows of others.
+func (q *Query) Union(others ...*Query) *Query {
+	n := q.clone()
+	if n.err != nil {
+		return n
+	}
+	if len(others) == 0 {
+		return n.with("", fmt.Errorf("union needs at least one query"))
+	}
+
+	texts := make([]string, 0, len(others))
+	for _, o := range others {
+		text, err := n.nested(o)
+		if err != nil {
+			return n.with("", fmt.Errorf("union: %w", err))
+		}
+		texts = append(texts, "("+text+")")
+	}
+	return n.with("union "+strings.Join(texts, ", "), nil)
+}
+
+// Build returns the query as a Builder, which can be passed to Client.Query(), or the first error of the query.
+func (q *Query) Build() (*Builder, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	b := New("")
+	for _, l := range q.lets {
+		b.addBase(stringConstant("let " + l.name + " = " + l.text + ";\n"))
+	}
+	return b.addBase(stringConstant(strings.Join(q.parts, ""))), nil
+}
+
+// literal renders v as a KQL literal. An Expr is rendered as is.
+func literal(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case Expr:
+		return v.text, v.err
+	case *Query:
+		return "", fmt.Errorf("a query is not a scalar value")
+	}
+	val, err := goValue(v)
+	if err != nil {
+		return "", err
+	}
+	return val.String(), nil
+}
+
+// listLiteral renders the elements of the slice or array v as a parenthesized list of literals.
+func listLiteral(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("a %T is not a list of values", v)
+	}
+	values := make([]interface{}, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		values = append(values, rv.Index(i).Interface())
+	}
+	return listOf(values)
+}
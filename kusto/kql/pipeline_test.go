@@ -0,0 +1,159 @@
+package kql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	base := From("Events").Where("Level", GreaterOrEqual, 3)
+
+	tests := []struct {
+		name     string
+		q        *Query
+		expected string
+	}{
+		{"From", From("Events"), "Events"},
+		{"From quoted", From("my-events"), `["my-events"]`},
+		{"Where", base, "Events | where Level >= long(3)"},
+		{
+			"Where string injection",
+			From("Events").Where("User", Equal, `x" or 1==1 //`),
+			`Events | where User == "x\" or 1==1 //"`,
+		},
+		{"Where empty string", From("T").Where("s", NotEqual, ""), `T | where s != ""`},
+		{"Where column", From("T").Where("a", Less, Col("b-c")), `T | where a < ["b-c"]`},
+		{
+			"Where in",
+			From("T").Where("Source", In, []string{"a", "b"}).Where("Id", NotIn, []int32{1, 2}),
+			`T | where Source in ("a", "b") | where Id !in (int(1), int(2))`,
+		},
+		{
+			"Where types",
+			From("T").
+				Where("t", Greater, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)).
+				Where("d", Less, time.Minute).
+				Where("g", Equal, uuid.MustParse("11111111-2222-3333-4444-555555555555")).
+				Where("r", Greater, 1.5).
+				Where("b", Equal, true).
+				Where("s", MatchesRegex, "^a.*"),
+			`T | where t > datetime(2023-01-02T03:04:05Z) | where d < timespan(00:01:00.0000000)` +
+				` | where g == guid(11111111-2222-3333-4444-555555555555) | where r > real(1.5) | where b == bool(true)` +
+				` | where s matches regex "^a.*"`,
+		},
+		{"Project", base.Project("Level", "my col"), `Events | where Level >= long(3) | project Level, ["my col"]`},
+		{
+			"Extend",
+			From("T").Extend(Call("strlen", Col("Message")).As("Length"), Lit("x").As("Tag")),
+			`T | extend Length = strlen(Message), Tag = "x"`,
+		},
+		{
+			"Summarize",
+			From("T").Summarize(Count().As("Total"), Sum("Size")).Query,
+			"T | summarize Total = count(), sum(Size)",
+		},
+		{
+			"Summarize by",
+			From("T").Summarize(Count()).By("Source", Call("bin", Col("Timestamp"), time.Hour)).OrderBy(Desc("count_"), Asc("Source")).Take(10),
+			"T | summarize count() by Source, bin(Timestamp, timespan(01:00:00.0000000)) | order by count_ desc, Source asc | take 10",
+		},
+		{
+			"Summarize then operator",
+			From("T").Summarize(Max("a")).Take(1),
+			"T | summarize max(a) | take 1",
+		},
+		{
+			"Join",
+			From("T").Join(LeftOuter, From("U").Where("x", Equal, 1), "Id", "Key"),
+			"T | join kind=leftouter (U | where x == long(1)) on Id, Key",
+		},
+		{
+			"Union",
+			From("T").Union(From("U"), From("V").Take(1)),
+			"T | union (U), (V | take 1)",
+		},
+		{
+			"Let",
+			From("T").Let("since", time.Hour).Where("Age", Less, Col("since")),
+			"let since = timespan(01:00:00.0000000);\nT | where Age < since",
+		},
+		{
+			"Let tabular",
+			From("Recent").Let("Recent", From("T").Where("x", Greater, 1)),
+			"let Recent = T | where x > long(1);\nRecent",
+		},
+		{
+			"Lets of nested queries",
+			From("A").Let("n", 1).Join(Inner, From("B").Let("m", 2).Where("x", Equal, Col("m")), "Id"),
+			"let n = long(1);\nlet m = long(2);\nA | join kind=inner (B | where x == m) on Id",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			b, err := test.q.Build()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, b.String())
+		})
+	}
+}
+
+func TestQueryImmutable(t *testing.T) {
+	t.Parallel()
+
+	base := From("T").Where("a", Equal, 1)
+	first, err := base.Take(1).Build()
+	require.NoError(t, err)
+	second, err := base.Project("a").Build()
+	require.NoError(t, err)
+	got, err := base.Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "T | where a == long(1) | take 1", first.String())
+	assert.Equal(t, "T | where a == long(1) | project a", second.String())
+	assert.Equal(t, "T | where a == long(1)", got.String())
+}
+
+func TestQueryErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		q    *Query
+	}{
+		{"Empty table", From("")},
+		{"Unknown operator", From("T").Where("a", Operator("== 1 or 1"), 1)},
+		{"Empty column", From("T").Where("", Equal, 1)},
+		{"Nil value", From("T").Where("a", Equal, nil)},
+		{"Unsupported value", From("T").Where("a", Equal, func() {})},
+		{"In without list", From("T").Where("a", In, 1)},
+		{"Empty list", From("T").Where("a", In, []string{})},
+		{"Bad function", From("T").Extend(Call("f(x); T", Col("a")))},
+		{"No columns", From("T").Project()},
+		{"Bad by", From("T").Summarize(Count()).By(1)},
+		{"Negative take", From("T").Take(-1)},
+		{"Unknown join", From("T").Join("cross", From("U"), "a")},
+		{"Join without columns", From("T").Join(Inner, From("U"))},
+		{"Nil join", From("T").Join(Inner, nil, "a")},
+		{"Error in nested", From("T").Union(From(""))},
+		{"Conflicting lets", From("T").Let("a", 1).Union(From("U").Let("a", 2))},
+		{"Error is kept", From("T").Take(-1).Where("a", Equal, 1).Take(1)},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := test.q.Build()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package kql
 
 import (
+	"fmt"
 	"github.com/Azure/azure-kusto-go/kusto/data/types"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -67,6 +69,59 @@ func (q *Parameters) AddDecimal(key string, value decimal.Decimal) *Parameters {
 	return q.addBase(key, newValue(value, types.Decimal))
 }
 
+// Add adds the parameter key with the Kusto type inferred from v: bool to bool, int8, int16, int32, uint8 and uint16
+// to int, other integers to long, floats to real, string to string, time.Time to datetime, time.Duration to timespan,
+// uuid.UUID to guid, decimal.Decimal to decimal, value.* types to their Kusto type, and maps, slices, arrays and
+// structs to dynamic. Pointers are dereferenced, and a nil pointer or a value.* type that is not Valid is a null of
+// its type. It fails if key is not a valid parameter name or v has no Kusto type, such as an untyped nil.
+func (q *Parameters) Add(key string, v interface{}) (*Parameters, error) {
+	if key == "" || RequiresQuoting(key) {
+		return q, fmt.Errorf("%q is not a valid parameter name", key)
+	}
+	val, err := nullableValue(v)
+	if err != nil {
+		return q, fmt.Errorf("parameter %s: %w", key, err)
+	}
+	return q.addBase(key, val), nil
+}
+
+// ParametersFromStruct returns the Parameters of the fields of p, a struct or a pointer to a struct, so that a request
+// type can be bound to a query directly. Parameters are named by the `kusto` field tags that table.Row.ToStruct()
+// understands and typed as Add() does, and a `kusto:"Name,type=dynamic"` tag stores any field as JSON, where strings
+// and []byte are already JSON encoded. Unexported fields and fields tagged "-" are skipped.
+func ParametersFromStruct(p interface{}) (*Parameters, error) {
+	rv := reflect.ValueOf(p)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("ParametersFromStruct: a %T is not a struct", p)
+	}
+
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return nil, fmt.Errorf("ParametersFromStruct: %w", err)
+	}
+	params := NewParameters()
+	for _, f := range fields {
+		if f.name == "" || RequiresQuoting(f.name) {
+			return nil, fmt.Errorf("ParametersFromStruct: %q is not a valid parameter name", f.name)
+		}
+		cell, err := structCell(rv.Field(f.index), f.kustoType)
+		if err != nil {
+			return nil, fmt.Errorf("ParametersFromStruct: parameter %s: %w", f.name, err)
+		}
+		var val Value = nullValue{kustoType: f.kustoType}
+		if cell != nil {
+			if val, err = kustoValue(cell); err != nil {
+				return nil, fmt.Errorf("ParametersFromStruct: parameter %s: %w", f.name, err)
+			}
+		}
+		params.addBase(f.name, val)
+	}
+	return params, nil
+}
+
 func (q *Parameters) ToDeclarationString() string {
 	const (
 		declare   = "declare query_parameters("
@@ -2,6 +2,7 @@ package kql
 
 import (
 	"fmt"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
@@ -129,3 +130,105 @@ func TestQueryParameters(t *testing.T) {
 		})
 	}
 }
+
+func TestParametersAdd(t *testing.T) {
+	n := int64(3)
+	var nilLong *int64
+	var nilTime *time.Time
+	dt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	params := NewParameters()
+	for key, v := range map[string]interface{}{
+		"s":   "a'b",
+		"i":   int32(1),
+		"u":   uint16(2),
+		"l":   3,
+		"p":   &n,
+		"nl":  nilLong,
+		"nt":  nilTime,
+		"r":   1.5,
+		"b":   true,
+		"t":   dt,
+		"d":   time.Minute,
+		"dyn": map[string]int{"a": 1},
+		"v":   value.Long{},
+	} {
+		_, err := params.Add(key, v)
+		require.NoError(t, err, key)
+	}
+
+	require.Equal(t, map[string]string{
+		"s":   `"a\'b"`,
+		"i":   "int(1)",
+		"u":   "int(2)",
+		"l":   "long(3)",
+		"p":   "long(3)",
+		"nl":  "long(null)",
+		"nt":  "datetime(null)",
+		"r":   "real(1.5)",
+		"b":   "bool(true)",
+		"t":   "datetime(2023-01-02T03:04:05Z)",
+		"d":   "timespan(00:01:00.0000000)",
+		"dyn": `dynamic({"a":1})`,
+		"v":   "long(null)",
+	}, params.ToParameterCollection())
+	require.Contains(t, params.ToDeclarationString(), "nt:datetime")
+
+	for key, v := range map[string]interface{}{
+		"nil":      nil,
+		"func":     func() {},
+		"overflow": uint64(1 << 63),
+		"bad-name": 1,
+		"":         1,
+		"chan":     make(chan int),
+	} {
+		_, err := NewParameters().Add(key, v)
+		require.Error(t, err, key)
+	}
+}
+
+type parametersRequest struct {
+	User     string `kusto:"user"`
+	Since    time.Time
+	Limit    *int64 `kusto:"limit"`
+	Tags     []string
+	Raw      string `kusto:"raw,type=dynamic"`
+	Ignored  string `kusto:"-"`
+	internal string
+}
+
+func TestParametersFromStruct(t *testing.T) {
+	dt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, p := range []interface{}{
+		parametersRequest{User: "u", Since: dt, Tags: []string{"x"}, Raw: `{"a":1}`, internal: "no"},
+		&parametersRequest{User: "u", Since: dt, Tags: []string{"x"}, Raw: `{"a":1}`, internal: "no"},
+	} {
+		params, err := ParametersFromStruct(p)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"user":  `"u"`,
+			"Since": "datetime(2023-01-02T03:04:05Z)",
+			"limit": "long(null)",
+			"Tags":  `dynamic(["x"])`,
+			"raw":   `dynamic({"a":1})`,
+		}, params.ToParameterCollection())
+	}
+
+	for _, p := range []interface{}{
+		nil,
+		1,
+		(*parametersRequest)(nil),
+		struct {
+			A string `kusto:"my-a"`
+		}{},
+		struct{ F func() }{},
+		struct {
+			A int64 `kusto:"A,type=string"`
+		}{},
+		parametersRequest{Raw: "1) | union SecretTable //"},
+	} {
+		_, err := ParametersFromStruct(p)
+		require.Error(t, err, fmt.Sprintf("%#v", p))
+	}
+}
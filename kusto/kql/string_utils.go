@@ -2,6 +2,7 @@ package kql
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 	"unicode"
@@ -26,10 +27,6 @@ func RequiresQuoting(value string) bool {
 }
 
 func QuoteString(value string, hidden bool) string {
-	if value == "" {
-		return value
-	}
-
 	var literal strings.Builder
 
 	if hidden {
@@ -94,6 +91,14 @@ func ShouldBeEscaped(c int32) bool {
 }
 
 func FormatTimespan(duration time.Duration) string {
+	if duration < 0 {
+		// The sign applies to the whole timespan, as in -1.02:03:04.
+		if duration == math.MinInt64 {
+			duration++
+		}
+		return "-" + FormatTimespan(-duration)
+	}
+
 	// Calculate the number of days in the duration
 	days := duration / (24 * time.Hour)
 
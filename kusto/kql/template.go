@@ -0,0 +1,132 @@
+package kql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto/kql/lexer"
+)
+
+// Template is a query with named placeholders, such as "Events | where Id == {id} and Ts > {since}", that is parsed
+// once by NewTemplate() and bound to values with Bind(). A placeholder is a name made of letters, digits and
+// underscores between braces. Braces inside string literals and comments, or around anything else, are left as is.
+//
+// A Template is immutable and safe to use from multiple goroutines.
+type Template struct {
+	text string
+	// segments alternate between query text and placeholder names, starting with query text.
+	segments []string
+	names    map[string]bool
+}
+
+// NewTemplate parses text into a Template. It fails if a string literal of text is not closed.
+func NewTemplate(text string) (*Template, error) {
+	tokens, err := lexer.Tokenize(text)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+
+	t := &Template{text: text, names: map[string]bool{}}
+	start := 0
+	for i := 0; i+2 < len(tokens); i++ {
+		open, name, closing := tokens[i], tokens[i+1], tokens[i+2]
+		if open.Text != "{" || name.Kind != lexer.Identifier || closing.Text != "}" ||
+			name.Offset != open.End() || closing.Offset != name.End() || RequiresQuoting(name.Text) {
+			continue
+		}
+		t.segments = append(t.segments, text[start:open.Offset], name.Text)
+		t.names[name.Text] = true
+		start = closing.End()
+		i += 2
+	}
+	t.segments = append(t.segments, text[start:])
+	return t, nil
+}
+
+// String returns the text of the template.
+func (t *Template) String() string {
+	return t.text
+}
+
+// Placeholders returns the sorted names of the placeholders of the template.
+func (t *Template) Placeholders() []string {
+	names := make([]string, 0, len(t.names))
+	for name := range t.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type templateOptions struct {
+	asParameters bool
+}
+
+// TemplateOption is an optional argument to Template.Bind().
+type TemplateOption func(o *templateOptions)
+
+// AsQueryParameters makes Template.Bind() replace each placeholder with a query parameter of the same name, and return
+// the values as Parameters, which are sent with the query and declared with "declare query_parameters".
+// By default, the values are rendered inline as typed literals.
+func AsQueryParameters() TemplateOption {
+	return func(o *templateOptions) {
+		o.asParameters = true
+	}
+}
+
+// Bind returns the query of the template with the placeholders bound to values, keyed by placeholder name.
+// Values are Go values or value.* types, which are converted to Kusto types as AddDatatableFromStructs() does.
+// It fails if a placeholder has no value, or a value has no placeholder.
+//
+// The returned Parameters hold the values when AsQueryParameters() is used, and are empty otherwise. Pass them to the
+// query with kusto.QueryParameters().
+func (t *Template) Bind(values map[string]interface{}, options ...TemplateOption) (*Builder, *Parameters, error) {
+	opts := templateOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	var unbound, unused []string
+	for _, name := range t.Placeholders() {
+		if _, ok := values[name]; !ok {
+			unbound = append(unbound, name)
+		}
+	}
+	for name := range values {
+		if !t.names[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	if len(unbound) > 0 {
+		return nil, nil, fmt.Errorf("template: placeholders %s have no value", strings.Join(unbound, ", "))
+	}
+	if len(unused) > 0 {
+		return nil, nil, fmt.Errorf("template: values %s have no placeholder", strings.Join(unused, ", "))
+	}
+
+	params := NewParameters()
+	rendered := make(map[string]string, len(values))
+	for name, v := range values {
+		val, err := goValue(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("template: value %s: %w", name, err)
+		}
+		if opts.asParameters {
+			params.addBase(name, val)
+			rendered[name] = name
+		} else {
+			rendered[name] = val.String()
+		}
+	}
+
+	b := New("")
+	for i, s := range t.segments {
+		if i%2 == 1 {
+			s = rendered[s]
+		}
+		b.addBase(stringConstant(s))
+	}
+	return b, params, nil
+}
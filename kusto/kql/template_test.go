@@ -0,0 +1,127 @@
+package kql
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		text     string
+		values   map[string]interface{}
+		inline   string
+		params   string
+		declared map[string]string
+	}{
+		{
+			name:     "Values",
+			text:     "Events | where Id == {id} and Ts > {since}",
+			values:   map[string]interface{}{"id": int64(3), "since": since},
+			inline:   "Events | where Id == long(3) and Ts > datetime(2023-01-02T03:04:05Z)",
+			params:   "Events | where Id == id and Ts > since",
+			declared: map[string]string{"id": "long(3)", "since": "datetime(2023-01-02T03:04:05Z)"},
+		},
+		{
+			name:     "Injection",
+			text:     "T | where Name == {name}",
+			values:   map[string]interface{}{"name": `x" or 1==1 //`},
+			inline:   `T | where Name == "x\" or 1==1 //"`,
+			params:   "T | where Name == name",
+			declared: map[string]string{"name": `"x\" or 1==1 //"`},
+		},
+		{
+			name:     "Repeated and null",
+			text:     "T | where a == {n} or b == {n}",
+			values:   map[string]interface{}{"n": value.Long{}},
+			inline:   "T | where a == long(null) or b == long(null)",
+			params:   "T | where a == n or b == n",
+			declared: map[string]string{"n": "long(null)"},
+		},
+		{
+			name: "Not placeholders",
+			text: "T | where s == \"{s}\" and v == @'{''v}' and d == dynamic({\"a\": {x}}) // {c}\n" +
+				"| extend m = ```{m}```, b = {a b}, n = {1x}",
+			values:   map[string]interface{}{"x": 1.5},
+			inline:   "T | where s == \"{s}\" and v == @'{''v}' and d == dynamic({\"a\": real(1.5)}) // {c}\n| extend m = ```{m}```, b = {a b}, n = {1x}",
+			params:   "T | where s == \"{s}\" and v == @'{''v}' and d == dynamic({\"a\": x}) // {c}\n| extend m = ```{m}```, b = {a b}, n = {1x}",
+			declared: map[string]string{"x": "real(1.5)"},
+		},
+		{
+			name:     "No placeholders",
+			text:     "T | take 1",
+			inline:   "T | take 1",
+			params:   "T | take 1",
+			declared: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			tmpl, err := NewTemplate(test.text)
+			require.NoError(t, err)
+			assert.Equal(t, test.text, tmpl.String())
+
+			b, params, err := tmpl.Bind(test.values)
+			require.NoError(t, err)
+			assert.Equal(t, test.inline, b.String())
+			assert.Equal(t, 0, params.Count())
+
+			b, params, err = tmpl.Bind(test.values, AsQueryParameters())
+			require.NoError(t, err)
+			assert.Equal(t, test.params, b.String())
+			assert.Equal(t, test.declared, params.ToParameterCollection())
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, text := range []string{`T | where a == "{a}`, "T | where a == @'x''", "T | extend a = ```{a}"} {
+		_, err := NewTemplate(text)
+		assert.Error(t, err, text)
+	}
+
+	tmpl, err := NewTemplate("T | where a == {a} and b == {b}")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tmpl.Placeholders())
+
+	_, _, err = tmpl.Bind(map[string]interface{}{"a": 1})
+	assert.EqualError(t, err, "template: placeholders b have no value")
+	_, _, err = tmpl.Bind(map[string]interface{}{"a": 1, "b": 2, "d": 3, "c": 4})
+	assert.EqualError(t, err, "template: values c, d have no placeholder")
+	_, _, err = tmpl.Bind(map[string]interface{}{"a": 1, "b": func() {}}, AsQueryParameters())
+	assert.Error(t, err)
+}
+
+func TestTemplateConcurrentBind(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := NewTemplate("T | where a == {a}")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, _, err := tmpl.Bind(map[string]interface{}{"a": i})
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("T | where a == long(%d)", i), b.String())
+		}()
+	}
+	wg.Wait()
+}
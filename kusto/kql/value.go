@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/Azure/azure-kusto-go/kusto/data/types"
 	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"math"
 	"time"
 )
 
@@ -35,6 +36,15 @@ func (v *kqlValue) String() string {
 		val = FormatDatetime(val.(time.Time))
 	case types.Timespan:
 		val = FormatTimespan(val.(time.Duration))
+	case types.Real:
+		switch f := val.(float64); {
+		case math.IsNaN(f):
+			val = "nan"
+		case math.IsInf(f, 1):
+			val = "+inf"
+		case math.IsInf(f, -1):
+			val = "-inf"
+		}
 	case types.Dynamic:
 		got := value.Dynamic{}
 		_ = got.Unmarshal(val)
@@ -12,6 +12,7 @@ import (
 	"github.com/Azure/azure-kusto-go/kusto/data/errors"
 	"github.com/Azure/azure-kusto-go/kusto/internal/frames"
 	v2 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v2"
+	"github.com/Azure/azure-kusto-go/kusto/trustedendpoints"
 )
 
 // queryer provides for getting a stream of Kusto frames. Exists to allow fake Kusto streams in tests.
@@ -42,6 +43,13 @@ type Client struct {
 	mgmtConnMu       sync.Mutex
 	http             *http.Client
 	clientDetails    *ClientDetails
+
+	trustedEndpoints       *trustedendpoints.TrustedEndpoints
+	skipEndpointValidation bool
+	cloudInfo              *CloudInfo
+	noAuth                 bool
+	ingestionURI           string
+	defaultQueryOptions    []QueryOption
 }
 
 // Option is an optional argument type for New().
@@ -61,20 +69,34 @@ func New(kcsb *ConnectionStringBuilder, options ...Option) (*Client, error) {
 	if err != nil {
 		return nil, errors.ES(errors.OpServConn, errors.KClientArgs, "could not parse the endpoint(%s): %s", endpoint, err).SetNoRetry()
 	}
-	if strings.HasPrefix(u.Hostname(), "ingest-") {
-		return nil, errors.ES(
-			errors.OpServConn,
-			errors.KClientArgs,
-			"endpoint argument started with 'ingest-'. Adding 'ingest-' is taken care of by the client. "+
-				"If using Mgmt() on an ingestion endpoint, use option QueryIngestion(). This is very uncommon",
-		)
-	}
 
-	client := &Client{auth: *auth, endpoint: endpoint, clientDetails: NewClientDetails(kcsb.ApplicationForTracing, kcsb.UserForTracing)}
+	client := &Client{auth: *auth, endpoint: endpoint, ingestionURI: kcsb.IngestionURI, clientDetails: NewClientDetails(kcsb.ApplicationForTracing, kcsb.UserForTracing)}
 	for _, o := range options {
 		o(client)
 	}
 
+	if client.ingestionURI == "" {
+		if strings.HasPrefix(u.Hostname(), "ingest-") {
+			return nil, errors.ES(
+				errors.OpServConn,
+				errors.KClientArgs,
+				"endpoint argument started with 'ingest-'. Adding 'ingest-' is taken care of by the client. "+
+					"If using Mgmt() on an ingestion endpoint, use option QueryIngestion(). This is very uncommon. "+
+					"If the engine endpoint really starts with 'ingest-', set the ingestion endpoint with WithIngestionURI()",
+			).SetNoRetry()
+		}
+	} else if err := validateIngestionURI(u, client.ingestionURI); err != nil {
+		return nil, err
+	}
+
+	// The emulator has no AAD, so there is no cloud metadata or trusted endpoint to check against.
+	if kcsb.NoAuth {
+		client.noAuth = true
+		client.skipEndpointValidation = true
+	}
+
+	tkp.cloudInfo = client.cloudInfo
+
 	if client.http == nil {
 		client.http = &http.Client{
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -83,7 +105,7 @@ func New(kcsb *ConnectionStringBuilder, options ...Option) (*Client, error) {
 		}
 	}
 
-	conn, err := NewConn(endpoint, *auth, client.http, client.clientDetails)
+	conn, err := NewConn(endpoint, *auth, client.http, client.clientDetails, client.ConnOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +120,106 @@ func WithHttpClient(client *http.Client) Option {
 	}
 }
 
+// WithTrustedEndpoints sets the trusted endpoints policy used by this client only, instead of the process-wide
+// trustedendpoints.Instance. Use trustedendpoints.New() or trustedendpoints.FromFile() to create one.
+func WithTrustedEndpoints(trusted *trustedendpoints.TrustedEndpoints) Option {
+	return func(c *Client) {
+		c.trustedEndpoints = trusted
+	}
+}
+
+// WithIngestionURI sets the data management (ingestion) endpoint explicitly, instead of deriving it by adding "ingest-"
+// to the engine host. It overrides ConnectionStringBuilder.IngestionURI.
+func WithIngestionURI(uri string) Option {
+	return func(c *Client) {
+		c.ingestionURI = uri
+	}
+}
+
+// validateIngestionURI checks that the explicit ingestion endpoint can be used with the engine endpoint, and that it
+// is not the engine endpoint, nor swapped with it.
+func validateIngestionURI(engine *url.URL, ingestionURI string) error {
+	u, err := url.Parse(ingestionURI)
+	if err != nil {
+		return errors.ES(errors.OpServConn, errors.KClientArgs, "could not parse the ingestion endpoint(%s): %s", ingestionURI, err).SetNoRetry()
+	}
+	if u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return errors.ES(errors.OpServConn, errors.KClientArgs, "ingestion endpoint(%s) must be an absolute http(s) URI", ingestionURI).SetNoRetry()
+	}
+	if u.Scheme != engine.Scheme {
+		return errors.ES(
+			errors.OpServConn,
+			errors.KClientArgs,
+			"ingestion endpoint(%s) and endpoint(%s) must use the same scheme", ingestionURI, engine.String(),
+		).SetNoRetry()
+	}
+	if strings.EqualFold(u.Host, engine.Host) && strings.TrimSuffix(u.Path, "/") == strings.TrimSuffix(engine.Path, "/") {
+		return errors.ES(
+			errors.OpServConn,
+			errors.KClientArgs,
+			"ingestion endpoint(%s) is the engine endpoint; it must be the data management endpoint of the cluster, "+
+				"such as https://ingest-<cluster>.<region>.kusto.windows.net", ingestionURI,
+		).SetNoRetry()
+	}
+	if strings.EqualFold("ingest-"+u.Host, engine.Host) {
+		return errors.ES(
+			errors.OpServConn,
+			errors.KClientArgs,
+			"the endpoint(%s) and the ingestion endpoint(%s) are swapped: the endpoint is the engine endpoint, without 'ingest-'",
+			engine.String(), ingestionURI,
+		).SetNoRetry()
+	}
+	return nil
+}
+
+// WithoutEndpointValidation disables trusted endpoint validation for this client only. This is meant for
+// talking to a local Kusto emulator, and should not be used with production clusters.
+func WithoutEndpointValidation() Option {
+	return func(c *Client) {
+		c.skipEndpointValidation = true
+	}
+}
+
+// WithCloudInfo provides the cloud metadata for the client's endpoint, so that the client never calls the
+// /v1/rest/auth/metadata endpoint. This is needed for sovereign-cloud and air-gapped deployments where that call is
+// blocked. To pre-seed the metadata for all clients in the process, use SetCloudInfo().
+func WithCloudInfo(info CloudInfo) Option {
+	return func(c *Client) {
+		c.cloudInfo = &info
+	}
+}
+
+// WithDefaultQueryOptions sets QueryOptions that apply to every Query(), QueryToJson() and Mgmt() call of the client,
+// such as Application(), RequestDescription(), RequestReadonly(), ServerTimeout() or QueryConsistency(). The options
+// of a call are applied after them, so they can override them.
+func WithDefaultQueryOptions(opts ...QueryOption) Option {
+	return func(c *Client) {
+		c.defaultQueryOptions = append(c.defaultQueryOptions, opts...)
+	}
+}
+
+// IsEmulator returns true if the client was created with ConnectionStringBuilder.WithNoAuth(), meaning it talks to a
+// local Kusto emulator. The ingest package uses this to ingest without queued ingestion resources.
+func (c *Client) IsEmulator() bool {
+	return c.noAuth
+}
+
+// ConnOptions returns the ConnOptions that a Conn created on behalf of this client should use, so that it
+// shares the client's settings (such as trusted endpoints).
+func (c *Client) ConnOptions() []ConnOption {
+	var options []ConnOption
+	if c.trustedEndpoints != nil {
+		options = append(options, ConnTrustedEndpoints(c.trustedEndpoints))
+	}
+	if c.skipEndpointValidation {
+		options = append(options, ConnSkipEndpointValidation())
+	}
+	if c.cloudInfo != nil {
+		options = append(options, ConnCloudInfo(*c.cloudInfo))
+	}
+	return options
+}
+
 // QueryOption is an option type for a call to Query().
 type QueryOption func(q *queryOptions) error
 
@@ -118,6 +240,21 @@ func (c *Client) Endpoint() string {
 	return c.endpoint
 }
 
+// IngestionURI returns the data management (ingestion) endpoint. This is the URI set with WithIngestionURI(), or
+// the endpoint with "ingest-" added to its host.
+func (c *Client) IngestionURI() string {
+	if c.ingestionURI != "" {
+		return c.ingestionURI
+	}
+
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return c.endpoint
+	}
+	u.Host = "ingest-" + u.Host
+	return u.String()
+}
+
 type callType int8
 
 const (
@@ -133,7 +270,7 @@ const (
 func (c *Client) Query(ctx context.Context, db string, query Statement, options ...QueryOption) (*RowIterator, error) {
 	ctx, cancel := contextSetup(ctx) // Note: cancel is called when *RowIterator has Stop() called.
 
-	opts, err := setQueryOptions(ctx, errors.OpQuery, query, queryCall, options...)
+	opts, err := setQueryOptions(ctx, errors.OpQuery, query, queryCall, c.defaultQueryOptions, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -143,15 +280,26 @@ func (c *Client) Query(ctx context.Context, db string, query Statement, options
 		return nil, err
 	}
 
+	timer := newFrameTimer(errors.OpQuery, opts, cancel)
+	timer.wait()
 	execResp, err := conn.query(ctx, db, query, opts)
 	if err != nil {
 		cancel()
-		return nil, err
+		return nil, timer.err(err)
 	}
 
 	var header v2.DataSetHeader
 
-	ff := <-execResp.frameCh
+	var ff frames.Frame
+	select {
+	case ff = <-execResp.frameCh:
+	case <-ctx.Done():
+	}
+	timer.frame()
+	if err := timer.err(nil); err != nil {
+		cancel()
+		return nil, err
+	}
 	switch v := ff.(type) {
 	case v2.DataSetHeader:
 		header = v
@@ -161,23 +309,26 @@ func (c *Client) Query(ctx context.Context, db string, query Statement, options
 	}
 
 	iter, columnsReady := newRowIterator(ctx, cancel, execResp, header, errors.OpQuery)
+	iter.timer = timer
 
 	var sm stateMachine
 	if header.IsProgressive {
 		sm = &progressiveSM{
-			op:   errors.OpQuery,
-			iter: iter,
-			in:   execResp.frameCh,
-			ctx:  ctx,
-			wg:   &sync.WaitGroup{},
+			op:    errors.OpQuery,
+			iter:  iter,
+			in:    execResp.frameCh,
+			ctx:   ctx,
+			timer: timer,
+			wg:    &sync.WaitGroup{},
 		}
 	} else {
 		sm = &nonProgressiveSM{
-			op:   errors.OpQuery,
-			iter: iter,
-			in:   execResp.frameCh,
-			ctx:  ctx,
-			wg:   &sync.WaitGroup{},
+			op:    errors.OpQuery,
+			iter:  iter,
+			in:    execResp.frameCh,
+			ctx:   ctx,
+			timer: timer,
+			wg:    &sync.WaitGroup{},
 		}
 	}
 	go runSM(sm)
@@ -190,7 +341,7 @@ func (c *Client) Query(ctx context.Context, db string, query Statement, options
 func (c *Client) QueryToJson(ctx context.Context, db string, query Statement, options ...QueryOption) (string, error) {
 	ctx, cancel := contextSetup(ctx) // Note: cancel is called when *RowIterator has Stop() called.
 
-	opts, err := setQueryOptions(ctx, errors.OpQuery, query, queryCall, options...)
+	opts, err := setQueryOptions(ctx, errors.OpQuery, query, queryCall, c.defaultQueryOptions, options...)
 	if err != nil {
 		return "", err
 	}
@@ -211,41 +362,48 @@ func (c *Client) QueryToJson(ctx context.Context, db string, query Statement, op
 
 // Mgmt is used to do management queries to Kusto.
 // Details can be found at: https://docs.microsoft.com/en-us/azure/kusto/management/
-// Mgmt accepts a Stmt, but that Stmt cannot have any query parameters attached at this time.
+// Query parameters, from a Stmt or the QueryParameters() option, are rendered as typed literals in the query part of
+// the command, as the service does not take query parameters with management commands: after "<|" they are bound with
+// let statements. Mgmt fails if the command has no query part, which includes .show commands piped into a query.
 // Note that the server has a timeout of 10 minutes for a management call by default unless the context deadline is set.
 // There is a maximum of 1 hour.
 func (c *Client) Mgmt(ctx context.Context, db string, query Statement, options ...QueryOption) (*RowIterator, error) {
-	if stmt, ok := query.(Stmt); ok {
-		if !stmt.params.IsZero() || !stmt.defs.IsZero() {
-			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "a Mgmt() call cannot accept a Stmt object that has Definitions or Parameters attached")
-		}
-	}
-
 	ctx, cancel := contextSetup(ctx) // Note: cancel is called when *RowIterator has Stop() called.
 
-	opts, err := setQueryOptions(ctx, errors.OpQuery, query, mgmtCall, options...)
+	opts, err := setQueryOptions(ctx, errors.OpQuery, query, mgmtCall, c.defaultQueryOptions, options...)
 	if err != nil {
 		return nil, err
 	}
 
+	query, err = inlineMgmtParameters(query, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
 	conn, err := c.getConn(mgmtCall, connOptions{queryOptions: opts})
 	if err != nil {
 		return nil, err
 	}
 
+	// The first frame timeout runs until the state machine receives the first table.
+	timer := newFrameTimer(errors.OpMgmt, opts, cancel)
+	timer.wait()
 	execResp, err := conn.mgmt(ctx, db, query, opts)
 	if err != nil {
 		cancel()
-		return nil, err
+		return nil, timer.err(err)
 	}
 
 	iter, columnsReady := newRowIterator(ctx, cancel, execResp, v2.DataSetHeader{}, errors.OpMgmt)
+	iter.timer = timer
 	sm := &v1SM{
-		op:   errors.OpQuery,
-		iter: iter,
-		in:   execResp.frameCh,
-		ctx:  ctx,
-		wg:   &sync.WaitGroup{},
+		op:    errors.OpQuery,
+		iter:  iter,
+		in:    execResp.frameCh,
+		ctx:   ctx,
+		timer: timer,
+		wg:    &sync.WaitGroup{},
 	}
 
 	go runSM(sm)
@@ -255,7 +413,8 @@ func (c *Client) Mgmt(ctx context.Context, db string, query Statement, options .
 	return iter, nil
 }
 
-func setQueryOptions(ctx context.Context, op errors.Op, query Statement, queryType int, options ...QueryOption) (*queryOptions, error) {
+// setQueryOptions applies the defaults of the client, then the options of the call.
+func setQueryOptions(ctx context.Context, op errors.Op, query Statement, queryType int, defaults []QueryOption, options ...QueryOption) (*queryOptions, error) {
 	opt := &queryOptions{
 		requestProperties: &requestProperties{
 			Options: map[string]interface{}{},
@@ -268,6 +427,11 @@ func setQueryOptions(ctx context.Context, op errors.Op, query Statement, queryTy
 		opt.requestProperties.Options[RequestProgressiveEnabledValue] = true
 	}
 
+	for _, o := range defaults {
+		if err := o(opt); err != nil {
+			return nil, errors.ES(op, errors.KClientArgs, "the default QueryOptions of the client were incorrect: %s", err).SetNoRetry()
+		}
+	}
 	for _, o := range options {
 		if err := o(opt); err != nil {
 			return nil, errors.ES(op, errors.KClientArgs, "QueryValues in the the Stmt were incorrect: %s", err).SetNoRetry()
@@ -329,15 +493,13 @@ func (c *Client) getConn(callType callType, options connOptions) (queryer, error
 				return c.ingestConn, nil
 			}
 
-			u, _ := url.Parse(c.endpoint) // Don't care about the error
-			u.Host = "ingest-" + u.Host
 			auth := c.auth
 			var details *ClientDetails
 			if innerConn, ok := c.conn.(*Conn); ok {
 				details = innerConn.clientDetails
 			}
 
-			iconn, err := NewConn(u.String(), auth, c.http, details)
+			iconn, err := NewConn(c.IngestionURI(), auth, c.http, details, c.ConnOptions()...)
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,26 @@
+/*
+Package mgmt provides typed wrappers over Client.Mgmt() for common management commands, so that callers don't have
+to build management strings (and get identifier quoting right) themselves.
+
+All identifiers (tables, columns) are escaped with kql.NormalizeName, so names such as "my-table" are safe to pass as is.
+
+	client, err := kusto.New(kcsb)
+	...
+	err = mgmt.CreateMerge(ctx, client, "db", "my-table", table.Columns{
+		{Name: "Timestamp", Type: types.DateTime},
+		{Name: "Message", Type: types.String},
+	}, mgmt.WithFolder("logs"))
+	...
+	schema, err := mgmt.GetTableSchema(ctx, client, "db", "my-table")
+
+PlanMigration() compares a desired DatabaseSchema, built in Go or loaded from a YAML or JSON file, to the live
+database and returns the commands that bring the database to that state. Review the plan, then apply it:
+
+	desired, err := mgmt.LoadDatabaseSchema("schema.yaml")
+	...
+	plan, err := mgmt.PlanMigration(ctx, client, "db", desired)
+	...
+	fmt.Println(plan)
+	err = plan.Apply(ctx, client, "db")
+*/
+package mgmt
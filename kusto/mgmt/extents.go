@@ -0,0 +1,221 @@
+package mgmt
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/google/uuid"
+)
+
+// Extent is a row of the ".show table T extents" command.
+type Extent struct {
+	ID                uuid.UUID `kusto:"ExtentId"`
+	DatabaseName      string
+	TableName         string
+	MinCreatedOn      time.Time
+	MaxCreatedOn      time.Time
+	OriginalSize      float64
+	ExtentSize        float64
+	CompressedSize    float64
+	IndexSize         float64
+	Blocks            int64
+	Segments          int64
+	RowCount          int64
+	ExtentContainerID uuid.UUID `kusto:"ExtentContainerId"`
+	// Tags holds the extent tags, one per line. Use TagList() to split them.
+	Tags        string
+	Kind        string
+	DeletedRows int64
+}
+
+// TagList returns the tags of the extent, such as "drop-by:2023-01" or "ingest-by:batch-42".
+func (e Extent) TagList() []string {
+	return strings.FieldsFunc(e.Tags, func(r rune) bool { return r == '\r' || r == '\n' })
+}
+
+// ExtentChange is a row of the result of the ".move extents", ".replace extents" and ".merge" commands.
+type ExtentChange struct {
+	OriginalExtentID uuid.UUID `kusto:"OriginalExtentId"`
+	ResultExtentID   uuid.UUID `kusto:"ResultExtentId"`
+	Details          string
+	// Duration is only set by ".merge".
+	Duration time.Duration
+}
+
+// DroppedExtent is a row of the result of the ".drop extents" command.
+type DroppedExtent struct {
+	ID        uuid.UUID `kusto:"ExtentId"`
+	TableName string
+	CreatedOn time.Time
+}
+
+// DetachedExtent is a row of the result of the ".detach extents" command.
+type DetachedExtent struct {
+	ID        uuid.UUID `kusto:"ExtentId"`
+	TableName string
+	// MetadataURI is the metadata file of the extent, which AttachExtents() attaches it back by.
+	MetadataURI string `kusto:"ExtentMetadataUri"`
+}
+
+// ExtentSelector selects extents of a table. Create one with AllExtents(), ExtentsByID() or ExtentsByTag().
+type ExtentSelector struct {
+	table string
+	ids   []uuid.UUID
+	tags  []string
+	all   bool
+}
+
+// AllExtents selects every extent of table tableName.
+func AllExtents(tableName string) ExtentSelector {
+	return ExtentSelector{table: tableName, all: true}
+}
+
+// ExtentsByID selects the extents of table tableName with the given ids.
+func ExtentsByID(tableName string, ids ...uuid.UUID) ExtentSelector {
+	return ExtentSelector{table: tableName, ids: ids}
+}
+
+// ExtentsByTag selects the extents of table tableName that have all the given tags, such as "drop-by:2023-01".
+func ExtentsByTag(tableName string, tags ...string) ExtentSelector {
+	return ExtentSelector{table: tableName, tags: tags}
+}
+
+// show adds to stmt the ".show table T extents" command that lists the extents selected by s.
+func (s ExtentSelector) show(stmt *kql.Builder) (*kql.Builder, error) {
+	if s.table == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "the extent selector must be created with AllExtents(), ExtentsByID() or ExtentsByTag()").SetNoRetry()
+	}
+	if !s.all && len(s.ids) == 0 && len(s.tags) == 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "no extent ids or tags were given for table %q", s.table).SetNoRetry()
+	}
+
+	stmt.AddLiteral(".show table ").AddTable(s.table).AddLiteral(" extents")
+	addExtentIDs(stmt, s.ids)
+	for i, tag := range s.tags {
+		if tag == "" {
+			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "extent tags cannot be empty").SetNoRetry()
+		}
+		if i == 0 {
+			stmt.AddLiteral(" where ")
+		} else {
+			stmt.AddLiteral(" and ")
+		}
+		stmt.AddLiteral("tags has ").AddString(tag)
+	}
+	return stmt, nil
+}
+
+// addExtentIDs adds " (id1, id2, ...)" to stmt, if there are any ids. A uuid.UUID only formats to hex digits and
+// dashes, so it is safe as is.
+func addExtentIDs(stmt *kql.Builder, ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	stmt.AddLiteral(" (")
+	for i, id := range ids {
+		if i > 0 {
+			stmt.AddLiteral(", ")
+		}
+		stmt.AddUnsafe(id.String())
+	}
+	stmt.AddLiteral(")")
+}
+
+// ListExtents returns the extents selected by s.
+func ListExtents(ctx context.Context, client Client, db string, s ExtentSelector) ([]Extent, error) {
+	stmt, err := s.show(kql.New(""))
+	if err != nil {
+		return nil, err
+	}
+	return rowsTo[Extent](ctx, client, db, stmt)
+}
+
+// MoveExtents moves the extents selected by s to table toTable, with ".move extents". The tables must have the
+// same schema.
+func MoveExtents(ctx context.Context, client Client, db string, s ExtentSelector, toTable string) ([]ExtentChange, error) {
+	stmt, err := s.show(kql.New(".move extents to table ").AddTable(toTable).AddLiteral(" <| "))
+	if err != nil {
+		return nil, err
+	}
+	return rowsTo[ExtentChange](ctx, client, db, stmt)
+}
+
+// ReplaceExtents atomically drops the extents selected by drop from table tableName, and moves the extents selected
+// by move into it, with ".replace extents". This is how a slice of data is reprocessed without a window where it is
+// missing or duplicated.
+func ReplaceExtents(ctx context.Context, client Client, db, tableName string, drop, move ExtentSelector) ([]ExtentChange, error) {
+	stmt, err := drop.show(kql.New(".replace extents in table ").AddTable(tableName).AddLiteral(" <| {"))
+	if err != nil {
+		return nil, err
+	}
+	if stmt, err = move.show(stmt.AddLiteral("}, {")); err != nil {
+		return nil, err
+	}
+	return rowsTo[ExtentChange](ctx, client, db, stmt.AddLiteral("}"))
+}
+
+// DropExtents drops the extents selected by s, with ".drop extents". The data is no longer available to queries,
+// but can be recovered during the recoverability period of the retention policy.
+func DropExtents(ctx context.Context, client Client, db string, s ExtentSelector) ([]DroppedExtent, error) {
+	stmt, err := s.show(kql.New(".drop extents <| "))
+	if err != nil {
+		return nil, err
+	}
+	return rowsTo[DroppedExtent](ctx, client, db, stmt)
+}
+
+// DropExtentsByTag drops the extents of table tableName tagged "drop-by:<tag>", as set at ingestion time with
+// ingest.Tags([]string{"drop-by:<tag>"}).
+func DropExtentsByTag(ctx context.Context, client Client, db, tableName, tag string) ([]DroppedExtent, error) {
+	if tag == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "the drop-by tag cannot be empty").SetNoRetry()
+	}
+	return DropExtents(ctx, client, db, ExtentsByTag(tableName, "drop-by:"+tag))
+}
+
+// MergeExtents merges the extents of table tableName with the given ids, with ".merge". If rebuild is true, the
+// extents are rebuilt instead of only having their indexes merged.
+func MergeExtents(ctx context.Context, client Client, db, tableName string, rebuild bool, ids ...uuid.UUID) ([]ExtentChange, error) {
+	if len(ids) < 2 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "at least 2 extents are needed for a merge, got %d", len(ids)).SetNoRetry()
+	}
+
+	stmt := kql.New(".merge ").AddTable(tableName)
+	addExtentIDs(stmt, ids)
+	if rebuild {
+		stmt.AddLiteral(" with (rebuild=true)")
+	}
+	return rowsTo[ExtentChange](ctx, client, db, stmt)
+}
+
+// AttachExtents attaches to table tableName the extents described by the metadata files at metadataURIs, with
+// ".attach extents by metadata". The URIs must include credentials the service can read them with.
+func AttachExtents(ctx context.Context, client Client, db, tableName string, metadataURIs ...string) error {
+	if len(metadataURIs) == 0 {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "no extent metadata URIs were given").SetNoRetry()
+	}
+
+	stmt := kql.New(".attach extents into table ").AddTable(tableName).AddLiteral(" by metadata <| ")
+	for i, uri := range metadataURIs {
+		if i > 0 {
+			stmt.AddLiteral(", ")
+		}
+		// Hide the URIs, as they hold secrets.
+		stmt.AddUnsafe(kql.QuoteString(uri, true))
+	}
+	return run(ctx, client, db, stmt)
+}
+
+// DetachExtents detaches the extents selected by s from their table, with ".detach extents". The data is no longer
+// available to queries, but stays in storage, and the metadata files of the results attach it again with
+// AttachExtents().
+func DetachExtents(ctx context.Context, client Client, db string, s ExtentSelector) ([]DetachedExtent, error) {
+	stmt, err := s.show(kql.New(".detach extents <| "))
+	if err != nil {
+		return nil, err
+	}
+	return rowsTo[DetachedExtent](ctx, client, db, stmt)
+}
@@ -0,0 +1,194 @@
+package mgmt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	extent1 = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	extent2 = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+)
+
+func TestListExtents(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := &fakeClient{results: []fakeResult{{
+		columns: table.Columns{
+			{Name: "ExtentId", Type: types.GUID},
+			{Name: "TableName", Type: types.String},
+			{Name: "MinCreatedOn", Type: types.DateTime},
+			{Name: "MaxCreatedOn", Type: types.DateTime},
+			{Name: "OriginalSize", Type: types.Real},
+			{Name: "RowCount", Type: types.Long},
+			{Name: "Tags", Type: types.String},
+		},
+		rows: []value.Values{{
+			value.GUID{Value: extent1, Valid: true},
+			value.String{Value: "Logs", Valid: true},
+			value.DateTime{Value: created, Valid: true},
+			value.DateTime{Value: created.Add(time.Hour), Valid: true},
+			value.Real{Value: 1024, Valid: true},
+			value.Long{Value: 10, Valid: true},
+			value.String{Value: "drop-by:2023-01\r\ningest-by:batch-1", Valid: true},
+		}},
+	}}}
+
+	extents, err := ListExtents(context.Background(), client, "db", ExtentsByTag("Logs", "drop-by:2023-01", "ingest-by:batch-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`.show table Logs extents where tags has "drop-by:2023-01" and tags has "ingest-by:batch-1"`}, client.stmts)
+	require.Len(t, extents, 1)
+	assert.Equal(t, extent1, extents[0].ID)
+	assert.Equal(t, created.Add(time.Hour), extents[0].MaxCreatedOn)
+	assert.Equal(t, 1024.0, extents[0].OriginalSize)
+	assert.Equal(t, int64(10), extents[0].RowCount)
+	assert.Equal(t, []string{"drop-by:2023-01", "ingest-by:batch-1"}, extents[0].TagList())
+}
+
+func TestDetachExtents(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{results: []fakeResult{{
+		columns: table.Columns{
+			{Name: "ExtentId", Type: types.GUID},
+			{Name: "TableName", Type: types.String},
+			{Name: "ExtentMetadataUri", Type: types.String},
+		},
+		rows: []value.Values{{
+			value.GUID{Value: extent1, Valid: true},
+			value.String{Value: "Logs", Valid: true},
+			value.String{Value: "https://storage/metadata.csv", Valid: true},
+		}},
+	}}}
+
+	extents, err := DetachExtents(context.Background(), client, "db", ExtentsByTag("Logs", "drop-by:2023-01"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`.detach extents <| .show table Logs extents where tags has "drop-by:2023-01"`}, client.stmts)
+	assert.Equal(t, []DetachedExtent{{ID: extent1, TableName: "Logs", MetadataURI: "https://storage/metadata.csv"}}, extents)
+}
+
+func TestExtentCommands(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tests := []struct {
+		desc    string
+		run     func(client Client) error
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "MoveAll",
+			run: func(client Client) error {
+				_, err := MoveExtents(ctx, client, "db", AllExtents("Staging"), "Logs")
+				return err
+			},
+			want: ".move extents to table Logs <| .show table Staging extents",
+		},
+		{
+			desc: "MoveByID",
+			run: func(client Client) error {
+				_, err := MoveExtents(ctx, client, "db", ExtentsByID("my-table", extent1, extent2), "Logs")
+				return err
+			},
+			want: `.move extents to table Logs <| .show table ["my-table"] extents (` + extent1.String() + ", " + extent2.String() + ")",
+		},
+		{
+			desc: "Replace",
+			run: func(client Client) error {
+				_, err := ReplaceExtents(ctx, client, "db", "Logs", ExtentsByTag("Logs", "drop-by:2023-01"), AllExtents("Staging"))
+				return err
+			},
+			want: `.replace extents in table Logs <| {.show table Logs extents where tags has "drop-by:2023-01"}, {.show table Staging extents}`,
+		},
+		{
+			desc: "DropByTag",
+			run: func(client Client) error {
+				_, err := DropExtentsByTag(ctx, client, "db", "Logs", "2023-01")
+				return err
+			},
+			want: `.drop extents <| .show table Logs extents where tags has "drop-by:2023-01"`,
+		},
+		{
+			desc: "Merge",
+			run: func(client Client) error {
+				_, err := MergeExtents(ctx, client, "db", "Logs", true, extent1, extent2)
+				return err
+			},
+			want: ".merge Logs (" + extent1.String() + ", " + extent2.String() + ") with (rebuild=true)",
+		},
+		{
+			desc: "Attach",
+			run: func(client Client) error {
+				return AttachExtents(ctx, client, "db", "Logs", "https://storage/metadata.csv;secret")
+			},
+			want: `.attach extents into table Logs by metadata <| h"https://storage/metadata.csv;secret"`,
+		},
+		{
+			desc: "Detach",
+			run: func(client Client) error {
+				_, err := DetachExtents(ctx, client, "db", ExtentsByID("Logs", extent1))
+				return err
+			},
+			want: ".detach extents <| .show table Logs extents (" + extent1.String() + ")",
+		},
+		{
+			desc: "EmptySelector",
+			run: func(client Client) error {
+				_, err := DropExtents(ctx, client, "db", ExtentsByID("Logs"))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			desc: "ZeroSelector",
+			run: func(client Client) error {
+				_, err := ListExtents(ctx, client, "db", ExtentSelector{})
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			desc: "EmptyDropByTag",
+			run: func(client Client) error {
+				_, err := DropExtentsByTag(ctx, client, "db", "Logs", "")
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			desc: "MergeSingle",
+			run: func(client Client) error {
+				_, err := MergeExtents(ctx, client, "db", "Logs", false, extent1)
+				return err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeClient{}
+			err := test.run(client)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, client.stmts)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{test.want}, client.stmts)
+		})
+	}
+}
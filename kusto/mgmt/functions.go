@@ -0,0 +1,99 @@
+package mgmt
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+)
+
+// FunctionSchema is the signature of a stored function.
+type FunctionSchema struct {
+	Name       string
+	Folder     string
+	DocString  string
+	Parameters []FunctionParameter
+	Body       string
+	// OutputColumns are the columns of the result of the function, if the service could infer them.
+	OutputColumns table.Columns
+}
+
+// FunctionParameter is a parameter of a stored function.
+type FunctionParameter struct {
+	Name string
+	// Type is the type of a scalar parameter. It is empty for tabular parameters.
+	Type types.Column
+	// Columns are the columns required by a tabular parameter. It is empty for scalar parameters, and for tabular
+	// parameters that accept any table.
+	Columns table.Columns
+	// Default is the default value of the parameter as a KQL literal, if it has one.
+	Default *string
+}
+
+// Tabular returns true if the parameter takes a table.
+func (p FunctionParameter) Tabular() bool {
+	return p.Type == ""
+}
+
+// ListFunctionSchemas returns the signatures of the stored functions of database db, sorted by name.
+func ListFunctionSchemas(ctx context.Context, client Client, db string) ([]FunctionSchema, error) {
+	databases, err := showDatabaseSchemaJSON(ctx, client, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []FunctionSchema
+	for _, d := range databases {
+		for _, f := range d.Functions {
+			s, err := f.schema()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (f functionJSON) schema() (FunctionSchema, error) {
+	s := FunctionSchema{Name: f.Name, Folder: f.Folder, DocString: f.DocString, Body: f.Body}
+
+	columnType := func(name, cslType string) (types.Column, error) {
+		t := types.Column(cslType)
+		if !t.Valid() {
+			return "", errors.ES(errors.OpMgmt, errors.KInternal, "%q of function %q has unknown type %q", name, f.Name, cslType).SetNoRetry()
+		}
+		return t, nil
+	}
+
+	for _, p := range f.InputParameters {
+		param := FunctionParameter{Name: p.Name, Default: p.CslDefaultValue}
+		if p.CslType != "" {
+			t, err := columnType(p.Name, p.CslType)
+			if err != nil {
+				return FunctionSchema{}, err
+			}
+			param.Type = t
+		}
+		for _, c := range p.Columns {
+			t, err := columnType(p.Name+"."+c.Name, c.CslType)
+			if err != nil {
+				return FunctionSchema{}, err
+			}
+			param.Columns = append(param.Columns, table.Column{Name: c.Name, Type: t})
+		}
+		s.Parameters = append(s.Parameters, param)
+	}
+
+	for _, c := range f.OutputColumns {
+		t, err := columnType(c.Name, c.CslType)
+		if err != nil {
+			return FunctionSchema{}, err
+		}
+		s.OutputColumns = append(s.OutputColumns, table.Column{Name: c.Name, Type: t})
+	}
+	return s, nil
+}
@@ -0,0 +1,61 @@
+package mgmt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFunctionSchemas(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{results: liveSchemaResults(`{"Databases": {"db": {"Functions": {
+		"RecentLogs": {
+			"Name": "RecentLogs",
+			"InputParameters": [{"Name": "since", "CslType": "timespan", "CslDefaultValue": "1h"}],
+			"Body": "{ Logs | where Timestamp > ago(since) }",
+			"Folder": "views",
+			"OutputColumns": [{"Name": "Timestamp", "CslType": "datetime"}, {"Name": "Message", "CslType": "string"}]
+		},
+		"Filter": {
+			"Name": "Filter",
+			"InputParameters": [{"Name": "T", "Columns": [{"Name": "a", "CslType": "long"}]}],
+			"Body": "{ T }"
+		}
+	}}}}`)}
+
+	functions, err := ListFunctionSchemas(context.Background(), client, "db")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".show database schema as json"}, client.stmts)
+
+	oneHour := "1h"
+	assert.Equal(t, []FunctionSchema{
+		{
+			Name:       "Filter",
+			Parameters: []FunctionParameter{{Name: "T", Columns: table.Columns{{Name: "a", Type: types.Long}}}},
+			Body:       "{ T }",
+		},
+		{
+			Name:       "RecentLogs",
+			Folder:     "views",
+			Parameters: []FunctionParameter{{Name: "since", Type: types.Timespan, Default: &oneHour}},
+			Body:       "{ Logs | where Timestamp > ago(since) }",
+			OutputColumns: table.Columns{
+				{Name: "Timestamp", Type: types.DateTime},
+				{Name: "Message", Type: types.String},
+			},
+		},
+	}, functions)
+	assert.True(t, functions[0].Parameters[0].Tabular())
+	assert.False(t, functions[1].Parameters[0].Tabular())
+
+	client = &fakeClient{results: liveSchemaResults(`{"Databases": {"db": {"Functions": {
+		"F": {"Name": "F", "InputParameters": [{"Name": "x", "CslType": "integer"}], "Body": "{ 1 }"}
+	}}}}`)}
+	_, err = ListFunctionSchemas(context.Background(), client, "db")
+	assert.Error(t, err)
+}
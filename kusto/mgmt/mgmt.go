@@ -0,0 +1,56 @@
+package mgmt
+
+import (
+	"context"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+)
+
+// Client is the subset of *kusto.Client used by this package.
+type Client interface {
+	Mgmt(ctx context.Context, db string, query kusto.Statement, options ...kusto.QueryOption) (*kusto.RowIterator, error)
+}
+
+// run executes a management command whose result is not needed.
+func run(ctx context.Context, client Client, db string, stmt kusto.Statement, options ...kusto.QueryOption) error {
+	iter, err := client.Mgmt(ctx, db, stmt, options...)
+	if err != nil {
+		return err
+	}
+	defer iter.Stop()
+
+	return iter.DoOnRowOrError(func(r *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		return nil
+	})
+}
+
+// rowsTo executes a management command and decodes every row of its primary result into a T with Row.ToStruct().
+func rowsTo[T any](ctx context.Context, client Client, db string, stmt kusto.Statement, options ...kusto.QueryOption) ([]T, error) {
+	iter, err := client.Mgmt(ctx, db, stmt, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	var out []T
+	err = iter.DoOnRowOrError(func(r *table.Row, e *errors.Error) error {
+		if e != nil {
+			return e
+		}
+		var rec T
+		if err := r.ToStruct(&rec); err != nil {
+			return err
+		}
+		out = append(out, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package mgmt
+
+import (
+	"context"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+)
+
+// fakeClient records the management commands it receives, and replies to each with the next entry of results.
+type fakeClient struct {
+	stmts   []string
+	results []fakeResult
+}
+
+type fakeResult struct {
+	columns table.Columns
+	rows    []value.Values
+	err     error
+}
+
+func (f *fakeClient) Mgmt(_ context.Context, _ string, query kusto.Statement, _ ...kusto.QueryOption) (*kusto.RowIterator, error) {
+	f.stmts = append(f.stmts, query.String())
+
+	res := fakeResult{}
+	if len(f.results) > 0 {
+		res, f.results = f.results[0], f.results[1:]
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	if res.columns == nil {
+		res.columns = table.Columns{{Name: "Result", Type: types.String}}
+	}
+
+	rows, err := kusto.NewMockRows(res.columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range res.rows {
+		if err := rows.Row(r); err != nil {
+			return nil, err
+		}
+	}
+
+	iter := &kusto.RowIterator{}
+	if err := iter.Mock(rows); err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
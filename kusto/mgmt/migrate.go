@@ -0,0 +1,487 @@
+package mgmt
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+)
+
+// MigrationStep is a management command of a MigrationPlan.
+type MigrationStep struct {
+	// Description says what the step does, such as `add columns to table "Logs"`.
+	Description string
+	// Command is the management command.
+	Command kusto.Statement
+	// Destructive is true for steps that drop data or entities or change column types, which are only planned with
+	// AllowDrops().
+	Destructive bool
+}
+
+// MigrationPlan is the ordered list of management commands that bring a database to a desired state. Create one
+// with PlanMigration(), review it, then run it with Apply().
+type MigrationPlan struct {
+	Steps []MigrationStep
+}
+
+// Empty returns true if the database is already in the desired state.
+func (p *MigrationPlan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+// String returns the commands of the plan, separated by blank lines.
+func (p *MigrationPlan) String() string {
+	commands := make([]string, 0, len(p.Steps))
+	for _, s := range p.Steps {
+		commands = append(commands, s.Command.String())
+	}
+	return strings.Join(commands, "\n\n")
+}
+
+// MigrationOption is an optional argument to PlanMigration().
+type MigrationOption func(o *migrationOptions)
+
+type migrationOptions struct {
+	allowDrops bool
+}
+
+// AllowDrops plans to drop the tables, columns, functions and ingestion mappings that are not in the desired state,
+// and to change the type of columns. Without it, they are left as they are, and a column type change is an error.
+func AllowDrops() MigrationOption {
+	return func(o *migrationOptions) {
+		o.allowDrops = true
+	}
+}
+
+// PlanMigration compares desired to the live schema of database db, and returns the commands that make db match it.
+//
+// Steps are ordered so that entities exist before they are used: tables and columns first, then ingestion
+// mappings, functions and policies, and drops last. Column types are changed with ".alter column" under AllowDrops(),
+// which makes the existing data of the column unreadable. Column order is not changed.
+func PlanMigration(ctx context.Context, client Client, db string, desired *DatabaseSchema, options ...MigrationOption) (*MigrationPlan, error) {
+	opts := migrationOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+	live, err := GetDatabaseSchema(ctx, client, db)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &planner{plan: &MigrationPlan{}}
+	liveTables := map[string]TableDefinition{}
+	for _, t := range live.Tables {
+		liveTables[t.Name] = t
+	}
+
+	for _, want := range desired.Tables {
+		if got, ok := liveTables[want.Name]; ok {
+			if err := p.alterTable(want, got, opts.allowDrops); err != nil {
+				return nil, err
+			}
+		} else {
+			if err := p.createTable(want); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, want := range desired.Tables {
+		p.mappings(want, liveTables[want.Name])
+	}
+
+	liveFunctions := map[string]FunctionDefinition{}
+	for _, f := range live.Functions {
+		liveFunctions[f.Name] = f
+	}
+	for _, want := range desired.Functions {
+		if got, ok := liveFunctions[want.Name]; !ok || !sameFunction(want, got) {
+			p.add(MigrationStep{Description: "create or alter function " + quoteName(want.Name), Command: createFunctionStmt(want)})
+		}
+	}
+
+	if err := p.policies(ctx, client, db, DatabaseEntity(db), desired.Policies, true); err != nil {
+		return nil, err
+	}
+	for _, want := range desired.Tables {
+		_, exists := liveTables[want.Name]
+		if err := p.policies(ctx, client, db, TableEntity(want.Name), want.Policies, exists); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.allowDrops {
+		p.drops(desired, live)
+	}
+
+	return p.plan, nil
+}
+
+// planner accumulates the steps of a MigrationPlan.
+type planner struct {
+	plan *MigrationPlan
+}
+
+func (p *planner) add(step MigrationStep) {
+	p.plan.Steps = append(p.plan.Steps, step)
+}
+
+func (p *planner) createTable(want TableDefinition) error {
+	var options []TableOption
+	if want.Folder != "" {
+		options = append(options, WithFolder(want.Folder))
+	}
+	if want.DocString != "" {
+		options = append(options, WithDocString(want.DocString))
+	}
+	stmt, err := createStmt(kql.New(".create table "), want.Name, want.columns(), options)
+	if err != nil {
+		return err
+	}
+	p.add(MigrationStep{Description: "create table " + quoteName(want.Name), Command: stmt})
+	return nil
+}
+
+// alterTable plans the changes to an existing table. Changing the type of a column is destructive, so it is only
+// planned if allowDrops is set.
+func (p *planner) alterTable(want, got TableDefinition, allowDrops bool) error {
+	gotTypes := map[string]ColumnDefinition{}
+	for _, c := range got.Columns {
+		gotTypes[c.Name] = c
+	}
+
+	var missing []ColumnDefinition
+	for _, c := range want.Columns {
+		g, ok := gotTypes[c.Name]
+		if !ok {
+			missing = append(missing, c)
+			continue
+		}
+		if g.Type != c.Type {
+			if !allowDrops {
+				return errors.ES(errors.OpMgmt, errors.KClientArgs, "column %s is %s in the database and %s in the desired schema, changing its type needs AllowDrops()", quoteName(want.Name+"."+c.Name), g.Type, c.Type).SetNoRetry()
+			}
+			stmt := kql.New(".alter column ").AddTable(want.Name).AddLiteral(".").AddColumn(c.Name).
+				AddLiteral(" type=").AddKeyword(string(c.Type))
+			p.add(MigrationStep{
+				Description: "change the type of column " + quoteName(want.Name+"."+c.Name) + " from " + string(g.Type) + " to " + string(c.Type),
+				Command:     stmt,
+				Destructive: true,
+			})
+		}
+	}
+
+	if len(missing) > 0 {
+		stmt := kql.New(".alter-merge table ").AddTable(want.Name).AddLiteral(" (")
+		for i, c := range missing {
+			if i > 0 {
+				stmt.AddLiteral(", ")
+			}
+			stmt.AddColumn(c.Name).AddLiteral(":").AddKeyword(string(c.Type))
+		}
+		p.add(MigrationStep{Description: "add columns to table " + quoteName(want.Name), Command: stmt.AddLiteral(")")})
+	}
+
+	if want.Folder != "" && want.Folder != got.Folder {
+		p.add(MigrationStep{
+			Description: "move table " + quoteName(want.Name) + " to folder " + quoteName(want.Folder),
+			Command:     kql.New(".alter table ").AddTable(want.Name).AddLiteral(" folder ").AddString(want.Folder),
+		})
+	}
+	if want.DocString != "" && want.DocString != got.DocString {
+		p.add(MigrationStep{
+			Description: "set the docstring of table " + quoteName(want.Name),
+			Command:     kql.New(".alter table ").AddTable(want.Name).AddLiteral(" docstring ").AddString(want.DocString),
+		})
+	}
+	return nil
+}
+
+func (p *planner) mappings(want, got TableDefinition) {
+	gotMappings := map[string]IngestionMappingDefinition{}
+	for _, m := range got.IngestionMappings {
+		gotMappings[mappingKey(m.Kind, m.Name)] = m
+	}
+
+	for _, m := range want.IngestionMappings {
+		if g, ok := gotMappings[mappingKey(m.Kind, m.Name)]; ok && jsonSubset(m.Mapping, g.Mapping) {
+			continue
+		}
+		stmt := kql.New(".create-or-alter table ").AddTable(want.Name).AddLiteral(" ingestion ").
+			AddKeyword(strings.ToLower(m.Kind)).AddLiteral(" mapping ").AddString(m.Name).AddLiteral(" ").
+			AddString(string(m.Mapping))
+		p.add(MigrationStep{
+			Description: "create or alter " + strings.ToLower(m.Kind) + " mapping " + quoteName(m.Name) + " of table " + quoteName(want.Name),
+			Command:     stmt,
+		})
+	}
+}
+
+// policies plans the policies of entity. The live policies of an entity that does not exist yet cannot be shown, so
+// all of its policies are set.
+func (p *planner) policies(ctx context.Context, client Client, db string, entity Entity, policies *Policies, exists bool) error {
+	for _, want := range policies.list() {
+		var got []byte
+		if exists {
+			var err error
+			got, err = showPolicyJSON(ctx, client, db, entity, want)
+			if err != nil {
+				return err
+			}
+		}
+		same, err := samePolicy(want, got)
+		if err != nil {
+			return err
+		}
+		if same {
+			continue
+		}
+
+		stmt, err := alterPolicyStmt(entity, want)
+		if err != nil {
+			return err
+		}
+		p.add(MigrationStep{
+			Description: "set the " + want.PolicyKind() + " policy of " + entity.kind + " " + quoteName(entity.name),
+			Command:     stmt,
+		})
+	}
+	return nil
+}
+
+func (p *planner) drops(desired, live *DatabaseSchema) {
+	wantTables := map[string]TableDefinition{}
+	for _, t := range desired.Tables {
+		wantTables[t.Name] = t
+	}
+	wantFunctions := map[string]bool{}
+	for _, f := range desired.Functions {
+		wantFunctions[f.Name] = true
+	}
+
+	for _, got := range live.Tables {
+		want, ok := wantTables[got.Name]
+		if !ok {
+			continue
+		}
+		wantMappings := map[string]bool{}
+		for _, m := range want.IngestionMappings {
+			wantMappings[mappingKey(m.Kind, m.Name)] = true
+		}
+		for _, m := range got.IngestionMappings {
+			if wantMappings[mappingKey(m.Kind, m.Name)] {
+				continue
+			}
+			p.add(MigrationStep{
+				Description: "drop " + strings.ToLower(m.Kind) + " mapping " + quoteName(m.Name) + " of table " + quoteName(got.Name),
+				Command: kql.New(".drop table ").AddTable(got.Name).AddLiteral(" ingestion ").
+					AddKeyword(strings.ToLower(m.Kind)).AddLiteral(" mapping ").AddString(m.Name),
+				Destructive: true,
+			})
+		}
+	}
+
+	for _, got := range live.Functions {
+		if wantFunctions[got.Name] {
+			continue
+		}
+		p.add(MigrationStep{
+			Description: "drop function " + quoteName(got.Name),
+			Command:     kql.New(".drop function ").AddFunction(got.Name),
+			Destructive: true,
+		})
+	}
+
+	for _, got := range live.Tables {
+		want, ok := wantTables[got.Name]
+		if !ok {
+			p.add(MigrationStep{
+				Description: "drop table " + quoteName(got.Name),
+				Command:     kql.New(".drop table ").AddTable(got.Name),
+				Destructive: true,
+			})
+			continue
+		}
+
+		wantColumns := map[string]bool{}
+		for _, c := range want.Columns {
+			wantColumns[c.Name] = true
+		}
+		for _, c := range got.Columns {
+			if wantColumns[c.Name] {
+				continue
+			}
+			p.add(MigrationStep{
+				Description: "drop column " + quoteName(got.Name+"."+c.Name),
+				Command:     kql.New(".drop column ").AddTable(got.Name).AddLiteral(".").AddColumn(c.Name),
+				Destructive: true,
+			})
+		}
+	}
+}
+
+// createFunctionStmt builds the ".create-or-alter function" command for f. The parameters and body are KQL, and
+// are added as is.
+func createFunctionStmt(f FunctionDefinition) *kql.Builder {
+	stmt := kql.New(".create-or-alter function ")
+	if f.Folder != "" || f.DocString != "" {
+		stmt.AddLiteral("with (")
+		if f.Folder != "" {
+			stmt.AddLiteral("folder=").AddString(f.Folder)
+		}
+		if f.DocString != "" {
+			if f.Folder != "" {
+				stmt.AddLiteral(", ")
+			}
+			stmt.AddLiteral("docstring=").AddString(f.DocString)
+		}
+		stmt.AddLiteral(") ")
+	}
+	return stmt.AddFunction(f.Name).AddLiteral("(").AddUnsafe(f.Parameters).AddLiteral(") {\n").
+		AddUnsafe(functionBody(f.Body)).AddLiteral("\n}")
+}
+
+// functionBody returns body without the enclosing braces and surrounding whitespace.
+func functionBody(body string) string {
+	body = strings.TrimSpace(body)
+	if strings.HasPrefix(body, "{") && strings.HasSuffix(body, "}") {
+		body = strings.TrimSpace(body[1 : len(body)-1])
+	}
+	return body
+}
+
+// sameFunction compares functions ignoring whitespace differences, as the service reformats what it stores. An empty
+// folder or docstring in want is not managed.
+func sameFunction(want, got FunctionDefinition) bool {
+	if want.Folder != "" && want.Folder != got.Folder {
+		return false
+	}
+	if want.DocString != "" && want.DocString != got.DocString {
+		return false
+	}
+	return strings.Join(strings.Fields(want.Parameters), "") == strings.Join(strings.Fields(got.Parameters), "") &&
+		strings.Join(strings.Fields(functionBody(want.Body)), " ") == strings.Join(strings.Fields(functionBody(got.Body)), " ")
+}
+
+// samePolicy returns true if every field set in want has the same value in got, the JSON of the live policy. got is
+// first decoded into the type of want, so that equivalent serializations (such as {"Value": "1.00:00:00"} for a
+// timespan) compare equal.
+func samePolicy(want Policy, got []byte) (bool, error) {
+	if got == nil {
+		return false, nil
+	}
+
+	typed := reflect.New(reflect.TypeOf(want))
+	if err := json.Unmarshal(got, typed.Interface()); err != nil {
+		return false, errors.ES(errors.OpMgmt, errors.KInternal, "could not parse the live %s policy: %s", want.PolicyKind(), err).SetNoRetry()
+	}
+	gotJSON, err := json.Marshal(typed.Elem().Interface())
+	if err != nil {
+		return false, errors.E(errors.OpMgmt, errors.KInternal, err).SetNoRetry()
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		return false, errors.ES(errors.OpMgmt, errors.KClientArgs, "could not serialize the %s policy: %s", want.PolicyKind(), err).SetNoRetry()
+	}
+	return jsonSubset(wantJSON, gotJSON), nil
+}
+
+// jsonSubset returns true if every value in want is also in got. Objects in got may have more keys, arrays must
+// have the same length.
+func jsonSubset(want, got []byte) bool {
+	var w, g interface{}
+	if json.Unmarshal(want, &w) != nil || json.Unmarshal(got, &g) != nil {
+		return false
+	}
+	return subset(w, g)
+}
+
+func subset(want, got interface{}) bool {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			if !subset(v, g[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !subset(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
+
+func quoteName(name string) string {
+	return `"` + name + `"`
+}
+
+// ApplyOption is an optional argument to MigrationPlan.Apply().
+type ApplyOption func(o *applyOptions)
+
+type applyOptions struct {
+	script bool
+}
+
+// AsScript runs all the steps with ExecuteScript(), in a single ".execute database script" command, which stops at the
+// first failing command. This is not a transaction: the steps that ran before the failure are not rolled back.
+func AsScript() ApplyOption {
+	return func(o *applyOptions) {
+		o.script = true
+	}
+}
+
+// Apply runs the steps of the plan in database db, in order, and stops at the first failure.
+func (p *MigrationPlan) Apply(ctx context.Context, client Client, db string, options ...ApplyOption) error {
+	opts := applyOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	if p.Empty() {
+		return nil
+	}
+
+	if opts.script {
+		commands := make([]kusto.Statement, 0, len(p.Steps))
+		for _, s := range p.Steps {
+			commands = append(commands, s.Command)
+		}
+		_, err := ExecuteScript(ctx, client, db, commands)
+		if se, ok := err.(*ScriptError); ok {
+			return errors.ES(errors.OpMgmt, errors.KOther, "migration step %d of %d (%s) failed: %s", se.Index+1, len(p.Steps), p.Steps[se.Index].Description, se.Result.Reason)
+		}
+		return err
+	}
+
+	for i, s := range p.Steps {
+		if err := run(ctx, client, db, s.Command); err != nil {
+			if _, ok := err.(*errors.Error); ok {
+				return errors.W(err, errors.ES(errors.OpMgmt, errors.KOther, "migration step %d of %d (%s) failed", i+1, len(p.Steps), s.Description))
+			}
+			return errors.ES(errors.OpMgmt, errors.KOther, "migration step %d of %d (%s) failed: %s", i+1, len(p.Steps), s.Description, err)
+		}
+	}
+	return nil
+}
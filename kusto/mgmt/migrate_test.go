@@ -0,0 +1,219 @@
+package mgmt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func policyResult(policy string) fakeResult {
+	return fakeResult{
+		columns: table.Columns{
+			{Name: "PolicyName", Type: types.String},
+			{Name: "EntityName", Type: types.String},
+			{Name: "Policy", Type: types.String},
+			{Name: "EntityType", Type: types.String},
+		},
+		rows: []value.Values{{
+			value.String{Value: "Policy", Valid: true},
+			value.String{Value: "[db].[Logs]", Valid: true},
+			value.String{Value: policy, Valid: true},
+			value.String{Value: "Table", Valid: true},
+		}},
+	}
+}
+
+func testDesiredSchema() *DatabaseSchema {
+	return &DatabaseSchema{
+		Tables: []TableDefinition{
+			{
+				Name:   "Logs",
+				Folder: "raw",
+				Columns: []ColumnDefinition{
+					{Name: "Timestamp", Type: types.DateTime},
+					{Name: "Level", Type: types.Long},
+					{Name: "Message", Type: types.String},
+				},
+				IngestionMappings: []IngestionMappingDefinition{
+					{Name: "logs_csv", Kind: "csv", Mapping: json.RawMessage(`[{"Column": "Timestamp", "Properties": {"Ordinal": "0"}}]`)},
+					{Name: "logs_json", Kind: "json", Mapping: json.RawMessage(`[{"Column":"Timestamp","Properties":{"Path":"$.ts"}}]`)},
+				},
+				Policies: &Policies{
+					Retention: &RetentionPolicy{SoftDeletePeriod: Timespan(30 * 24 * time.Hour)},
+					Caching:   &CachingPolicy{DataHotSpan: Timespan(24 * time.Hour)},
+				},
+			},
+			{
+				Name:    "my-metrics",
+				Columns: []ColumnDefinition{{Name: "Value", Type: types.Real}},
+			},
+		},
+		Functions: []FunctionDefinition{
+			{Name: "RecentLogs", Parameters: "since: timespan", Body: "Logs | where Timestamp > ago(since)"},
+			{Name: "Errors", Body: "{ Logs | where Level > 2 }", Folder: "views"},
+		},
+	}
+}
+
+func TestPlanMigration(t *testing.T) {
+	t.Parallel()
+
+	results := func() []fakeResult {
+		return append(liveSchemaResults(testLiveSchema,
+			mappingRow("logs_csv", "Csv", `[{"Column":"Timestamp","DataType":"","Properties":{"Ordinal":"0"}}]`, "Logs"),
+			mappingRow("old_csv", "Csv", `[]`, "Logs"),
+		),
+			policyResult(`{"SoftDeletePeriod": "30.00:00:00", "Recoverability": "Enabled"}`),
+			policyResult("null"),
+		)
+	}
+
+	alter := ".alter column Logs.Level type=long"
+	steps := []string{
+		".alter-merge table Logs (Message:string)",
+		`.alter table Logs folder "raw"`,
+		`.create table ["my-metrics"] (Value:real)`,
+		`.create-or-alter table Logs ingestion json mapping "logs_json" "[{\"Column\":\"Timestamp\",\"Properties\":{\"Path\":\"$.ts\"}}]"`,
+		".create-or-alter function with (folder=\"views\") Errors() {\nLogs | where Level > 2\n}",
+		".alter table Logs policy caching hot = " + kql.New("").AddTimespan(24*time.Hour).String(),
+	}
+	drops := []string{
+		`.drop table Logs ingestion csv mapping "old_csv"`,
+		".drop function Filter",
+		".drop table Old",
+	}
+
+	// Without AllowDrops(), a column type change is not planned.
+	client := &fakeClient{results: results()}
+	plan, err := PlanMigration(context.Background(), client, "db", testDesiredSchema())
+	assert.ErrorContains(t, err, `column "Logs.Level" is int in the database and long in the desired schema`)
+	assert.Nil(t, plan)
+
+	desired := testDesiredSchema()
+	desired.Tables[0].Columns[1].Type = types.Int
+	client = &fakeClient{results: results()}
+	plan, err = PlanMigration(context.Background(), client, "db", desired)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		".show database schema as json",
+		".show database db ingestion mappings",
+		".show table Logs policy retention",
+		".show table Logs policy caching",
+	}, client.stmts)
+
+	var got []string
+	for _, s := range plan.Steps {
+		got = append(got, s.Command.String())
+		assert.False(t, s.Destructive)
+		assert.NotEmpty(t, s.Description)
+	}
+	assert.Equal(t, steps, got)
+
+	client = &fakeClient{results: results()}
+	plan, err = PlanMigration(context.Background(), client, "db", testDesiredSchema(), AllowDrops())
+	require.NoError(t, err)
+
+	got = nil
+	for _, s := range plan.Steps {
+		got = append(got, s.Command.String())
+	}
+	assert.Equal(t, append(append([]string{alter}, steps...), drops...), got)
+	assert.True(t, plan.Steps[0].Destructive)
+	for _, s := range plan.Steps[1+len(steps):] {
+		assert.True(t, s.Destructive)
+	}
+}
+
+func TestPlanMigrationUpToDate(t *testing.T) {
+	t.Parallel()
+
+	desired := &DatabaseSchema{
+		Tables: []TableDefinition{
+			{Name: "Logs", Columns: []ColumnDefinition{{Name: "Timestamp", Type: types.DateTime}, {Name: "Level", Type: types.Int}}},
+			{Name: "Old", Columns: []ColumnDefinition{{Name: "a", Type: types.Long}}},
+		},
+		Functions: []FunctionDefinition{
+			{Name: "RecentLogs", Parameters: "since:timespan", Body: "{ Logs | where Timestamp > ago(since) }"},
+			{Name: "Filter", Parameters: "T:(*), S:(a:long), n:int=10", Body: "T | take n"},
+		},
+		Policies: &Policies{Caching: &CachingPolicy{DataHotSpan: Timespan(24 * time.Hour), IndexHotSpan: Timespan(24 * time.Hour)}},
+	}
+
+	client := &fakeClient{results: append(liveSchemaResults(testLiveSchema),
+		policyResult(`{"DataHotSpan": {"Value": "1.00:00:00"}, "IndexHotSpan": {"Value": "1.00:00:00"}}`))}
+	plan, err := PlanMigration(context.Background(), client, "db", desired, AllowDrops())
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+	assert.Equal(t, ".show database db policy caching", client.stmts[2])
+}
+
+func TestPlanMigrationNewTablePolicies(t *testing.T) {
+	t.Parallel()
+
+	desired := &DatabaseSchema{
+		Tables: []TableDefinition{{
+			Name:     "New",
+			Columns:  []ColumnDefinition{{Name: "a", Type: types.Long}},
+			Policies: &Policies{Caching: &CachingPolicy{DataHotSpan: Timespan(24 * time.Hour)}},
+		}},
+	}
+
+	client := &fakeClient{results: liveSchemaResults(testLiveSchema)}
+	plan, err := PlanMigration(context.Background(), client, "db", desired)
+	require.NoError(t, err)
+	// The policies of a table that does not exist yet are not shown, as the command would fail.
+	assert.Equal(t, []string{".show database schema as json", ".show database db ingestion mappings"}, client.stmts)
+
+	var got []string
+	for _, s := range plan.Steps {
+		got = append(got, s.Command.String())
+	}
+	assert.Equal(t, []string{
+		".create table New (a:long)",
+		".alter table New policy caching hot = " + kql.New("").AddTimespan(24*time.Hour).String(),
+	}, got)
+}
+
+func TestMigrationPlanApply(t *testing.T) {
+	t.Parallel()
+
+	plan := &MigrationPlan{Steps: []MigrationStep{
+		{Description: "create table T", Command: kql.New(".create table T (a:long)")},
+		{Description: "create function F", Command: kql.New(".create-or-alter function F() {\nT\n}")},
+	}}
+
+	client := &fakeClient{}
+	require.NoError(t, plan.Apply(context.Background(), client, "db"))
+	assert.Equal(t, []string{".create table T (a:long)", ".create-or-alter function F() {\nT\n}"}, client.stmts)
+
+	client = &fakeClient{}
+	require.NoError(t, plan.Apply(context.Background(), client, "db", AsScript()))
+	assert.Equal(t, []string{
+		".execute database script <|\n.create table T (a:long)\n\n.create-or-alter function F() {\nT\n}",
+	}, client.stmts)
+
+	client = &fakeClient{results: []fakeResult{scriptResult(
+		scriptRow(".create table T (a:long)", ScriptCommandCompleted, ""),
+		scriptRow(".create-or-alter function F() {\nT\n}", ScriptCommandFailed, "syntax error"),
+	)}}
+	err := plan.Apply(context.Background(), client, "db", AsScript())
+	assert.ErrorContains(t, err, "migration step 2 of 2 (create function F) failed: syntax error")
+
+	client = &fakeClient{results: []fakeResult{{}, {err: errors.New("function F is invalid")}}}
+	err = plan.Apply(context.Background(), client, "db")
+	assert.ErrorContains(t, err, "migration step 2 of 2 (create function F) failed")
+	assert.ErrorContains(t, err, "function F is invalid")
+
+	client = &fakeClient{}
+	require.NoError(t, (&MigrationPlan{}).Apply(context.Background(), client, "db", AsScript()))
+	assert.Empty(t, client.stmts)
+}
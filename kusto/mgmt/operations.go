@@ -0,0 +1,243 @@
+package mgmt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	kustoErrors "github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+)
+
+// States of an operation, as reported in OperationStatus.State.
+const (
+	OperationInProgress         = "InProgress"
+	OperationScheduled          = "Scheduled"
+	OperationCompleted          = "Completed"
+	OperationFailed             = "Failed"
+	OperationPartiallySucceeded = "PartiallySucceeded"
+	OperationAbandoned          = "Abandoned"
+	OperationBadInput           = "BadInput"
+	OperationCanceled           = "Canceled"
+	OperationThrottled          = "Throttled"
+)
+
+// OperationStatus is a row of the ".show operations" command.
+type OperationStatus struct {
+	OperationID    uuid.UUID `kusto:"OperationId"`
+	Operation      string
+	NodeID         string `kusto:"NodeId"`
+	StartedOn      time.Time
+	LastUpdatedOn  time.Time
+	Duration       time.Duration
+	State          string
+	Status         string
+	RootActivityID uuid.UUID `kusto:"RootActivityId"`
+	ShouldRetry    bool
+	Database       string
+	Principal      string
+	User           string
+}
+
+// Done returns true if the operation reached a final state.
+func (s OperationStatus) Done() bool {
+	return s.State != "" && s.State != OperationInProgress && s.State != OperationScheduled
+}
+
+// OperationError is returned by Operation.Wait() when an operation ends in any state but OperationCompleted.
+type OperationError struct {
+	// Status is the final status of the operation. Status.Status holds the failure reason.
+	Status OperationStatus
+}
+
+// Error implements error.
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %s (%s) ended in state %s: %s", e.Status.OperationID, e.Status.Operation, e.Status.State, e.Status.Status)
+}
+
+// Retryable returns true if the service reported that the operation can be retried.
+func (e *OperationError) Retryable() bool {
+	return e.Status.ShouldRetry
+}
+
+// Operation is a management command running asynchronously on the service, such as ".export async".
+type Operation struct {
+	// ID is the OperationId returned by the command.
+	ID uuid.UUID
+
+	client Client
+	db     string
+
+	// newBackoff returns the policy for polling in Wait(). Overridden in tests.
+	newBackoff func() backoff.BackOff
+	// notFoundGrace is how long Wait() keeps polling an operation that ".show operations" does not return yet.
+	notFoundGrace time.Duration
+}
+
+// NewOperation returns an Operation for an OperationId obtained elsewhere, for example in a previous run.
+func NewOperation(client Client, db string, id uuid.UUID) *Operation {
+	return &Operation{ID: id, client: client, db: db, newBackoff: defaultOperationBackoff, notFoundGrace: defaultNotFoundGrace}
+}
+
+// defaultNotFoundGrace covers the delay before a new operation shows up in ".show operations" on every node.
+const defaultNotFoundGrace = time.Minute
+
+func defaultOperationBackoff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Second
+	b.MaxInterval = 30 * time.Second
+	b.MaxElapsedTime = 0 // Wait until the context is done.
+	return b
+}
+
+// RunAsync runs stmt, a management command with the "async" keyword (such as ".set-or-append async T <| ..."),
+// and returns the Operation it started. Use Operation.Wait() to wait for the result.
+func RunAsync(ctx context.Context, client Client, db string, stmt kusto.Statement, options ...kusto.QueryOption) (*Operation, error) {
+	iter, err := client.Mgmt(ctx, db, stmt, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	var id uuid.UUID
+	found := false
+	err = iter.DoOnRowOrError(func(r *table.Row, e *kustoErrors.Error) error {
+		if e != nil {
+			return e
+		}
+		if found {
+			return nil
+		}
+		v, err := operationID(r)
+		if err != nil {
+			return err
+		}
+		id, found = v, true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, kustoErrors.ES(kustoErrors.OpMgmt, kustoErrors.KInternal, "the command did not return an OperationId, was it run with the async keyword?").SetNoRetry()
+	}
+
+	return NewOperation(client, db, id), nil
+}
+
+// operationID reads the OperationId column of r, which may be a guid or a string.
+func operationID(r *table.Row) (uuid.UUID, error) {
+	for i, c := range r.ColumnTypes {
+		if c.Name != "OperationId" {
+			continue
+		}
+		switch v := r.Values[i].(type) {
+		case value.GUID:
+			if v.Valid {
+				return v.Value, nil
+			}
+		case value.String:
+			if id, err := uuid.Parse(v.Value); err == nil {
+				return id, nil
+			}
+		}
+		return uuid.UUID{}, kustoErrors.ES(kustoErrors.OpMgmt, kustoErrors.KInternal, "the OperationId returned by the command is not a guid: %s", r.Values[i]).SetNoRetry()
+	}
+	return uuid.UUID{}, kustoErrors.ES(kustoErrors.OpMgmt, kustoErrors.KInternal, "the command did not return an OperationId, was it run with the async keyword?").SetNoRetry()
+}
+
+// withID adds the operation id to stmt. A uuid.UUID only formats to hex digits and dashes, so it is safe as is.
+func (o *Operation) withID(stmt *kql.Builder) *kql.Builder {
+	return stmt.AddUnsafe(o.ID.String())
+}
+
+// Status returns the current status of the operation, with ".show operations <id>".
+func (o *Operation) Status(ctx context.Context) (OperationStatus, error) {
+	status, found, err := o.status(ctx)
+	if err != nil {
+		return OperationStatus{}, err
+	}
+	if !found {
+		return OperationStatus{}, kustoErrors.ES(kustoErrors.OpMgmt, kustoErrors.KOther, "operation %s was not found", o.ID).SetNoRetry()
+	}
+	return status, nil
+}
+
+// status returns the most recent status of the operation, or found == false if the service has no row for it.
+func (o *Operation) status(ctx context.Context) (status OperationStatus, found bool, err error) {
+	rows, err := rowsTo[OperationStatus](ctx, o.client, o.db, o.withID(kql.New(".show operations ")))
+	if err != nil {
+		return OperationStatus{}, false, err
+	}
+	// An operation that was retried has a row per attempt, the most recent one is the last updated.
+	for _, row := range rows {
+		if !found || !row.LastUpdatedOn.Before(status.LastUpdatedOn) {
+			status, found = row, true
+		}
+	}
+	return status, found, nil
+}
+
+// Wait polls the status of the operation with exponential backoff until it reaches a final state, or ctx is done.
+// An operation that is not found is polled again for a minute, as a new operation may not be listed yet.
+// If the final state is not OperationCompleted, the error is an *OperationError.
+func (o *Operation) Wait(ctx context.Context) (OperationStatus, error) {
+	start := time.Now()
+
+	var status OperationStatus
+	err := backoff.Retry(func() error {
+		var (
+			found bool
+			err   error
+		)
+		status, found, err = o.status(ctx)
+		if err != nil {
+			if e, ok := err.(*kustoErrors.Error); ok && !kustoErrors.Retry(e) {
+				return backoff.Permanent(err)
+			}
+			return err
+		}
+		if !found {
+			if time.Since(start) < o.notFoundGrace {
+				return errNotDone
+			}
+			return backoff.Permanent(kustoErrors.ES(kustoErrors.OpMgmt, kustoErrors.KOther, "operation %s was not found", o.ID).SetNoRetry())
+		}
+		if !status.Done() {
+			return errNotDone
+		}
+		return nil
+	}, backoff.WithContext(o.newBackoff(), ctx))
+
+	if err != nil {
+		if err == errNotDone {
+			err = ctx.Err()
+		}
+		return status, err
+	}
+
+	if status.State != OperationCompleted {
+		return status, &OperationError{Status: status}
+	}
+	return status, nil
+}
+
+var errNotDone = errors.New("operation is not done")
+
+// Details returns the result of a completed operation, with ".show operation <id> details". The columns depend on
+// the command, use Row.ToStruct() to decode them.
+func (o *Operation) Details(ctx context.Context) (*kusto.RowIterator, error) {
+	stmt := o.withID(kql.New(".show operation ")).AddLiteral(" details")
+	return o.client.Mgmt(ctx, o.db, stmt)
+}
+
+// Cancel asks the service to cancel the operation, with ".cancel operation <id>". Not all operations can be canceled.
+func (o *Operation) Cancel(ctx context.Context) error {
+	return run(ctx, o.client, o.db, o.withID(kql.New(".cancel operation ")))
+}
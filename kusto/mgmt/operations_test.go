@@ -0,0 +1,191 @@
+package mgmt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOperationID = uuid.MustParse("b3b8c7d2-5c7e-4e0b-9a2a-0a1f6b1c2d3e")
+
+func operationIDResult(id value.Kusto, t types.Column) fakeResult {
+	return fakeResult{
+		columns: table.Columns{{Name: "OperationId", Type: t}},
+		rows:    []value.Values{{id}},
+	}
+}
+
+func operationStatusResult(states ...string) fakeResult {
+	res := fakeResult{
+		columns: table.Columns{
+			{Name: "OperationId", Type: types.GUID},
+			{Name: "Operation", Type: types.String},
+			{Name: "StartedOn", Type: types.DateTime},
+			{Name: "LastUpdatedOn", Type: types.DateTime},
+			{Name: "Duration", Type: types.Timespan},
+			{Name: "State", Type: types.String},
+			{Name: "Status", Type: types.String},
+			{Name: "ShouldRetry", Type: types.Bool},
+		},
+	}
+	started := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, state := range states {
+		status := ""
+		if state == OperationFailed {
+			status = "Export failed: access denied"
+		}
+		res.rows = append(res.rows, value.Values{
+			value.GUID{Value: testOperationID, Valid: true},
+			value.String{Value: "DataExportCommand", Valid: true},
+			value.DateTime{Value: started, Valid: true},
+			value.DateTime{Value: started.Add(time.Duration(i) * time.Minute), Valid: true},
+			value.Timespan{Value: time.Minute, Valid: true},
+			value.String{Value: state, Valid: true},
+			value.String{Value: status, Valid: status != ""},
+			value.Bool{Value: state == OperationFailed, Valid: true},
+		})
+	}
+	return res
+}
+
+func fastBackoff() backoff.BackOff {
+	return backoff.NewConstantBackOff(time.Millisecond)
+}
+
+func TestRunAsync(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stmt := kql.New(".export async to csv (h@'https://storage/container;secret') <| T")
+
+	client := &fakeClient{results: []fakeResult{operationIDResult(value.GUID{Value: testOperationID, Valid: true}, types.GUID)}}
+	op, err := RunAsync(ctx, client, "db", stmt)
+	require.NoError(t, err)
+	assert.Equal(t, testOperationID, op.ID)
+
+	client = &fakeClient{results: []fakeResult{operationIDResult(value.String{Value: testOperationID.String(), Valid: true}, types.String)}}
+	op, err = RunAsync(ctx, client, "db", stmt)
+	require.NoError(t, err)
+	assert.Equal(t, testOperationID, op.ID)
+
+	client = &fakeClient{results: []fakeResult{{}}}
+	_, err = RunAsync(ctx, client, "db", stmt)
+	assert.Error(t, err)
+
+	client = &fakeClient{results: []fakeResult{operationIDResult(value.String{Value: "not a guid", Valid: true}, types.String)}}
+	_, err = RunAsync(ctx, client, "db", stmt)
+	assert.Error(t, err)
+}
+
+func TestOperationWait(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	client := &fakeClient{results: []fakeResult{
+		operationStatusResult(OperationInProgress),
+		operationStatusResult(OperationScheduled),
+		operationStatusResult(OperationFailed, OperationCompleted),
+	}}
+	op := NewOperation(client, "db", testOperationID)
+	op.newBackoff = fastBackoff
+
+	status, err := op.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationCompleted, status.State)
+	assert.Equal(t, time.Minute, status.Duration)
+	assert.Equal(t, testOperationID, status.OperationID)
+	assert.Len(t, client.stmts, 3)
+	assert.Equal(t, ".show operations "+testOperationID.String(), client.stmts[0])
+
+	client = &fakeClient{results: []fakeResult{operationStatusResult(OperationFailed)}}
+	op = NewOperation(client, "db", testOperationID)
+	op.newBackoff = fastBackoff
+
+	_, err = op.Wait(ctx)
+	var opErr *OperationError
+	require.True(t, errors.As(err, &opErr))
+	assert.Equal(t, "Export failed: access denied", opErr.Status.Status)
+	assert.True(t, opErr.Retryable())
+
+	// The most recent attempt is the one last updated, whatever the order of the rows.
+	retried := operationStatusResult(OperationFailed, OperationCompleted)
+	retried.rows[0], retried.rows[1] = retried.rows[1], retried.rows[0]
+	client = &fakeClient{results: []fakeResult{retried}}
+	op = NewOperation(client, "db", testOperationID)
+	op.newBackoff = fastBackoff
+
+	status, err = op.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationCompleted, status.State)
+
+	// An operation that never finishes is bounded by the context.
+	client = &fakeClient{}
+	for i := 0; i < 1000; i++ {
+		client.results = append(client.results, operationStatusResult(OperationInProgress))
+	}
+	op = NewOperation(client, "db", testOperationID)
+	op.newBackoff = fastBackoff
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = op.Wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestOperationNotFound(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// A new operation may not be listed yet, so Wait() polls it again.
+	client := &fakeClient{results: []fakeResult{{}, {}, operationStatusResult(OperationCompleted)}}
+	op := NewOperation(client, "db", testOperationID)
+	op.newBackoff = fastBackoff
+
+	status, err := op.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, OperationCompleted, status.State)
+	assert.Len(t, client.stmts, 3)
+
+	// After the grace period, it fails.
+	client = &fakeClient{results: []fakeResult{{}, {}}}
+	op = NewOperation(client, "db", testOperationID)
+	op.newBackoff = fastBackoff
+	op.notFoundGrace = 0
+
+	_, err = op.Wait(ctx)
+	assert.ErrorContains(t, err, "was not found")
+	assert.Len(t, client.stmts, 1)
+
+	client = &fakeClient{results: []fakeResult{{}}}
+	_, err = NewOperation(client, "db", testOperationID).Status(ctx)
+	assert.ErrorContains(t, err, "was not found")
+}
+
+func TestOperationCommands(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	op := NewOperation(client, "db", testOperationID)
+
+	iter, err := op.Details(context.Background())
+	require.NoError(t, err)
+	iter.Stop()
+	require.NoError(t, op.Cancel(context.Background()))
+
+	assert.Equal(t, []string{
+		".show operation " + testOperationID.String() + " details",
+		".cancel operation " + testOperationID.String(),
+	}, client.stmts)
+}
@@ -0,0 +1,307 @@
+package mgmt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+)
+
+// Entity is the database or table a policy applies to. Create one with DatabaseEntity() or TableEntity().
+type Entity struct {
+	kind string
+	name string
+}
+
+// DatabaseEntity is the database named name.
+func DatabaseEntity(name string) Entity {
+	return Entity{kind: "database", name: name}
+}
+
+// TableEntity is the table named name.
+func TableEntity(name string) Entity {
+	return Entity{kind: "table", name: name}
+}
+
+// Policy is implemented by the typed policies of this package, such as RetentionPolicy or CachingPolicy.
+type Policy interface {
+	// PolicyKind is the name of the policy in management commands, such as "retention".
+	PolicyKind() string
+}
+
+// policyAlterer is implemented by policies whose ".alter" command doesn't take the policy JSON.
+type policyAlterer interface {
+	alterArgs(stmt *kql.Builder)
+}
+
+// GetPolicy returns the policy of type P set on entity, with ".show <entity> policy <kind>". If no policy is set,
+// it returns nil.
+func GetPolicy[P Policy](ctx context.Context, client Client, db string, entity Entity) (*P, error) {
+	var p P
+	policy, err := showPolicyJSON(ctx, client, db, entity, p)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(policy, &p); err != nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "could not parse the %s policy of %s %q: %s", p.PolicyKind(), entity.kind, entity.name, err).SetNoRetry()
+	}
+	return &p, nil
+}
+
+// showPolicyJSON returns the JSON of the policy of the kind of p set on entity, or nil if no policy is set.
+func showPolicyJSON(ctx context.Context, client Client, db string, entity Entity, p Policy) ([]byte, error) {
+	stmt, err := policyStmt(kql.New(".show "), entity, p)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := rowsTo[showPolicy](ctx, client, db, stmt)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected 1 row for the %s policy of %s %q, got %d", p.PolicyKind(), entity.kind, entity.name, len(rows)).SetNoRetry()
+	}
+
+	policy := bytes.TrimSpace([]byte(rows[0].Policy))
+	if len(policy) == 0 || bytes.Equal(policy, []byte("null")) {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// AlterPolicy sets policy on entity, with ".alter <entity> policy <kind>".
+func AlterPolicy(ctx context.Context, client Client, db string, entity Entity, policy Policy) error {
+	stmt, err := alterPolicyStmt(entity, policy)
+	if err != nil {
+		return err
+	}
+	return run(ctx, client, db, stmt)
+}
+
+// alterPolicyStmt builds the ".alter <entity> policy <kind>" command that sets policy on entity.
+func alterPolicyStmt(entity Entity, policy Policy) (*kql.Builder, error) {
+	stmt, err := policyStmt(kql.New(".alter "), entity, policy)
+	if err != nil {
+		return nil, err
+	}
+	stmt.AddLiteral(" ")
+
+	if a, ok := policy.(policyAlterer); ok {
+		a.alterArgs(stmt)
+	} else {
+		b, err := json.Marshal(policy)
+		if err != nil {
+			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "could not serialize the %s policy: %s", policy.PolicyKind(), err).SetNoRetry()
+		}
+		stmt.AddString(string(b))
+	}
+	return stmt, nil
+}
+
+// DeletePolicy removes the policy of type P from entity, with ".delete <entity> policy <kind>".
+func DeletePolicy[P Policy](ctx context.Context, client Client, db string, entity Entity) error {
+	var p P
+	stmt, err := policyStmt(kql.New(".delete "), entity, p)
+	if err != nil {
+		return err
+	}
+	return run(ctx, client, db, stmt)
+}
+
+// policyStmt completes stmt, the ".show", ".alter" or ".delete" command, with "<entity> policy <kind>".
+func policyStmt(stmt *kql.Builder, entity Entity, policy Policy) (*kql.Builder, error) {
+	if entity.kind == "" || entity.name == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "the policy entity must be created with DatabaseEntity() or TableEntity()").SetNoRetry()
+	}
+	return stmt.AddKeyword(entity.kind).AddLiteral(" ").AddTable(entity.name).
+		AddLiteral(" policy ").AddKeyword(policy.PolicyKind()), nil
+}
+
+// showPolicy is a row of the ".show <entity> policy <kind>" command.
+type showPolicy struct {
+	PolicyName string
+	EntityName string
+	Policy     string
+	EntityType string
+}
+
+// Timespan is a time.Duration that is serialized as a Kusto timespan ("1.00:00:00") in policy JSON.
+type Timespan time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (t Timespan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(value.Timespan{Value: time.Duration(t), Valid: true}.Marshal())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Besides a timespan string, it accepts the {"Value": "..."} form used by
+// some policies.
+func (t *Timespan) UnmarshalJSON(b []byte) error {
+	var s *string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var wrapped struct {
+			Value *string
+		}
+		if err := json.Unmarshal(b, &wrapped); err != nil {
+			return err
+		}
+		s = wrapped.Value
+	}
+	if s == nil {
+		*t = 0
+		return nil
+	}
+
+	ts := value.Timespan{}
+	if err := ts.Unmarshal(*s); err != nil {
+		return err
+	}
+	*t = Timespan(ts.Value)
+	return nil
+}
+
+// Recoverability values of RetentionPolicy.
+const (
+	RecoverabilityEnabled  = "Enabled"
+	RecoverabilityDisabled = "Disabled"
+)
+
+// RetentionPolicy controls how long data is kept.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/retention-policy
+type RetentionPolicy struct {
+	// SoftDeletePeriod is how long data is kept available to queries.
+	SoftDeletePeriod Timespan `json:"SoftDeletePeriod"`
+	// Recoverability is RecoverabilityEnabled or RecoverabilityDisabled.
+	Recoverability string `json:"Recoverability,omitempty"`
+}
+
+// PolicyKind implements Policy.
+func (RetentionPolicy) PolicyKind() string { return "retention" }
+
+// CachingPolicy controls how long data is kept in the hot cache.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/cache-policy
+type CachingPolicy struct {
+	// DataHotSpan is how long data is kept in the hot cache.
+	DataHotSpan Timespan `json:"DataHotSpan"`
+	// IndexHotSpan is how long indexes are kept in the hot cache. It is usually the same as DataHotSpan.
+	IndexHotSpan Timespan `json:"IndexHotSpan"`
+}
+
+// PolicyKind implements Policy.
+func (CachingPolicy) PolicyKind() string { return "caching" }
+
+// alterArgs implements policyAlterer, as ".alter policy caching" takes "hot = <timespan>" instead of JSON.
+func (c CachingPolicy) alterArgs(stmt *kql.Builder) {
+	if c.DataHotSpan == c.IndexHotSpan || c.IndexHotSpan == 0 {
+		stmt.AddLiteral("hot = ").AddTimespan(time.Duration(c.DataHotSpan))
+		return
+	}
+	stmt.AddLiteral("hotdata = ").AddTimespan(time.Duration(c.DataHotSpan)).
+		AddLiteral(" hotindex = ").AddTimespan(time.Duration(c.IndexHotSpan))
+}
+
+// IngestionBatchingPolicy controls how queued ingestion batches data.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/batching-policy
+type IngestionBatchingPolicy struct {
+	MaximumBatchingTimeSpan Timespan `json:"MaximumBatchingTimeSpan"`
+	MaximumNumberOfItems    int      `json:"MaximumNumberOfItems"`
+	MaximumRawDataSizeMB    int      `json:"MaximumRawDataSizeMB"`
+}
+
+// PolicyKind implements Policy.
+func (IngestionBatchingPolicy) PolicyKind() string { return "ingestionbatching" }
+
+// StreamingIngestionPolicy enables streaming ingestion.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/streaming-ingestion-policy
+type StreamingIngestionPolicy struct {
+	IsEnabled bool `json:"IsEnabled"`
+	// HintAllocatedRate is the estimated ingestion rate in GB/hour, if set.
+	HintAllocatedRate *float64 `json:"HintAllocatedRate,omitempty"`
+}
+
+// PolicyKind implements Policy.
+func (StreamingIngestionPolicy) PolicyKind() string { return "streamingingestion" }
+
+// UpdatePolicy is the set of update policies of a table. Each entry runs Query when data is ingested to Source, and
+// ingests the result to the table.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/update-policy
+type UpdatePolicy []UpdatePolicyEntry
+
+// UpdatePolicyEntry is a single update policy.
+type UpdatePolicyEntry struct {
+	IsEnabled                    bool   `json:"IsEnabled"`
+	Source                       string `json:"Source"`
+	Query                        string `json:"Query"`
+	IsTransactional              bool   `json:"IsTransactional"`
+	PropagateIngestionProperties bool   `json:"PropagateIngestionProperties"`
+	ManagedIdentity              string `json:"ManagedIdentity,omitempty"`
+}
+
+// PolicyKind implements Policy.
+func (UpdatePolicy) PolicyKind() string { return "update" }
+
+// Partition key kinds of PartitionKey.
+const (
+	PartitionKindHash         = "Hash"
+	PartitionKindUniformRange = "UniformRange"
+)
+
+// PartitioningPolicy controls how extents are partitioned.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/partitioning-policy
+type PartitioningPolicy struct {
+	PartitionKeys               []PartitionKey `json:"PartitionKeys"`
+	EffectiveDateTime           *time.Time     `json:"EffectiveDateTime,omitempty"`
+	MinRowCountPerOperation     int            `json:"MinRowCountPerOperation,omitempty"`
+	MaxRowCountPerOperation     int            `json:"MaxRowCountPerOperation,omitempty"`
+	MaxOriginalSizePerOperation int64          `json:"MaxOriginalSizePerOperation,omitempty"`
+}
+
+// PartitionKey is a column extents are partitioned by.
+type PartitionKey struct {
+	ColumnName string `json:"ColumnName"`
+	// Kind is PartitionKindHash or PartitionKindUniformRange.
+	Kind       string                 `json:"Kind"`
+	Properties PartitionKeyProperties `json:"Properties"`
+}
+
+// PartitionKeyProperties holds the properties of a PartitionKey. Function, MaxPartitionCount, Seed and
+// PartitionAssignmentMode apply to hash keys, Reference, RangeSize and OverrideCreationTime to uniform range keys.
+type PartitionKeyProperties struct {
+	Function                string    `json:"Function,omitempty"`
+	MaxPartitionCount       int       `json:"MaxPartitionCount,omitempty"`
+	Seed                    int       `json:"Seed,omitempty"`
+	PartitionAssignmentMode string    `json:"PartitionAssignmentMode,omitempty"`
+	Reference               string    `json:"Reference,omitempty"`
+	RangeSize               *Timespan `json:"RangeSize,omitempty"`
+	OverrideCreationTime    bool      `json:"OverrideCreationTime,omitempty"`
+}
+
+// PolicyKind implements Policy.
+func (PartitioningPolicy) PolicyKind() string { return "partitioning" }
+
+// MergePolicy controls how extents are merged.
+// See https://learn.microsoft.com/azure/data-explorer/kusto/management/merge-policy
+type MergePolicy struct {
+	RowCountUpperBoundForMerge       int            `json:"RowCountUpperBoundForMerge,omitempty"`
+	OriginalSizeMBUpperBoundForMerge int            `json:"OriginalSizeMBUpperBoundForMerge,omitempty"`
+	MaxExtentsToMerge                int            `json:"MaxExtentsToMerge,omitempty"`
+	LoopPeriod                       Timespan       `json:"LoopPeriod,omitempty"`
+	MaxRangeInHours                  int            `json:"MaxRangeInHours,omitempty"`
+	AllowRebuild                     bool           `json:"AllowRebuild"`
+	AllowMerge                       bool           `json:"AllowMerge"`
+	Lookback                         *MergeLookback `json:"Lookback,omitempty"`
+}
+
+// MergeLookback is the period of extents considered for merges. Kind is "Default", "All" or "Custom", in which case
+// CustomPeriod is set.
+type MergeLookback struct {
+	Kind         string    `json:"Kind"`
+	CustomPeriod *Timespan `json:"CustomPeriod,omitempty"`
+}
+
+// PolicyKind implements Policy.
+func (MergePolicy) PolicyKind() string { return "merge" }
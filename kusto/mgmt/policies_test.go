@@ -0,0 +1,168 @@
+package mgmt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func showPolicyResult(policy string) fakeResult {
+	return fakeResult{
+		columns: table.Columns{
+			{Name: "PolicyName", Type: types.String},
+			{Name: "EntityName", Type: types.String},
+			{Name: "Policy", Type: types.String},
+			{Name: "ChildEntities", Type: types.String},
+			{Name: "EntityType", Type: types.String},
+		},
+		rows: []value.Values{{
+			value.String{Value: "Policy", Valid: true},
+			value.String{Value: "[db].[T]", Valid: true},
+			value.String{Value: policy, Valid: true},
+			value.String{},
+			value.String{Value: "Table", Valid: true},
+		}},
+	}
+}
+
+func TestGetPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	client := &fakeClient{results: []fakeResult{showPolicyResult(`{"SoftDeletePeriod": "365.00:00:00", "Recoverability": "Enabled"}`)}}
+	retention, err := GetPolicy[RetentionPolicy](ctx, client, "db", TableEntity("my-table"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`.show table ["my-table"] policy retention`}, client.stmts)
+	assert.Equal(t, &RetentionPolicy{SoftDeletePeriod: Timespan(365 * 24 * time.Hour), Recoverability: RecoverabilityEnabled}, retention)
+
+	client = &fakeClient{results: []fakeResult{showPolicyResult(`{"DataHotSpan": {"Value": "3.00:00:00"}, "IndexHotSpan": {"Value": "3.00:00:00"}, "ColumnOverrides": []}`)}}
+	caching, err := GetPolicy[CachingPolicy](ctx, client, "db", DatabaseEntity("db"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`.show database db policy caching`}, client.stmts)
+	assert.Equal(t, &CachingPolicy{DataHotSpan: Timespan(72 * time.Hour), IndexHotSpan: Timespan(72 * time.Hour)}, caching)
+
+	client = &fakeClient{results: []fakeResult{showPolicyResult(`[{"IsEnabled": true, "Source": "Raw", "Query": "Raw | extend x = 1", "IsTransactional": false, "PropagateIngestionProperties": false}]`)}}
+	update, err := GetPolicy[UpdatePolicy](ctx, client, "db", TableEntity("T"))
+	require.NoError(t, err)
+	assert.Equal(t, &UpdatePolicy{{IsEnabled: true, Source: "Raw", Query: "Raw | extend x = 1"}}, update)
+
+	client = &fakeClient{results: []fakeResult{showPolicyResult("null")}}
+	merge, err := GetPolicy[MergePolicy](ctx, client, "db", TableEntity("T"))
+	require.NoError(t, err)
+	assert.Nil(t, merge)
+
+	client = &fakeClient{results: []fakeResult{showPolicyResult("{")}}
+	_, err = GetPolicy[MergePolicy](ctx, client, "db", TableEntity("T"))
+	assert.Error(t, err)
+
+	_, err = GetPolicy[MergePolicy](ctx, client, "db", Entity{})
+	assert.Error(t, err)
+}
+
+func TestAlterPolicy(t *testing.T) {
+	t.Parallel()
+
+	rate := 1.5
+	tests := []struct {
+		desc   string
+		entity Entity
+		policy Policy
+		want   string
+	}{
+		{
+			desc:   "Retention",
+			entity: TableEntity("my-table"),
+			policy: RetentionPolicy{SoftDeletePeriod: Timespan(30 * 24 * time.Hour), Recoverability: RecoverabilityDisabled},
+			want:   `.alter table ["my-table"] policy retention "{\"SoftDeletePeriod\":\"30.00:00:00\",\"Recoverability\":\"Disabled\"}"`,
+		},
+		{
+			desc:   "Caching",
+			entity: DatabaseEntity("db"),
+			policy: CachingPolicy{DataHotSpan: Timespan(24 * time.Hour)},
+			want:   `.alter database db policy caching hot = timespan(1.00:00:00.0000000)`,
+		},
+		{
+			desc:   "CachingDataAndIndex",
+			entity: TableEntity("T"),
+			policy: CachingPolicy{DataHotSpan: Timespan(24 * time.Hour), IndexHotSpan: Timespan(48 * time.Hour)},
+			want:   `.alter table T policy caching hotdata = timespan(1.00:00:00.0000000) hotindex = timespan(2.00:00:00.0000000)`,
+		},
+		{
+			desc:   "IngestionBatching",
+			entity: TableEntity("T"),
+			policy: IngestionBatchingPolicy{MaximumBatchingTimeSpan: Timespan(30 * time.Second), MaximumNumberOfItems: 500, MaximumRawDataSizeMB: 1024},
+			want:   `.alter table T policy ingestionbatching "{\"MaximumBatchingTimeSpan\":\"00:00:30\",\"MaximumNumberOfItems\":500,\"MaximumRawDataSizeMB\":1024}"`,
+		},
+		{
+			desc:   "StreamingIngestion",
+			entity: TableEntity("T"),
+			policy: StreamingIngestionPolicy{IsEnabled: true, HintAllocatedRate: &rate},
+			want:   `.alter table T policy streamingingestion "{\"IsEnabled\":true,\"HintAllocatedRate\":1.5}"`,
+		},
+		{
+			desc:   "Update",
+			entity: TableEntity("T"),
+			policy: UpdatePolicy{{IsEnabled: true, Source: "Raw", Query: `Raw | where a == 'x'`, IsTransactional: true}},
+			want:   `.alter table T policy update "[{\"IsEnabled\":true,\"Source\":\"Raw\",\"Query\":\"Raw | where a == \'x\'\",\"IsTransactional\":true,\"PropagateIngestionProperties\":false}]"`,
+		},
+		{
+			desc:   "Partitioning",
+			entity: TableEntity("T"),
+			policy: PartitioningPolicy{PartitionKeys: []PartitionKey{{
+				ColumnName: "tenant",
+				Kind:       PartitionKindHash,
+				Properties: PartitionKeyProperties{Function: "XxHash64", MaxPartitionCount: 128, Seed: 1, PartitionAssignmentMode: "Uniform"},
+			}}},
+			want: `.alter table T policy partitioning "{\"PartitionKeys\":[{\"ColumnName\":\"tenant\",\"Kind\":\"Hash\",\"Properties\":{\"Function\":\"XxHash64\",\"MaxPartitionCount\":128,\"Seed\":1,\"PartitionAssignmentMode\":\"Uniform\"}}]}"`,
+		},
+		{
+			desc:   "Merge",
+			entity: TableEntity("T"),
+			policy: MergePolicy{MaxRangeInHours: 24, AllowRebuild: true, AllowMerge: true, Lookback: &MergeLookback{Kind: "Default"}},
+			want:   `.alter table T policy merge "{\"MaxRangeInHours\":24,\"AllowRebuild\":true,\"AllowMerge\":true,\"Lookback\":{\"Kind\":\"Default\"}}"`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeClient{}
+			require.NoError(t, AlterPolicy(context.Background(), client, "db", test.entity, test.policy))
+			assert.Equal(t, []string{test.want}, client.stmts)
+		})
+	}
+}
+
+func TestDeletePolicy(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	require.NoError(t, DeletePolicy[StreamingIngestionPolicy](context.Background(), client, "db", TableEntity("my-table")))
+	assert.Equal(t, []string{`.delete table ["my-table"] policy streamingingestion`}, client.stmts)
+}
+
+func TestTimespanJSON(t *testing.T) {
+	t.Parallel()
+
+	var p RetentionPolicy
+	require.NoError(t, p.SoftDeletePeriod.UnmarshalJSON([]byte(`"1.02:03:04.5"`)))
+	assert.Equal(t, Timespan(26*time.Hour+3*time.Minute+4*time.Second+500*time.Millisecond), p.SoftDeletePeriod)
+
+	require.NoError(t, p.SoftDeletePeriod.UnmarshalJSON([]byte(`null`)))
+	assert.Equal(t, Timespan(0), p.SoftDeletePeriod)
+
+	assert.Error(t, p.SoftDeletePeriod.UnmarshalJSON([]byte(`"tomorrow"`)))
+
+	b, err := Timespan(90 * time.Minute).MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `"01:30:00"`, string(b))
+}
@@ -0,0 +1,357 @@
+package mgmt
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"gopkg.in/yaml.v3"
+)
+
+// DatabaseSchema describes the tables, functions, ingestion mappings and policies of a database. It is the desired
+// state given to PlanMigration(), built in Go or loaded with LoadDatabaseSchema().
+type DatabaseSchema struct {
+	Tables    []TableDefinition    `json:"tables,omitempty"`
+	Functions []FunctionDefinition `json:"functions,omitempty"`
+	// Policies are the database policies. Nil policies are left as they are.
+	Policies *Policies `json:"policies,omitempty"`
+}
+
+// TableDefinition describes a table.
+type TableDefinition struct {
+	Name      string             `json:"name"`
+	Folder    string             `json:"folder,omitempty"`
+	DocString string             `json:"docString,omitempty"`
+	Columns   []ColumnDefinition `json:"columns"`
+	// IngestionMappings are the named ingestion mappings of the table.
+	IngestionMappings []IngestionMappingDefinition `json:"ingestionMappings,omitempty"`
+	// Policies are the table policies. Nil policies are left as they are.
+	Policies *Policies `json:"policies,omitempty"`
+}
+
+// ColumnDefinition describes a column of a table.
+type ColumnDefinition struct {
+	Name string       `json:"name"`
+	Type types.Column `json:"type"`
+}
+
+// ColumnDefinitions converts columns, such as the result of GetTableSchema(), to column definitions.
+func ColumnDefinitions(columns table.Columns) []ColumnDefinition {
+	defs := make([]ColumnDefinition, 0, len(columns))
+	for _, c := range columns {
+		defs = append(defs, ColumnDefinition{Name: c.Name, Type: c.Type})
+	}
+	return defs
+}
+
+// FunctionDefinition describes a stored function. Parameters and Body are KQL, and are sent to the service as is.
+type FunctionDefinition struct {
+	Name string `json:"name"`
+	// Parameters is the parameter list, without the parentheses, such as "from:datetime, to:datetime=now()".
+	Parameters string `json:"parameters,omitempty"`
+	// Body is the function body. The enclosing braces are optional.
+	Body      string `json:"body"`
+	Folder    string `json:"folder,omitempty"`
+	DocString string `json:"docString,omitempty"`
+}
+
+// IngestionMappingDefinition describes a named ingestion mapping of a table.
+type IngestionMappingDefinition struct {
+	Name string `json:"name"`
+	// Kind is the mapping kind, such as "csv" or "json".
+	Kind string `json:"kind"`
+	// Mapping is the JSON array of column mappings.
+	Mapping json.RawMessage `json:"mapping"`
+}
+
+// Policies holds the policies of a table or a database. Only the policies that are set are managed.
+type Policies struct {
+	Retention          *RetentionPolicy          `json:"retention,omitempty"`
+	Caching            *CachingPolicy            `json:"caching,omitempty"`
+	IngestionBatching  *IngestionBatchingPolicy  `json:"ingestionBatching,omitempty"`
+	StreamingIngestion *StreamingIngestionPolicy `json:"streamingIngestion,omitempty"`
+	Update             UpdatePolicy              `json:"update,omitempty"`
+	Partitioning       *PartitioningPolicy       `json:"partitioning,omitempty"`
+	Merge              *MergePolicy              `json:"merge,omitempty"`
+}
+
+// list returns the policies that are set, in the order they are applied. The update policy comes last, as its
+// query may depend on the other policies being in place.
+func (p *Policies) list() []Policy {
+	if p == nil {
+		return nil
+	}
+
+	var out []Policy
+	if p.Retention != nil {
+		out = append(out, *p.Retention)
+	}
+	if p.Caching != nil {
+		out = append(out, *p.Caching)
+	}
+	if p.IngestionBatching != nil {
+		out = append(out, *p.IngestionBatching)
+	}
+	if p.StreamingIngestion != nil {
+		out = append(out, *p.StreamingIngestion)
+	}
+	if p.Partitioning != nil {
+		out = append(out, *p.Partitioning)
+	}
+	if p.Merge != nil {
+		out = append(out, *p.Merge)
+	}
+	if p.Update != nil {
+		out = append(out, p.Update)
+	}
+	return out
+}
+
+// LoadDatabaseSchema reads a DatabaseSchema from a YAML or JSON file.
+func LoadDatabaseSchema(path string) (*DatabaseSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.E(errors.OpMgmt, errors.KLocalFileSystem, err).SetNoRetry()
+	}
+	return ParseDatabaseSchema(data)
+}
+
+// ParseDatabaseSchema parses a DatabaseSchema from YAML or JSON. Keys are matched to the JSON names of the fields,
+// case-insensitively.
+func ParseDatabaseSchema(data []byte) (*DatabaseSchema, error) {
+	// YAML is a superset of JSON. Converting to JSON first lets both formats use the JSON field names, and the
+	// JSON serialization of timespans in policies.
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "could not parse the database schema: %s", err).SetNoRetry()
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "could not parse the database schema: %s", err).SetNoRetry()
+	}
+
+	schema := &DatabaseSchema{}
+	if err := json.Unmarshal(b, schema); err != nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "could not parse the database schema: %s", err).SetNoRetry()
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Validate checks that names are set and unique, and that column types are valid.
+func (s *DatabaseSchema) Validate() error {
+	tables := map[string]bool{}
+	for _, t := range s.Tables {
+		if t.Name == "" {
+			return errors.ES(errors.OpMgmt, errors.KClientArgs, "a table has no name").SetNoRetry()
+		}
+		if tables[t.Name] {
+			return errors.ES(errors.OpMgmt, errors.KClientArgs, "table %q is defined more than once", t.Name).SetNoRetry()
+		}
+		tables[t.Name] = true
+
+		if err := t.columns().Validate(); err != nil {
+			return errors.ES(errors.OpMgmt, errors.KClientArgs, "table %q: %s", t.Name, err).SetNoRetry()
+		}
+
+		mappings := map[string]bool{}
+		for _, m := range t.IngestionMappings {
+			if m.Name == "" || m.Kind == "" || len(m.Mapping) == 0 {
+				return errors.ES(errors.OpMgmt, errors.KClientArgs, "table %q: ingestion mappings need a name, kind and mapping", t.Name).SetNoRetry()
+			}
+			key := mappingKey(m.Kind, m.Name)
+			if mappings[key] {
+				return errors.ES(errors.OpMgmt, errors.KClientArgs, "table %q: %s mapping %q is defined more than once", t.Name, m.Kind, m.Name).SetNoRetry()
+			}
+			mappings[key] = true
+		}
+	}
+
+	functions := map[string]bool{}
+	for _, f := range s.Functions {
+		if f.Name == "" || strings.TrimSpace(f.Body) == "" {
+			return errors.ES(errors.OpMgmt, errors.KClientArgs, "functions need a name and a body").SetNoRetry()
+		}
+		if functions[f.Name] {
+			return errors.ES(errors.OpMgmt, errors.KClientArgs, "function %q is defined more than once", f.Name).SetNoRetry()
+		}
+		functions[f.Name] = true
+	}
+	return nil
+}
+
+func (t TableDefinition) columns() table.Columns {
+	columns := make(table.Columns, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		columns = append(columns, table.Column{Name: c.Name, Type: c.Type})
+	}
+	return columns
+}
+
+func mappingKey(kind, name string) string {
+	return strings.ToLower(kind) + "/" + name
+}
+
+// showDatabaseSchema is a row of the ".show database schema as json" command.
+type showDatabaseSchema struct {
+	DatabaseSchema string
+}
+
+// databaseSchemaJSON is the content of the DatabaseSchema column of ".show database schema as json".
+type databaseSchemaJSON struct {
+	Databases map[string]databaseJSON
+}
+
+type databaseJSON struct {
+	Name      string
+	Tables    map[string]schemaJSON
+	Functions map[string]functionJSON
+}
+
+type functionJSON struct {
+	Name            string
+	InputParameters []functionParameterJSON
+	Body            string
+	Folder          string
+	DocString       string
+	OutputColumns   []struct {
+		Name    string
+		CslType string
+	}
+}
+
+type functionParameterJSON struct {
+	Name            string
+	CslType         string
+	CslDefaultValue *string
+	// Columns is set for tabular parameters.
+	Columns []struct {
+		Name    string
+		CslType string
+	}
+}
+
+// showIngestionMapping is a row of the ".show database D ingestion mappings" command.
+type showIngestionMapping struct {
+	Name    string
+	Kind    string
+	Mapping string
+	Table   string
+}
+
+// showDatabaseSchemaJSON runs ".show database schema as json" and returns the entry of database db.
+func showDatabaseSchemaJSON(ctx context.Context, client Client, db string) ([]databaseJSON, error) {
+	rows, err := rowsTo[showDatabaseSchema](ctx, client, db, kql.New(".show database schema as json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "expected 1 row for the schema of database %q, got %d", db, len(rows)).SetNoRetry()
+	}
+
+	var s databaseSchemaJSON
+	if err := json.Unmarshal([]byte(rows[0].DatabaseSchema), &s); err != nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KInternal, "could not parse the schema of database %q: %s", db, err).SetNoRetry()
+	}
+
+	// The command returns the database the command runs in, its key is the database name as stored by the service.
+	var out []databaseJSON
+	for name, d := range s.Databases {
+		if len(s.Databases) == 1 || strings.EqualFold(name, db) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// GetDatabaseSchema returns the tables, functions and ingestion mappings of database db. Policies are not included,
+// as each one takes a command per table to read.
+func GetDatabaseSchema(ctx context.Context, client Client, db string) (*DatabaseSchema, error) {
+	databases, err := showDatabaseSchemaJSON(ctx, client, db)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &DatabaseSchema{}
+	for _, d := range databases {
+		for _, t := range d.Tables {
+			parsed, err := tableSchemaFromJSON(showTableSchema{TableName: t.Name, Folder: t.Folder, DocString: t.DocString}, t)
+			if err != nil {
+				return nil, err
+			}
+			schema.Tables = append(schema.Tables, TableDefinition{
+				Name:      parsed.Name,
+				Folder:    parsed.Folder,
+				DocString: parsed.DocString,
+				Columns:   ColumnDefinitions(parsed.Columns),
+			})
+		}
+		for _, f := range d.Functions {
+			schema.Functions = append(schema.Functions, FunctionDefinition{
+				Name:       f.Name,
+				Parameters: f.parameters(),
+				Body:       f.Body,
+				Folder:     f.Folder,
+				DocString:  f.DocString,
+			})
+		}
+	}
+	sort.Slice(schema.Tables, func(i, j int) bool { return schema.Tables[i].Name < schema.Tables[j].Name })
+	sort.Slice(schema.Functions, func(i, j int) bool { return schema.Functions[i].Name < schema.Functions[j].Name })
+
+	mappings, err := rowsTo[showIngestionMapping](ctx, client, db, kql.New(".show database ").AddTable(db).AddLiteral(" ingestion mappings"))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mappings {
+		for i := range schema.Tables {
+			if schema.Tables[i].Name == m.Table {
+				schema.Tables[i].IngestionMappings = append(schema.Tables[i].IngestionMappings, IngestionMappingDefinition{
+					Name:    m.Name,
+					Kind:    strings.ToLower(m.Kind),
+					Mapping: json.RawMessage(m.Mapping),
+				})
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+// parameters renders the parameter list of the function as it is declared.
+func (f functionJSON) parameters() string {
+	params := make([]string, 0, len(f.InputParameters))
+	for _, p := range f.InputParameters {
+		var b strings.Builder
+		b.WriteString(p.Name)
+		b.WriteString(":")
+		switch {
+		case p.CslType != "":
+			b.WriteString(p.CslType)
+		case len(p.Columns) == 0:
+			b.WriteString("(*)")
+		default:
+			b.WriteString("(")
+			for i, c := range p.Columns {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(c.Name + ":" + c.CslType)
+			}
+			b.WriteString(")")
+		}
+		if p.CslDefaultValue != nil {
+			b.WriteString("=" + *p.CslDefaultValue)
+		}
+		params = append(params, b.String())
+	}
+	return strings.Join(params, ", ")
+}
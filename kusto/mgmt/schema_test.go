@@ -0,0 +1,213 @@
+package mgmt
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchemaYAML = `
+tables:
+  - name: Logs
+    folder: raw
+    columns:
+      - {name: Timestamp, type: datetime}
+      - {name: Message, type: string}
+    ingestionMappings:
+      - name: logs_json
+        kind: json
+        mapping:
+          - {column: Timestamp, Properties: {Path: "$.ts"}}
+    policies:
+      retention:
+        SoftDeletePeriod: "30.00:00:00"
+functions:
+  - name: RecentLogs
+    parameters: "since:timespan"
+    body: "Logs | where Timestamp > ago(since)"
+policies:
+  caching:
+    DataHotSpan: "7.00:00:00"
+`
+
+func TestParseDatabaseSchema(t *testing.T) {
+	t.Parallel()
+
+	want := &DatabaseSchema{
+		Tables: []TableDefinition{{
+			Name:   "Logs",
+			Folder: "raw",
+			Columns: []ColumnDefinition{
+				{Name: "Timestamp", Type: types.DateTime},
+				{Name: "Message", Type: types.String},
+			},
+			IngestionMappings: []IngestionMappingDefinition{{
+				Name:    "logs_json",
+				Kind:    "json",
+				Mapping: json.RawMessage(`[{"Properties":{"Path":"$.ts"},"column":"Timestamp"}]`),
+			}},
+			Policies: &Policies{Retention: &RetentionPolicy{SoftDeletePeriod: Timespan(30 * 24 * time.Hour)}},
+		}},
+		Functions: []FunctionDefinition{{Name: "RecentLogs", Parameters: "since:timespan", Body: "Logs | where Timestamp > ago(since)"}},
+		Policies:  &Policies{Caching: &CachingPolicy{DataHotSpan: Timespan(7 * 24 * time.Hour)}},
+	}
+
+	got, err := ParseDatabaseSchema([]byte(testSchemaYAML))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// The same schema as JSON, through a file.
+	b, err := json.Marshal(want)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(path, b, 0600))
+
+	got, err = LoadDatabaseSchema(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = LoadDatabaseSchema(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestParseDatabaseSchemaInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		data string
+	}{
+		{desc: "Syntax", data: "tables: [\n"},
+		{desc: "NoTableName", data: "tables: [{columns: [{name: a, type: long}]}]"},
+		{desc: "DuplicateTable", data: "tables: [{name: T, columns: [{name: a, type: long}]}, {name: T, columns: [{name: a, type: long}]}]"},
+		{desc: "BadColumnType", data: "tables: [{name: T, columns: [{name: a, type: integer}]}]"},
+		{desc: "NoMappingKind", data: "tables: [{name: T, columns: [{name: a, type: long}], ingestionMappings: [{name: m, mapping: []}]}]"},
+		{desc: "NoFunctionBody", data: "functions: [{name: F}]"},
+		{desc: "DuplicateFunction", data: "functions: [{name: F, body: T}, {name: F, body: T}]"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseDatabaseSchema([]byte(test.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// liveSchemaResults are the results of the commands run by GetDatabaseSchema().
+func liveSchemaResults(schema string, mappings ...value.Values) []fakeResult {
+	return []fakeResult{
+		{
+			columns: table.Columns{{Name: "DatabaseSchema", Type: types.String}},
+			rows:    []value.Values{{value.String{Value: schema, Valid: true}}},
+		},
+		{
+			columns: table.Columns{
+				{Name: "Name", Type: types.String},
+				{Name: "Kind", Type: types.String},
+				{Name: "Mapping", Type: types.String},
+				{Name: "LastUpdatedOn", Type: types.DateTime},
+				{Name: "Database", Type: types.String},
+				{Name: "Table", Type: types.String},
+			},
+			rows: mappings,
+		},
+	}
+}
+
+func mappingRow(name, kind, mapping, tableName string) value.Values {
+	return value.Values{
+		value.String{Value: name, Valid: true},
+		value.String{Value: kind, Valid: true},
+		value.String{Value: mapping, Valid: true},
+		value.DateTime{},
+		value.String{Value: "db", Valid: true},
+		value.String{Value: tableName, Valid: true},
+	}
+}
+
+const testLiveSchema = `{
+  "Plugins": [],
+  "Databases": {
+    "db": {
+      "Name": "db",
+      "Tables": {
+        "Logs": {
+          "Name": "Logs",
+          "EntityType": "Table",
+          "OrderedColumns": [
+            {"Name": "Timestamp", "Type": "System.DateTime", "CslType": "datetime"},
+            {"Name": "Level", "Type": "System.Int32", "CslType": "int"}
+          ],
+          "Folder": "",
+          "DocString": ""
+        },
+        "Old": {
+          "Name": "Old",
+          "OrderedColumns": [{"Name": "a", "CslType": "long"}]
+        }
+      },
+      "Functions": {
+        "RecentLogs": {
+          "Name": "RecentLogs",
+          "InputParameters": [{"Name": "since", "Type": "System.TimeSpan", "CslType": "timespan", "CslDefaultValue": null}],
+          "Body": "{\n    Logs\n    | where Timestamp > ago(since)\n}",
+          "Folder": "",
+          "DocString": ""
+        },
+        "Filter": {
+          "Name": "Filter",
+          "InputParameters": [
+            {"Name": "T", "Columns": []},
+            {"Name": "S", "Columns": [{"Name": "a", "CslType": "long"}]},
+            {"Name": "n", "CslType": "int", "CslDefaultValue": "10"}
+          ],
+          "Body": "{ T | take n }"
+        }
+      }
+    }
+  }
+}`
+
+func TestGetDatabaseSchema(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{results: liveSchemaResults(testLiveSchema,
+		mappingRow("logs_csv", "Csv", `[{"Column":"Timestamp","Properties":{"Ordinal":"0"}}]`, "Logs"))}
+
+	schema, err := GetDatabaseSchema(context.Background(), client, "db")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".show database schema as json", ".show database db ingestion mappings"}, client.stmts)
+	assert.Equal(t, &DatabaseSchema{
+		Tables: []TableDefinition{
+			{
+				Name: "Logs",
+				Columns: []ColumnDefinition{
+					{Name: "Timestamp", Type: types.DateTime},
+					{Name: "Level", Type: types.Int},
+				},
+				IngestionMappings: []IngestionMappingDefinition{{
+					Name:    "logs_csv",
+					Kind:    "csv",
+					Mapping: json.RawMessage(`[{"Column":"Timestamp","Properties":{"Ordinal":"0"}}]`),
+				}},
+			},
+			{Name: "Old", Columns: []ColumnDefinition{{Name: "a", Type: types.Long}}},
+		},
+		Functions: []FunctionDefinition{
+			{Name: "Filter", Parameters: "T:(*), S:(a:long), n:int=10", Body: "{ T | take n }"},
+			{Name: "RecentLogs", Parameters: "since:timespan", Body: "{\n    Logs\n    | where Timestamp > ago(since)\n}"},
+		},
+	}, schema)
+}
@@ -0,0 +1,177 @@
+package mgmt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/Azure/azure-kusto-go/kusto/kql/lexer"
+	"github.com/google/uuid"
+)
+
+// Results of a command of a database script, as reported in ScriptCommandResult.Result.
+const (
+	ScriptCommandCompleted = "Completed"
+	ScriptCommandFailed    = "Failed"
+	ScriptCommandSkipped   = "Skipped"
+)
+
+// ScriptCommandResult is the outcome of one command of a ".execute database script" command.
+type ScriptCommandResult struct {
+	OperationID uuid.UUID `kusto:"OperationId"`
+	CommandType string
+	CommandText string
+	// Result is one of ScriptCommandCompleted, ScriptCommandFailed or ScriptCommandSkipped.
+	Result string
+	// Reason is why the command failed, if it did.
+	Reason string
+}
+
+// Failed returns true if the command failed.
+func (r ScriptCommandResult) Failed() bool {
+	return r.Result == ScriptCommandFailed
+}
+
+// ScriptError is returned by ExecuteScript() when a command of the script failed.
+type ScriptError struct {
+	// Index is the position of the first failed command in the script, starting at 0.
+	Index int
+	// Result is the result of the failed command.
+	Result ScriptCommandResult
+}
+
+// Error implements error.
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("command %d of the script (%s) failed: %s", e.Index+1, e.Result.CommandType, e.Result.Reason)
+}
+
+type scriptOptions struct {
+	continueOnErrors bool
+	throwOnErrors    bool
+}
+
+// ScriptOption is an optional argument to ExecuteScript().
+type ScriptOption func(o *scriptOptions)
+
+// ContinueOnErrors runs all the commands of the script, even after one of them fails. By default, the script stops at
+// the first failing command and the following commands are not run.
+func ContinueOnErrors() ScriptOption {
+	return func(o *scriptOptions) {
+		o.continueOnErrors = true
+	}
+}
+
+// ThrowOnErrors fails the whole ".execute database script" command at the first failing command, instead of
+// reporting it in the results. ExecuteScript() then returns the error of the service and no results.
+func ThrowOnErrors() ScriptOption {
+	return func(o *scriptOptions) {
+		o.throwOnErrors = true
+	}
+}
+
+// ExecuteScript runs commands in database db as a single ".execute database script" command, in order, and returns
+// the result of each command. This is not a transaction: the commands that completed before a failure are not rolled
+// back.
+//
+// Blank lines separate the commands of a script, so they are removed from the commands, such as from the body of a
+// function. A command with a blank line inside a string literal is rejected, as the literal cannot be changed.
+//
+// If a command failed, the results are returned along with a *ScriptError for the first failed command.
+func ExecuteScript(ctx context.Context, client Client, db string, commands []kusto.Statement, options ...ScriptOption) ([]ScriptCommandResult, error) {
+	opts := scriptOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+	if opts.continueOnErrors && opts.throwOnErrors {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "ContinueOnErrors and ThrowOnErrors cannot be used together").SetNoRetry()
+	}
+	if len(commands) == 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "a script needs at least one command").SetNoRetry()
+	}
+
+	stmt := kql.New(".execute database script")
+	switch {
+	case opts.continueOnErrors:
+		stmt.AddLiteral(" with (ContinueOnErrors=true)")
+	case opts.throwOnErrors:
+		stmt.AddLiteral(" with (ThrowOnErrors=true)")
+	}
+	stmt.AddLiteral(" <|\n")
+
+	for i, c := range commands {
+		if s, ok := c.(kusto.Stmt); ok {
+			if params, err := s.GetParameters(); err != nil || len(params) > 0 {
+				return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "command %d of the script has query parameters, which scripts do not support", i+1).SetNoRetry()
+			}
+		}
+		text := strings.TrimSpace(c.String())
+		isCommand, err := lexer.IsCommand(text)
+		if err != nil {
+			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "command %d of the script is not valid KQL: %s", i+1, err).SetNoRetry()
+		}
+		if !isCommand {
+			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "command %d of the script is not a management command: %q", i+1, text).SetNoRetry()
+		}
+		text, err = removeBlankLines(text)
+		if err != nil {
+			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "command %d of the script %s", i+1, err).SetNoRetry()
+		}
+		if i > 0 {
+			stmt.AddLiteral("\n\n")
+		}
+		// The commands are Statements, which are already escaped.
+		stmt.AddUnsafe(text)
+	}
+
+	results, err := rowsTo[ScriptCommandResult](ctx, client, db, stmt)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range results {
+		if r.Failed() {
+			return results, &ScriptError{Index: i, Result: r}
+		}
+	}
+	return results, nil
+}
+
+// removeBlankLines removes the blank lines between the tokens of command text. It fails if a string literal holds a
+// blank line.
+func removeBlankLines(text string) (string, error) {
+	tokens, err := lexer.Tokenize(text)
+	if err != nil {
+		return "", err
+	}
+
+	b := strings.Builder{}
+	end := 0
+	for _, tok := range tokens {
+		// The text between tokens is whitespace, and keeps the indentation of its last line.
+		space := text[end:tok.Offset]
+		if strings.Count(space, "\n") > 1 {
+			space = "\n" + space[strings.LastIndex(space, "\n")+1:]
+		}
+		b.WriteString(space)
+
+		if tok.Kind == lexer.String && hasBlankLine(tok.Text) {
+			return "", fmt.Errorf("has a blank line in the string literal at offset %d, which would split the script", tok.Offset)
+		}
+		b.WriteString(tok.Text)
+		end = tok.End()
+	}
+	return b.String(), nil
+}
+
+// hasBlankLine returns true if s has a line of whitespace between two line breaks.
+func hasBlankLine(s string) bool {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines)-1; i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package mgmt
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scriptRow(command, result, reason string) value.Values {
+	return value.Values{
+		value.GUID{Value: testOperationID, Valid: true},
+		value.String{Value: "TableCreate", Valid: true},
+		value.String{Value: command, Valid: true},
+		value.String{Value: result, Valid: true},
+		value.String{Value: reason, Valid: true},
+	}
+}
+
+func scriptResult(rows ...value.Values) fakeResult {
+	return fakeResult{
+		columns: table.Columns{
+			{Name: "OperationId", Type: types.GUID},
+			{Name: "CommandType", Type: types.String},
+			{Name: "CommandText", Type: types.String},
+			{Name: "Result", Type: types.String},
+			{Name: "Reason", Type: types.String},
+		},
+		rows: rows,
+	}
+}
+
+func TestExecuteScript(t *testing.T) {
+	t.Parallel()
+
+	commands := []kusto.Statement{
+		kql.New(".create table ").AddTable("my-table").AddLiteral(" (a:long)"),
+		kql.New(".create table T (b:string)"),
+		kql.New(".drop table X"),
+	}
+
+	client := &fakeClient{results: []fakeResult{scriptResult(
+		scriptRow(`.create table ["my-table"] (a:long)`, ScriptCommandCompleted, ""),
+		scriptRow(".create table T (b:string)", ScriptCommandCompleted, ""),
+		scriptRow(".drop table X", ScriptCommandCompleted, ""),
+	)}}
+	results, err := ExecuteScript(context.Background(), client, "db", commands)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, ScriptCommandResult{
+		OperationID: testOperationID,
+		CommandType: "TableCreate",
+		CommandText: ".create table T (b:string)",
+		Result:      ScriptCommandCompleted,
+	}, results[1])
+	assert.Equal(t, []string{
+		".execute database script <|\n.create table [\"my-table\"] (a:long)\n\n.create table T (b:string)\n\n.drop table X",
+	}, client.stmts)
+
+	client = &fakeClient{results: []fakeResult{scriptResult(
+		scriptRow(`.create table ["my-table"] (a:long)`, ScriptCommandCompleted, ""),
+		scriptRow(".create table T (b:string)", ScriptCommandFailed, "table T already exists"),
+		scriptRow(".drop table X", ScriptCommandCompleted, ""),
+	)}}
+	results, err = ExecuteScript(context.Background(), client, "db", commands, ContinueOnErrors())
+	require.Len(t, results, 3)
+	var scriptErr *ScriptError
+	require.True(t, errors.As(err, &scriptErr))
+	assert.Equal(t, 1, scriptErr.Index)
+	assert.Equal(t, "table T already exists", scriptErr.Result.Reason)
+	assert.EqualError(t, err, "command 2 of the script (TableCreate) failed: table T already exists")
+	assert.True(t, results[1].Failed())
+	assert.Contains(t, client.stmts[0], ".execute database script with (ContinueOnErrors=true) <|\n")
+
+	client = &fakeClient{results: []fakeResult{{err: errors.New("script failed")}}}
+	results, err = ExecuteScript(context.Background(), client, "db", commands, ThrowOnErrors())
+	assert.EqualError(t, err, "script failed")
+	assert.Nil(t, results)
+	assert.Contains(t, client.stmts[0], ".execute database script with (ThrowOnErrors=true) <|\n")
+}
+
+func TestExecuteScriptBlankLines(t *testing.T) {
+	t.Parallel()
+
+	function := createFunctionStmt(FunctionDefinition{Name: "F", Body: "let n = 1;\n\n  \n  T | take n // a comment\n\n| count"})
+	client := &fakeClient{results: []fakeResult{scriptResult(scriptRow("", ScriptCommandCompleted, ""))}}
+	_, err := ExecuteScript(context.Background(), client, "db", []kusto.Statement{function, kql.New(".show tables")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		".execute database script <|\n.create-or-alter function F() {\nlet n = 1;\n  T | take n // a comment\n| count\n}\n\n.show tables",
+	}, client.stmts)
+
+	client = &fakeClient{}
+	_, err = ExecuteScript(context.Background(), client, "db", []kusto.Statement{
+		kql.New(".set T <| print s = ").AddUnsafe("```a\n\nb```"),
+	})
+	assert.Error(t, err)
+	assert.Empty(t, client.stmts)
+}
+
+func TestExecuteScriptErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		commands []kusto.Statement
+		options  []ScriptOption
+	}{
+		{desc: "No commands"},
+		{desc: "Both options", commands: []kusto.Statement{kql.New(".show tables")}, options: []ScriptOption{ContinueOnErrors(), ThrowOnErrors()}},
+		{desc: "Query", commands: []kusto.Statement{kql.New("T | take 1")}},
+		{desc: "Unclosed string", commands: []kusto.Statement{kql.New(".show table ").AddUnsafe(`"T`)}},
+		{desc: "Parameters", commands: []kusto.Statement{kusto.NewStmt(".show tables").MustDefinitions(
+			kusto.NewDefinitions().Must(kusto.ParamTypes{"n": kusto.ParamType{Type: types.Long}}),
+		).MustParameters(kusto.NewParameters().Must(kusto.QueryValues{"n": int64(1)}))}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			client := &fakeClient{}
+			_, err := ExecuteScript(context.Background(), client, "db", test.commands, test.options...)
+			assert.Error(t, err)
+			assert.Empty(t, client.stmts)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package mgmt
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+)
+
+// Table is a row of the ".show tables" command.
+type Table struct {
+	Name         string `kusto:"TableName"`
+	DatabaseName string
+	Folder       string
+	DocString    string
+}
+
+// TableSchema is the schema of a table, as returned by ".show table T schema as json".
+type TableSchema struct {
+	Name      string
+	Folder    string
+	DocString string
+	// Columns are the table columns, in order.
+	Columns table.Columns
+	// ColumnDocStrings holds the docstrings of the columns that have one, by column name.
+	ColumnDocStrings map[string]string
+}
+
+// showTableSchema is a row of the ".show table T schema as json" command.
+type showTableSchema struct {
+	TableName    string
+	Schema       string
+	DatabaseName string
+	Folder       string
+	DocString    string
+}
+
+// schemaJSON is the content of the Schema column of ".show table T schema as json", and a table of
+// ".show database schema as json".
+type schemaJSON struct {
+	Name           string
+	Folder         string
+	DocString      string
+	OrderedColumns []struct {
+		Name      string
+		CslType   string
+		DocString string
+	}
+}
+
+// ListTables returns the tables in database db.
+func ListTables(ctx context.Context, client Client, db string) ([]Table, error) {
+	return rowsTo[Table](ctx, client, db, kql.New(".show tables"))
+}
+
+// GetTableSchema returns the schema of table tableName in database db.
+func GetTableSchema(ctx context.Context, client Client, db, tableName string) (TableSchema, error) {
+	rows, err := rowsTo[showTableSchema](ctx, client, db, kql.New(".show table ").AddTable(tableName).AddLiteral(" schema as json"))
+	if err != nil {
+		return TableSchema{}, err
+	}
+	if len(rows) != 1 {
+		return TableSchema{}, errors.ES(errors.OpMgmt, errors.KInternal, "expected 1 row for the schema of table %q, got %d", tableName, len(rows)).SetNoRetry()
+	}
+
+	return parseTableSchema(rows[0])
+}
+
+func parseTableSchema(row showTableSchema) (TableSchema, error) {
+	var s schemaJSON
+	if err := json.Unmarshal([]byte(row.Schema), &s); err != nil {
+		return TableSchema{}, errors.ES(errors.OpMgmt, errors.KInternal, "could not parse the schema of table %q: %s", row.TableName, err).SetNoRetry()
+	}
+	return tableSchemaFromJSON(row, s)
+}
+
+func tableSchemaFromJSON(row showTableSchema, s schemaJSON) (TableSchema, error) {
+	schema := TableSchema{
+		Name:      row.TableName,
+		Folder:    row.Folder,
+		DocString: row.DocString,
+		Columns:   make(table.Columns, 0, len(s.OrderedColumns)),
+	}
+	for _, c := range s.OrderedColumns {
+		t := types.Column(c.CslType)
+		if !t.Valid() {
+			return TableSchema{}, errors.ES(errors.OpMgmt, errors.KInternal, "column %q of table %q has unknown type %q", c.Name, row.TableName, c.CslType).SetNoRetry()
+		}
+		schema.Columns = append(schema.Columns, table.Column{Name: c.Name, Type: t})
+		if c.DocString != "" {
+			if schema.ColumnDocStrings == nil {
+				schema.ColumnDocStrings = map[string]string{}
+			}
+			schema.ColumnDocStrings[c.Name] = c.DocString
+		}
+	}
+
+	return schema, nil
+}
+
+// TableOption is an optional argument to CreateTable() and CreateMerge().
+type TableOption func(p *tableProperties)
+
+type tableProperties struct {
+	folder    string
+	docString string
+}
+
+// WithFolder sets the folder of the table.
+func WithFolder(folder string) TableOption {
+	return func(p *tableProperties) {
+		p.folder = folder
+	}
+}
+
+// WithDocString sets the docstring of the table.
+func WithDocString(docString string) TableOption {
+	return func(p *tableProperties) {
+		p.docString = docString
+	}
+}
+
+// CreateTable creates table tableName with columns in database db, with ".create table". This fails if the table
+// already exists with a different schema.
+func CreateTable(ctx context.Context, client Client, db, tableName string, columns table.Columns, options ...TableOption) error {
+	stmt, err := createStmt(kql.New(".create table "), tableName, columns, options)
+	if err != nil {
+		return err
+	}
+	return run(ctx, client, db, stmt)
+}
+
+// CreateMerge creates table tableName with columns in database db, or adds the missing columns if it exists, with
+// ".create-merge table".
+func CreateMerge(ctx context.Context, client Client, db, tableName string, columns table.Columns, options ...TableOption) error {
+	stmt, err := createStmt(kql.New(".create-merge table "), tableName, columns, options)
+	if err != nil {
+		return err
+	}
+	return run(ctx, client, db, stmt)
+}
+
+// createStmt completes stmt, the ".create table" or ".create-merge table" command, with the table definition.
+func createStmt(stmt *kql.Builder, tableName string, columns table.Columns, options []TableOption) (*kql.Builder, error) {
+	if tableName == "" {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "table name cannot be empty").SetNoRetry()
+	}
+	if err := columns.Validate(); err != nil {
+		return nil, errors.E(errors.OpMgmt, errors.KClientArgs, err).SetNoRetry()
+	}
+
+	props := tableProperties{}
+	for _, o := range options {
+		o(&props)
+	}
+
+	stmt.AddTable(tableName).AddLiteral(" (")
+	for i, c := range columns {
+		if i > 0 {
+			stmt.AddLiteral(", ")
+		}
+		stmt.AddColumn(c.Name).AddLiteral(":").AddKeyword(string(c.Type))
+	}
+	stmt.AddLiteral(")")
+
+	if props.folder != "" || props.docString != "" {
+		stmt.AddLiteral(" with (")
+		if props.folder != "" {
+			stmt.AddLiteral("folder=").AddString(props.folder)
+		}
+		if props.docString != "" {
+			if props.folder != "" {
+				stmt.AddLiteral(", ")
+			}
+			stmt.AddLiteral("docstring=").AddString(props.docString)
+		}
+		stmt.AddLiteral(")")
+	}
+
+	return stmt, nil
+}
+
+// AlterColumnType changes the type of column in table tableName. Existing data in the column is not converted, and
+// will read as null unless the new type is compatible.
+func AlterColumnType(ctx context.Context, client Client, db, tableName, column string, columnType types.Column) error {
+	if !columnType.Valid() {
+		return errors.ES(errors.OpMgmt, errors.KClientArgs, "column type %q is not valid", columnType).SetNoRetry()
+	}
+
+	stmt := kql.New(".alter column ").AddTable(tableName).AddLiteral(".").AddColumn(column).
+		AddLiteral(" type=").AddKeyword(string(columnType))
+	return run(ctx, client, db, stmt)
+}
+
+// RenameColumn renames column oldName of table tableName to newName.
+func RenameColumn(ctx context.Context, client Client, db, tableName, oldName, newName string) error {
+	stmt := kql.New(".rename column ").AddTable(tableName).AddLiteral(".").AddColumn(oldName).
+		AddLiteral(" to ").AddColumn(newName)
+	return run(ctx, client, db, stmt)
+}
+
+// DropTable drops table tableName. If ifExists is true, dropping a table that doesn't exist is not an error.
+func DropTable(ctx context.Context, client Client, db, tableName string, ifExists bool) error {
+	stmt := kql.New(".drop table ").AddTable(tableName)
+	if ifExists {
+		stmt.AddLiteral(" ifexists")
+	}
+	return run(ctx, client, db, stmt)
+}
@@ -0,0 +1,186 @@
+package mgmt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/table"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListTables(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{results: []fakeResult{{
+		columns: table.Columns{
+			{Name: "TableName", Type: types.String},
+			{Name: "DatabaseName", Type: types.String},
+			{Name: "Folder", Type: types.String},
+			{Name: "DocString", Type: types.String},
+		},
+		rows: []value.Values{
+			{value.String{Value: "Logs", Valid: true}, value.String{Value: "db", Valid: true}, value.String{Value: "raw", Valid: true}, value.String{Value: "All logs", Valid: true}},
+			{value.String{Value: "my-table", Valid: true}, value.String{Value: "db", Valid: true}, value.String{}, value.String{}},
+		},
+	}}}
+
+	tables, err := ListTables(context.Background(), client, "db")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".show tables"}, client.stmts)
+	assert.Equal(t, []Table{
+		{Name: "Logs", DatabaseName: "db", Folder: "raw", DocString: "All logs"},
+		{Name: "my-table", DatabaseName: "db"},
+	}, tables)
+}
+
+func TestGetTableSchema(t *testing.T) {
+	t.Parallel()
+
+	columns := table.Columns{
+		{Name: "TableName", Type: types.String},
+		{Name: "Schema", Type: types.String},
+		{Name: "DatabaseName", Type: types.String},
+		{Name: "Folder", Type: types.String},
+		{Name: "DocString", Type: types.String},
+	}
+	row := func(schema string) []value.Values {
+		return []value.Values{{
+			value.String{Value: "my-table", Valid: true},
+			value.String{Value: schema, Valid: true},
+			value.String{Value: "db", Valid: true},
+			value.String{Value: "logs", Valid: true},
+			value.String{Value: "A table", Valid: true},
+		}}
+	}
+
+	client := &fakeClient{results: []fakeResult{{
+		columns: columns,
+		rows: row(`{"Name":"my-table","OrderedColumns":[` +
+			`{"Name":"Timestamp","Type":"System.DateTime","CslType":"datetime","DocString":"Event time"},` +
+			`{"Name":"my-col","Type":"System.String","CslType":"string"},` +
+			`{"Name":"Count","Type":"System.Int64","CslType":"long"}]}`),
+	}}}
+
+	schema, err := GetTableSchema(context.Background(), client, "db", "my-table")
+	require.NoError(t, err)
+	assert.Equal(t, []string{`.show table ["my-table"] schema as json`}, client.stmts)
+	assert.Equal(t, TableSchema{
+		Name:      "my-table",
+		Folder:    "logs",
+		DocString: "A table",
+		Columns: table.Columns{
+			{Name: "Timestamp", Type: types.DateTime},
+			{Name: "my-col", Type: types.String},
+			{Name: "Count", Type: types.Long},
+		},
+		ColumnDocStrings: map[string]string{"Timestamp": "Event time"},
+	}, schema)
+
+	client = &fakeClient{results: []fakeResult{{columns: columns, rows: row(`{"OrderedColumns":[{"Name":"a","CslType":"blob"}]}`)}}}
+	_, err = GetTableSchema(context.Background(), client, "db", "my-table")
+	assert.Error(t, err)
+
+	client = &fakeClient{results: []fakeResult{{columns: columns}}}
+	_, err = GetTableSchema(context.Background(), client, "db", "my-table")
+	assert.Error(t, err)
+}
+
+func TestTableCommands(t *testing.T) {
+	t.Parallel()
+
+	columns := table.Columns{
+		{Name: "Timestamp", Type: types.DateTime},
+		{Name: "my-col", Type: types.String},
+	}
+
+	tests := []struct {
+		desc    string
+		run     func(client Client) error
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "CreateTable",
+			run: func(client Client) error {
+				return CreateTable(context.Background(), client, "db", "my-table", columns)
+			},
+			want: `.create table ["my-table"] (Timestamp:datetime, ["my-col"]:string)`,
+		},
+		{
+			desc: "CreateTableWithProperties",
+			run: func(client Client) error {
+				return CreateTable(context.Background(), client, "db", "Logs", columns, WithFolder("raw"), WithDocString(`say "hi"`))
+			},
+			want: `.create table Logs (Timestamp:datetime, ["my-col"]:string) with (folder="raw", docstring="say \"hi\"")`,
+		},
+		{
+			desc: "CreateMerge",
+			run: func(client Client) error {
+				return CreateMerge(context.Background(), client, "db", "Logs", columns, WithDocString("doc"))
+			},
+			want: `.create-merge table Logs (Timestamp:datetime, ["my-col"]:string) with (docstring="doc")`,
+		},
+		{
+			desc: "CreateTableNoColumns",
+			run: func(client Client) error {
+				return CreateTable(context.Background(), client, "db", "Logs", nil)
+			},
+			wantErr: true,
+		},
+		{
+			desc: "CreateTableBadType",
+			run: func(client Client) error {
+				return CreateTable(context.Background(), client, "db", "Logs", table.Columns{{Name: "a", Type: "string) ; .drop table X"}})
+			},
+			wantErr: true,
+		},
+		{
+			desc: "AlterColumnType",
+			run: func(client Client) error {
+				return AlterColumnType(context.Background(), client, "db", "my-table", "my-col", types.Long)
+			},
+			want: `.alter column ["my-table"].["my-col"] type=long`,
+		},
+		{
+			desc: "RenameColumn",
+			run: func(client Client) error {
+				return RenameColumn(context.Background(), client, "db", "Logs", "old", "new-name")
+			},
+			want: `.rename column Logs.old to ["new-name"]`,
+		},
+		{
+			desc: "DropTable",
+			run: func(client Client) error {
+				return DropTable(context.Background(), client, "db", "my-table", false)
+			},
+			want: `.drop table ["my-table"]`,
+		},
+		{
+			desc: "DropTableIfExists",
+			run: func(client Client) error {
+				return DropTable(context.Background(), client, "db", "Logs", true)
+			},
+			want: `.drop table Logs ifexists`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeClient{}
+			err := test.run(client)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, client.stmts)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{test.want}, client.stmts)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package kusto
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-kusto-go/kusto/data/errors"
+	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/data/value"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
+	"github.com/Azure/azure-kusto-go/kusto/kql/lexer"
+)
+
+// inlineMgmtParameters returns query with its query parameters, from a Stmt or the QueryParameters() option, rendered
+// as typed literals in the query part of the management command, bound with let statements after "<|". The service
+// does not take query parameters with management commands, so they are removed from opts. It fails if the command has
+// no query part. A .show command piped into a query has none: its output columns are not known, so a parameter could
+// not be told apart from a column of the same name.
+func inlineMgmtParameters(query Statement, opts *queryOptions) (Statement, error) {
+	var text string
+	var params *kql.Parameters
+	if stmt, ok := query.(Stmt); ok && !stmt.defs.IsZero() {
+		var err error
+		if params, err = stmtParameters(stmt); err != nil {
+			return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "Parameter validation error: %s", err).SetNoRetry()
+		}
+		// The definitions are rendered as literals, so they are not declared.
+		text = stmt.queryStr
+	} else if opts.requestProperties.QueryParameters.Count() > 0 {
+		params = &opts.requestProperties.QueryParameters
+		text = query.String()
+	} else {
+		return query, nil
+	}
+	literals := params.ToParameterCollection()
+
+	statements, err := lexer.Parse(text)
+	if err != nil {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "the command is not valid KQL: %s", err).SetNoRetry()
+	}
+	if len(statements) == 0 || statements[len(statements)-1].Kind != lexer.CommandStatement {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs, "query parameters can only be used with a management command").SetNoRetry()
+	}
+	// The parameters are bound in the command, so statements before it, such as set or declare, are not supported.
+	if len(statements) > 1 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs,
+			"query parameters can only be used with a single management command, found %d statements", len(statements)).SetNoRetry()
+	}
+	tokens := statements[0].Tokens
+
+	start := queryPartStart(tokens)
+	if start < 0 {
+		return nil, errors.ES(errors.OpMgmt, errors.KClientArgs,
+			"the %s command has no query part (after <|), so it cannot take query parameters; use kql.New with typed values instead", tokens[0].Text).SetNoRetry()
+	}
+
+	inlined := bindParameters(text, tokens, start, literals)
+
+	opts.requestProperties.QueryParameters = kql.Parameters{}
+	opts.requestProperties.Parameters = nil
+	// The literals are rendered by kql from typed values, and the rest of the command is the caller's statement.
+	return kql.New("").AddUnsafe(inlined), nil
+}
+
+// bindParameters binds the parameters to their literals with let statements at the start of the query part of a
+// command, which starts at tokens[start]. They are then scoped like declared query parameters: the columns and the
+// let statements of the query with the same names shadow them. A declare query_parameters statement at the start of
+// the query part is replaced by the let statements. The rest of text, including comments, is kept.
+func bindParameters(text string, tokens []lexer.Token, start int, literals map[string]string) string {
+	names := make([]string, 0, len(literals))
+	for name := range literals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := strings.Builder{}
+	b.WriteString(text[:tokens[start-1].End()])
+	for _, name := range names {
+		b.WriteString(" let " + kql.NormalizeName(name) + " = " + literals[name] + ";")
+	}
+
+	rest := start
+	if rest+2 < len(tokens) && tokens[rest].Text == "declare" && tokens[rest+1].Text == "query_parameters" && tokens[rest+2].Text == "(" {
+		depth := 0
+		for rest += 2; rest < len(tokens); rest++ {
+			switch tokens[rest].Text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+			if depth == 0 && tokens[rest].Text == ";" {
+				rest++
+				break
+			}
+		}
+	}
+	if rest < len(tokens) {
+		b.WriteString(" " + text[tokens[rest].Offset:])
+	} else {
+		b.WriteString(text[tokens[len(tokens)-1].End():])
+	}
+	return b.String()
+}
+
+// queryPartStart returns the index of the first token of the query part of a command, after "<|", or -1 if it has none.
+func queryPartStart(tokens []lexer.Token) int {
+	for i, tok := range tokens {
+		if tok.Text == "<|" {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// stmtParameters converts the parameters of s, or the defaults of its definitions, to kql.Parameters.
+func stmtParameters(s Stmt) (*kql.Parameters, error) {
+	params := kql.NewParameters()
+	for name, def := range s.defs.m {
+		v, ok := s.params.m[name]
+		if !ok {
+			v = def.Default
+		}
+		if v == nil {
+			return nil, fmt.Errorf("parameter %s has no value or default", name)
+		}
+
+		switch def.Type {
+		case types.Dynamic:
+			j, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s(dynamic) could not be marshalled into JSON: %s", name, err)
+			}
+			v = value.Dynamic{Value: j, Valid: true}
+		case types.Decimal:
+			switch d := v.(type) {
+			case string:
+				v = value.Decimal{Value: d, Valid: true}
+			case *big.Float:
+				v = value.Decimal{Value: d.String(), Valid: true}
+			case *big.Int:
+				v = value.Decimal{Value: d.String(), Valid: true}
+			}
+		}
+
+		if _, err := params.Add(name, v); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
@@ -1,6 +1,7 @@
 package kusto
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"math/big"
@@ -8,6 +9,9 @@ import (
 	"time"
 
 	"github.com/Azure/azure-kusto-go/kusto/data/types"
+	"github.com/Azure/azure-kusto-go/kusto/internal/frames"
+	v1 "github.com/Azure/azure-kusto-go/kusto/internal/frames/v1"
+	"github.com/Azure/azure-kusto-go/kusto/kql"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
@@ -603,3 +607,136 @@ func buildQueryStr(query string, params Definitions) string {
 	}
 	return query
 }
+
+// paramsConn records the statement and options of a management command and returns no rows.
+type paramsConn struct {
+	mockConn
+	sent chan paramsCall
+}
+
+type paramsCall struct {
+	query string
+	opts  *queryOptions
+}
+
+func (p paramsConn) mgmt(_ context.Context, _ string, query Statement, options *queryOptions) (execResp, error) {
+	p.sent <- paramsCall{query: query.String(), opts: options}
+	ch := make(chan frames.Frame, 1)
+	ch <- v1.DataTable{DataTypes: v1.DataTypes{{ColumnName: "x", ColumnType: "long"}}}
+	close(ch)
+	return execResp{frameCh: ch}, nil
+}
+
+func TestMgmtParameters(t *testing.T) {
+	t.Parallel()
+
+	defs := NewDefinitions().Must(ParamTypes{
+		"n": ParamType{Type: types.Long},
+		"s": ParamType{Type: types.String, Default: "x"},
+		"d": ParamType{Type: types.Decimal},
+	})
+
+	tests := []struct {
+		name     string
+		query    Statement
+		options  []QueryOption
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "Stmt",
+			query: NewStmt(".set-or-append T <| U | where x == n and y != s and z > d | extend n = 1").MustDefinitions(defs).
+				MustParameters(NewParameters().Must(QueryValues{"n": int64(1), "d": "1.5"})),
+			expected: `.set-or-append T <| let d = decimal(1.5); let n = long(1); let s = "x"; U | where x == n and y != s and z > d | extend n = 1`,
+		},
+		{
+			name: "Stmt injection",
+			query: NewStmt(".set-or-append T <| U | where y == s").MustDefinitions(defs).
+				MustParameters(NewParameters().Must(QueryValues{"n": int64(1), "d": "1", "s": `" | .drop table T //`})),
+			expected: `.set-or-append T <| let d = decimal(1); let n = long(1); let s = "\" | .drop table T //"; U | where y == s`,
+		},
+		{
+			name: "Stmt bad decimal",
+			query: NewStmt(".set-or-append T <| U | where z > d").MustDefinitions(defs).
+				MustParameters(NewParameters().Must(QueryValues{"n": int64(1), "d": "1) | .drop table T"})),
+			wantErr: true,
+		},
+		{
+			name:     "kql.Parameters",
+			query:    kql.New(".export to csv (h@'https://x') <| T | where Name == name and a.name == 1"),
+			options:  []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "a'b"))},
+			expected: `.export to csv (h@'https://x') <| let name = "a\'b"; T | where Name == name and a.name == 1`,
+		},
+		{
+			name:     "Declared",
+			query:    kql.New(".set T <| declare query_parameters(name:string = 'x', n:long);\nU | where Name == name"),
+			options:  []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "a").AddLong("n", 1))},
+			expected: `.set T <| let n = long(1); let name = "a"; U | where Name == name`,
+		},
+		{
+			name:     "Shadowed",
+			query:    kql.New(".set T <| let name = 'b'; U | project name"),
+			options:  []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "a"))},
+			expected: `.set T <| let name = "a"; let name = 'b'; U | project name`,
+		},
+		{
+			name:     "Comments",
+			query:    kql.New("// Copy the rows.\n.set T <| U | where Name == name // by name"),
+			options:  []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "a"))},
+			expected: "// Copy the rows.\n.set T <| let name = \"a\"; U | where Name == name // by name",
+		},
+		{
+			name:    "Statements before the command",
+			query:   kql.New("set notruncation;\n.set T <| U | where Name == name"),
+			options: []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "a"))},
+			wantErr: true,
+		},
+		{
+			name:    "Show pipeline",
+			query:   kql.New(".show tables | where TableName == name"),
+			options: []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "T"))},
+			wantErr: true,
+		},
+		{
+			name:     "No parameters",
+			query:    kql.New(".show tables"),
+			expected: ".show tables",
+		},
+		{
+			name:    "No query part",
+			query:   kql.New(".drop table name"),
+			options: []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "T"))},
+			wantErr: true,
+		},
+		{
+			name:    "Not a command",
+			query:   kql.New("T | where a == name"),
+			options: []QueryOption{QueryParameters(kql.NewParameters().AddString("name", "T"))},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			conn := paramsConn{sent: make(chan paramsCall, 1)}
+			client := NewMockClient()
+			client.conn = conn
+
+			iter, err := client.Mgmt(context.Background(), "db", tt.query, tt.options...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, conn.sent)
+				return
+			}
+			require.NoError(t, err)
+			defer iter.Stop()
+
+			call := <-conn.sent
+			assert.Equal(t, tt.expected, call.query)
+			assert.Empty(t, call.opts.requestProperties.Parameters)
+			assert.Equal(t, 0, call.opts.requestProperties.QueryParameters.Count())
+		})
+	}
+}
@@ -4,6 +4,7 @@ package kusto
 // it clogs up the main kusto.go file.
 
 import (
+	"fmt"
 	"github.com/Azure/azure-kusto-go/kusto/kql"
 	"time"
 
@@ -25,6 +26,8 @@ type requestProperties struct {
 type queryOptions struct {
 	requestProperties *requestProperties
 	queryIngestion    bool
+	firstFrameTimeout time.Duration
+	frameIdleTimeout  time.Duration
 }
 
 const RequestProgressiveEnabledValue = "results_progressive_enabled"
@@ -144,6 +147,33 @@ func ServerTimeout(d time.Duration) QueryOption {
 	}
 }
 
+// FirstFrameTimeout limits how long the client waits for the first frame of the response, from the time the request
+// is sent. This catches connections that a proxy or load balancer stalls before the response starts. If it elapses,
+// the call fails with a KTimeout error, which can be retried. It is disabled by default, leaving the context deadline
+// and ServerTimeout(). For Mgmt(), the first frame is the complete first table of the response.
+func FirstFrameTimeout(d time.Duration) QueryOption {
+	return func(q *queryOptions) error {
+		if d < 0 {
+			return fmt.Errorf("FirstFrameTimeout(%s) cannot be negative", d)
+		}
+		q.firstFrameTimeout = d
+		return nil
+	}
+}
+
+// FrameIdleTimeout limits how long the client waits for each frame of the response after the first one. The time
+// spent waiting on the RowIterator's consumer does not count. If it elapses, the iterator fails with a KTimeout error,
+// which can be retried. It is disabled by default. Note that without progressive frames, a whole table is one frame.
+func FrameIdleTimeout(d time.Duration) QueryOption {
+	return func(q *queryOptions) error {
+		if d < 0 {
+			return fmt.Errorf("FrameIdleTimeout(%s) cannot be negative", d)
+		}
+		q.frameIdleTimeout = d
+		return nil
+	}
+}
+
 // CustomQueryOption exists to allow a QueryOption that is not defined in the Go SDK, as all options
 // are not defined. Please Note: you should always use the type safe options provided below when available.
 // Also note that Kusto does not error on non-existent parameter names or bad values, it simply doesn't
@@ -567,7 +597,8 @@ func ValidatePermissions() QueryOption {
 	}
 }
 
-// IngestionEndpoint will instruct the Mgmt call to connect to the ingest-[endpoint] instead of [endpoint].
+// IngestionEndpoint will instruct the Mgmt call to connect to the ingest-[endpoint] instead of [endpoint], or to the
+// endpoint set with WithIngestionURI().
 // This is not often used by end users and can only be used with a Mgmt() call.
 func IngestionEndpoint() QueryOption {
 	return func(m *queryOptions) error {
@@ -50,6 +50,8 @@ type RowIterator struct {
 	op     errors.Op
 	ctx    context.Context
 	cancel context.CancelFunc
+	// timer reports the frame timeouts that cancelled ctx.
+	timer *frameTimer
 
 	// RequestHeader is the http.Header sent in the request to the server.
 	RequestHeader http.Header
@@ -278,7 +280,7 @@ func (r *RowIterator) NextRowOrError() (row *table.Row, inlineError *errors.Erro
 
 	select {
 	case <-r.ctx.Done():
-		return nil, nil, r.ctx.Err()
+		return nil, nil, r.timer.err(r.ctx.Err())
 	case kvs, ok := <-r.rows:
 		if !ok {
 			if err := r.getError(); err != nil {
@@ -49,6 +49,7 @@ type nonProgressiveSM struct {
 	in            chan frames.Frame
 	columnSetOnce sync.Once
 	ctx           context.Context
+	timer         *frameTimer
 	hasCompletion bool
 
 	wg *sync.WaitGroup // Used to know when everything has finished
@@ -71,10 +72,12 @@ func (d *nonProgressiveSM) process() (sf stateFn, err error) {
 	default:
 	}
 
+	d.timer.wait()
 	select {
 	case <-d.ctx.Done():
-		return nil, d.ctx.Err()
+		return nil, d.timer.err(d.ctx.Err())
 	case fr, ok := <-d.in:
+		d.timer.frame()
 		if !ok {
 			d.wg.Wait()
 
@@ -161,6 +164,7 @@ type progressiveSM struct {
 	in            chan frames.Frame
 	columnSetOnce sync.Once
 	ctx           context.Context
+	timer         *frameTimer
 
 	currentHeader *v2.TableHeader
 	currentFrame  frames.Frame
@@ -186,10 +190,12 @@ func (p *progressiveSM) nextFrame() (stateFn, error) {
 	default:
 	}
 
+	p.timer.wait()
 	select {
 	case <-p.ctx.Done():
-		return nil, p.ctx.Err()
+		return nil, p.timer.err(p.ctx.Err())
 	case fr, ok := <-p.in:
+		p.timer.frame()
 		if !ok {
 			return nil, errors.ES(p.op, errors.KInternal, "received a table stream that did not finish before our input channel, this is usually a return size or time limit")
 		}
@@ -245,10 +251,12 @@ func (p *progressiveSM) dataSetCompletion() (stateFn, error) {
 	case p.iter.inCompletion <- send{inCompletion: p.currentFrame.(v2.DataSetCompletion), wg: p.wg}:
 	}
 
+	p.timer.wait()
 	select {
 	case <-p.ctx.Done():
-		return nil, p.ctx.Err()
+		return nil, p.timer.err(p.ctx.Err())
 	case frame, ok := <-p.in:
+		p.timer.frame()
 		if !ok {
 			p.wg.Wait()
 			return nil, nil
@@ -331,6 +339,7 @@ type v1SM struct {
 	in            chan frames.Frame
 	columnSetOnce sync.Once
 	ctx           context.Context
+	timer         *frameTimer
 
 	currentTable v1.DataTable
 	tables       []v1.DataTable
@@ -365,10 +374,12 @@ func (p *v1SM) nextFrame() (stateFn, error) {
 	default:
 	}
 
+	p.timer.wait()
 	select {
 	case <-p.ctx.Done():
-		return nil, p.ctx.Err()
+		return nil, p.timer.err(p.ctx.Err())
 	case fr, ok := <-p.in:
+		p.timer.frame()
 		if !ok {
 			if len(p.tables) == 0 {
 				return p.done, nil
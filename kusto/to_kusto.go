@@ -28,7 +28,7 @@ func structToKustoValues(cols table.Columns, p interface{}) (value.Values, error
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if tag := field.Tag.Get("kusto"); strings.TrimSpace(tag) != "" {
+		if tag, _, _ := strings.Cut(field.Tag.Get("kusto"), ","); strings.TrimSpace(tag) != "" {
 			colData, ok := m[tag]
 			if !ok {
 				continue
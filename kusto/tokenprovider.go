@@ -20,6 +20,7 @@ type TokenProvider struct {
 	initOnce    utils.OnceWithInit[*tokenWrapperResult] //To ensure tokenprovider will be initialized only once while aquiring token
 	scopes      []string                                //Contains scopes of the auth token
 	http        atomic.Value                            //Contains the http client to be used for token provider
+	cloudInfo   *CloudInfo                              //Contains cloud info provided by the client, if set the metadata endpoint is not queried
 }
 
 // tokenProvider need to be received as reference, to reflect updations to the structs
@@ -57,7 +58,7 @@ type tokenWrapperResult struct {
 
 func (tkp *TokenProvider) setInit(kcsb *ConnectionStringBuilder, f func(*CloudInfo, *azcore.ClientOptions, string) (azcore.TokenCredential, error)) {
 	tkp.initOnce = utils.NewOnceWithInit(func() (*tokenWrapperResult, error) {
-		wrapper, err := tokenWrapper(kcsb, func() *http.Client { return tkp.http.Load().(*http.Client) }, f)
+		wrapper, err := tokenWrapper(kcsb, func() *http.Client { return tkp.http.Load().(*http.Client) }, tkp.cloudInfo, f)
 		if err != nil {
 			return nil, err
 		}
@@ -73,9 +74,9 @@ func (tkp *TokenProvider) SetHttp(http *http.Client) {
 	tkp.http.Store(http)
 }
 
-func tokenWrapper(kcsb *ConnectionStringBuilder, http func() *http.Client, f func(*CloudInfo, *azcore.ClientOptions, string) (azcore.TokenCredential, error)) (*tokenWrapperResult,
+func tokenWrapper(kcsb *ConnectionStringBuilder, http func() *http.Client, cloudInfo *CloudInfo, f func(*CloudInfo, *azcore.ClientOptions, string) (azcore.TokenCredential, error)) (*tokenWrapperResult,
 	error) {
-	ci, cliOpts, appClientId, err := getCommonCloudInfo(kcsb, http)
+	ci, cliOpts, appClientId, err := getCommonCloudInfo(kcsb, http, cloudInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -97,15 +98,21 @@ func tokenWrapper(kcsb *ConnectionStringBuilder, http func() *http.Client, f fun
 	}, nil
 }
 
-func getCommonCloudInfo(kcsb *ConnectionStringBuilder, http func() *http.Client) (*CloudInfo, *azcore.ClientOptions, string, error) {
+func getCommonCloudInfo(kcsb *ConnectionStringBuilder, http func() *http.Client, cloudInfo *CloudInfo) (*CloudInfo, *azcore.ClientOptions, string, error) {
 	client := http()
 	if http == nil {
 		return nil, nil, "", fmt.Errorf("error: No http client provided")
 	}
 
-	cloud, err := GetMetadata(kcsb.DataSource, client)
-	if err != nil {
-		return nil, nil, "", err
+	var cloud CloudInfo
+	if cloudInfo != nil {
+		cloud = *cloudInfo
+	} else {
+		var err error
+		cloud, err = GetMetadata(kcsb.DataSource, client)
+		if err != nil {
+			return nil, nil, "", err
+		}
 	}
 	cliOpts := kcsb.ClientOptions
 	appClientId := kcsb.ApplicationClientId
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"net/url"
+	"os"
 	"strings"
 
 	"github.com/Azure/azure-kusto-go/kusto/data/errors"
@@ -28,12 +29,27 @@ type WellKnownKustoEndpointsDataStruct struct {
 }
 
 func createInstance() *TrustedEndpoints {
+	trusted, err := FromJSON(jsonFile)
+	if err != nil {
+		panic(err.Error())
+	}
+	return trusted
+}
+
+// New returns a new TrustedEndpoints holding the well-known Kusto endpoints. Unlike Instance, changes to the
+// returned value only affect clients it is attached to.
+func New() *TrustedEndpoints {
+	return createInstance()
+}
+
+// FromJSON returns a new TrustedEndpoints holding only the rules in data. data must have the same shape as the
+// embedded well-known endpoints list (see WellKnownKustoEndpointsDataStruct).
+func FromJSON(data []byte) (*TrustedEndpoints, error) {
 	matchers := map[string]*FastSuffixMatcher{}
 	wellKnownData := WellKnownKustoEndpointsDataStruct{}
 
-	err := json.Unmarshal(jsonFile, &wellKnownData)
-	if err != nil {
-		panic(err.Error())
+	if err := json.Unmarshal(data, &wellKnownData); err != nil {
+		return nil, errors.ES(errors.OpUnknown, errors.KClientArgs, "could not parse trusted endpoints: %s", err).SetNoRetry()
 	}
 
 	for key, value := range wellKnownData.AllowedEndpointsByLogin {
@@ -47,12 +63,27 @@ func createInstance() *TrustedEndpoints {
 
 		f, err := newFastSuffixMatcher(rules)
 		if err != nil {
-			panic(err.Error())
+			return nil, err
 		}
 		matchers[key] = f
 	}
 
-	return &TrustedEndpoints{matchers: matchers}
+	return &TrustedEndpoints{matchers: matchers}, nil
+}
+
+// FromFile is like FromJSON, but reads the rules from the file at path.
+func FromFile(path string) (*TrustedEndpoints, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.E(errors.OpUnknown, errors.KLocalFileSystem, err).SetNoRetry()
+	}
+	return FromJSON(data)
+}
+
+// NewMatchRule creates a MatchRule for AddTrustedHosts. If exact is true, hostname must match exactly,
+// otherwise it is treated as a suffix (such as ".kusto.windows.net").
+func NewMatchRule(hostname string, exact bool) MatchRule {
+	return MatchRule{suffix: hostname, exact: exact}
 }
 
 // SetOverridePolicy Set a policy to override all other trusted rules
@@ -255,3 +255,26 @@ func TestWellTrustedEndpoints_AdditionalWebsites(t *testing.T) {
 		require.NoError(t, err)
 	}
 }
+
+func TestTrustedEndpoints_FromJSON(t *testing.T) {
+	trusted, err := FromJSON([]byte(`{"AllowedEndpointsByLogin": {"https://login.example.com": {"AllowedKustoSuffixes": [".kusto.example.com"], "AllowedKustoHostnames": ["adx.example.net"]}}}`))
+	require.NoError(t, err)
+
+	require.NoError(t, trusted.ValidateTrustedEndpoint("https://mycluster.kusto.example.com", "https://login.example.com"))
+	require.NoError(t, trusted.ValidateTrustedEndpoint("https://adx.example.net", "https://login.example.com"))
+	require.Error(t, trusted.ValidateTrustedEndpoint("https://sub.adx.example.net", "https://login.example.com"))
+	require.Error(t, trusted.ValidateTrustedEndpoint("https://mycluster.kusto.windows.net", defaultPublicLoginUrl))
+
+	_, err = FromJSON([]byte(`{"AllowedEndpointsByLogin": 5}`))
+	require.Error(t, err)
+}
+
+func TestTrustedEndpoints_NewIsIsolated(t *testing.T) {
+	trusted := New()
+	require.NoError(t, trusted.AddTrustedHosts([]MatchRule{NewMatchRule(".isolated.net", false)}, false))
+
+	require.NoError(t, trusted.ValidateTrustedEndpoint("https://some.isolated.net", defaultPublicLoginUrl))
+	require.NoError(t, trusted.ValidateTrustedEndpoint("https://kusto.kusto.windows.net", defaultPublicLoginUrl))
+	err := checkEndpoint("https://some.isolated.net", defaultPublicLoginUrl, true)
+	require.NoError(t, err)
+}